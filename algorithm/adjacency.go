@@ -0,0 +1,97 @@
+package algorithm
+
+import (
+	"sort"
+)
+
+// Edge defines a leg connecting two nodes
+type Edge struct {
+	Origin      string
+	Destination string
+	Leg         string
+	Weight      float32
+}
+
+// HasNode tells whether the node was connected to the graph
+func (g *Graph) HasNode(label string) bool {
+	_, found := g.nodes[label]
+	return found
+}
+
+// Degree counts the legs leaving and arriving at the node
+// Returns 0 and 0 in case the node is not in the graph
+func (g *Graph) Degree(label string) (int, int) {
+	n, found := g.nodes[label]
+	if !found {
+		return 0, 0
+	}
+
+	out, in := 0, 0
+	for _, c := range n.connections {
+		out += len(c.legs)
+	}
+	for origin := range n.inbound {
+		in += len(n.inbound[origin].connections[label].legs)
+	}
+	return out, in
+}
+
+// Outbound lists every leg leaving the node, ordered by destination and leg
+// Returns an empty slice in case the node is not in the graph
+func (g *Graph) Outbound(label string) []Edge {
+	edges := make([]Edge, 0)
+	n, found := g.nodes[label]
+	if !found {
+		return edges
+	}
+
+	for _, destination := range sortedLabels(n.connections) {
+		edges = append(edges, n.connections[destination].edges(label)...)
+	}
+	return edges
+}
+
+// Inbound lists every leg arriving at the node, ordered by origin and leg
+// Returns an empty slice in case the node is not in the graph
+func (g *Graph) Inbound(label string) []Edge {
+	edges := make([]Edge, 0)
+	n, found := g.nodes[label]
+	if !found {
+		return edges
+	}
+
+	origins := make([]string, 0, len(n.inbound))
+	for origin := range n.inbound {
+		origins = append(origins, origin)
+	}
+	sort.Strings(origins)
+	for _, origin := range origins {
+		edges = append(edges, n.inbound[origin].connections[label].edges(origin)...)
+	}
+	return edges
+}
+
+// edges lists the legs of the connection, ordered by leg
+func (c *connection) edges(origin string) []Edge {
+	legs := make([]string, 0, len(c.legs))
+	for leg := range c.legs {
+		legs = append(legs, leg)
+	}
+	sort.Strings(legs)
+
+	edges := make([]Edge, len(legs))
+	for i, leg := range legs {
+		edges[i] = Edge{origin, c.destination.label, leg, c.legs[leg]}
+	}
+	return edges
+}
+
+// sortedLabels lists the labels of the connections in order
+func sortedLabels(connections map[string]*connection) []string {
+	labels := make([]string, 0, len(connections))
+	for label := range connections {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
@@ -0,0 +1,44 @@
+package algorithm
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAdjacency(t *testing.T) {
+	g := NewGraph()
+	g.ConnectLeg("GRU", "BRC", "LA/", 10)
+	g.ConnectLeg("GRU", "BRC", "G3/", 12)
+	g.ConnectLeg("GRU", "CDG", "AF/", 75)
+	g.ConnectLeg("BRC", "SCL", "", 5)
+	g.ConnectLeg("SCL", "BRC", "", 4)
+	g.ConnectLeg("GRU", "BRC", "LA/", 9)
+
+	var tests = []struct {
+		label    string
+		outbound []Edge
+		inbound  []Edge
+	}{
+		{"GRU", []Edge{{"GRU", "BRC", "G3/", 12}, {"GRU", "BRC", "LA/", 9}, {"GRU", "CDG", "AF/", 75}}, []Edge{}},
+		{"BRC", []Edge{{"BRC", "SCL", "", 5}}, []Edge{{"GRU", "BRC", "G3/", 12}, {"GRU", "BRC", "LA/", 9}, {"SCL", "BRC", "", 4}}},
+		{"CDG", []Edge{}, []Edge{{"GRU", "CDG", "AF/", 75}}},
+		{"FCO", []Edge{}, []Edge{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.label, func(t *testing.T) {
+			if found := g.HasNode(tt.label); found != (tt.label != "FCO") {
+				t.Errorf("g.HasNode(%v) expected %v, got %v", tt.label, !found, found)
+			}
+			if outbound := g.Outbound(tt.label); !reflect.DeepEqual(outbound, tt.outbound) {
+				t.Errorf("g.Outbound(%v) expected %v, got %v", tt.label, tt.outbound, outbound)
+			}
+			if inbound := g.Inbound(tt.label); !reflect.DeepEqual(inbound, tt.inbound) {
+				t.Errorf("g.Inbound(%v) expected %v, got %v", tt.label, tt.inbound, inbound)
+			}
+			if out, in := g.Degree(tt.label); out != len(tt.outbound) || in != len(tt.inbound) {
+				t.Errorf("g.Degree(%v) expected %v %v, got %v %v", tt.label, len(tt.outbound), len(tt.inbound), out, in)
+			}
+		})
+	}
+}
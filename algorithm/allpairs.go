@@ -0,0 +1,182 @@
+package algorithm
+
+import (
+	"container/heap"
+	"math"
+	"runtime"
+	"sort"
+	"sync"
+)
+
+// floydWarshallLimit is the biggest graph, in nodes, solved by Floyd-Warshall
+// Bigger graphs run a Dijkstra search from each requested node in parallel
+// (or Bellman-Ford when the graph has negative weights)
+const floydWarshallLimit = 64
+
+// Labels lists the labels of every node in the graph in alphabetical order
+func (g *Graph) Labels() []string {
+	labels := make([]string, 0, len(g.nodes))
+	for label := range g.nodes {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+	return labels
+}
+
+// AllPairsCosts computes the cheapest cost between every pair of labels
+// Returns a matrix where costs[i][j] is the cost from labels[i] to labels[j]
+// The cost is 0 from a label to itself and +Inf when there is no path
+// Returns a NegativeCycleError in case the graph has a negative cycle, which leaves the costs unbounded
+func (g *Graph) AllPairsCosts(labels []string) ([][]float32, error) {
+	if g.HasNegativeWeights() {
+		if cycle := g.FindNegativeCycle(); len(cycle) != 0 {
+			return nil, &NegativeCycleError{cycle}
+		}
+	}
+
+	if len(g.nodes) <= floydWarshallLimit {
+		return g.floydWarshall(labels), nil
+	}
+	return g.parallelDijkstra(labels), nil
+}
+
+// newCostMatrix constructs a size x size matrix filled with +Inf except for the diagonal
+func newCostMatrix(size int) [][]float32 {
+	inf := float32(math.Inf(1))
+	costs := make([][]float32, size)
+	for i := range costs {
+		costs[i] = make([]float32, size)
+		for j := range costs[i] {
+			if i != j {
+				costs[i][j] = inf
+			}
+		}
+	}
+	return costs
+}
+
+// floydWarshall computes the cheapest costs between every node of the graph
+// and picks the requested labels
+// The graph must have no negative cycle
+func (g *Graph) floydWarshall(labels []string) [][]float32 {
+	all := g.Labels()
+	index := make(map[string]int, len(all))
+	for i, label := range all {
+		index[label] = i
+	}
+
+	dist := newCostMatrix(len(all))
+	for i, label := range all {
+		for destination, connection := range g.nodes[label].connections {
+			j := index[destination]
+			if connection.weight < dist[i][j] {
+				dist[i][j] = connection.weight
+			}
+		}
+	}
+
+	for k := range all {
+		for i := range all {
+			for j := range all {
+				if cost := dist[i][k] + dist[k][j]; cost < dist[i][j] {
+					dist[i][j] = cost
+				}
+			}
+		}
+	}
+
+	costs := newCostMatrix(len(labels))
+	for i, origin := range labels {
+		oi, found := index[origin]
+		if !found {
+			continue
+		}
+		for j, destination := range labels {
+			if dj, found := index[destination]; found && i != j {
+				costs[i][j] = dist[oi][dj]
+			}
+		}
+	}
+	return costs
+}
+
+// parallelDijkstra runs a Dijkstra search from each label in parallel
+func (g *Graph) parallelDijkstra(labels []string) [][]float32 {
+	costs := newCostMatrix(len(labels))
+	rows := make(chan int)
+	wg := &sync.WaitGroup{}
+	for w := 0; w < runtime.NumCPU(); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range rows {
+				originNode, found := g.nodes[labels[i]]
+				if !found {
+					continue
+				}
+				nodeCost, _, _ := g.singleSource(originNode)
+				for j, destination := range labels {
+					if cost, found := nodeCost[destination]; found && i != j {
+						costs[i][j] = cost
+					}
+				}
+			}
+		}()
+	}
+
+	for i := range labels {
+		rows <- i
+	}
+	close(rows)
+	wg.Wait()
+
+	return costs
+}
+
+// dijkstra finds the cheapest cost from origin to every reachable node
+// Returns the cost and the best previous node of every reachable node
+func (g *Graph) dijkstra(origin *node) (map[string]float32, map[string]string) {
+	nodeCost := map[string]float32{origin.label: 0}
+	nodeBestOrig := make(map[string]string)
+	visited := make(map[string]bool)
+	toVisit := &nodeQueue{{origin, 0}}
+
+	for toVisit.Len() > 0 {
+		visit := heap.Pop(toVisit).(queuedNode)
+		if visited[visit.node.label] {
+			continue
+		}
+		visited[visit.node.label] = true
+
+		for label, connection := range visit.node.connections {
+			currCost, found := nodeCost[label]
+			if !found || (visit.cost+connection.weight) < currCost {
+				nodeCost[label] = visit.cost + connection.weight
+				nodeBestOrig[label] = visit.node.label
+				heap.Push(toVisit, queuedNode{connection.destination, nodeCost[label]})
+			}
+		}
+	}
+
+	return nodeCost, nodeBestOrig
+}
+
+// queuedNode is a node waiting to be visited with the cost to reach it
+type queuedNode struct {
+	node *node
+	cost float32
+}
+
+// nodeQueue implements heap.Interface as a priority queue of the cheapest node
+type nodeQueue []queuedNode
+
+func (q nodeQueue) Len() int            { return len(q) }
+func (q nodeQueue) Less(i, j int) bool  { return q[i].cost < q[j].cost }
+func (q nodeQueue) Swap(i, j int)       { q[i], q[j] = q[j], q[i] }
+func (q *nodeQueue) Push(x interface{}) { *q = append(*q, x.(queuedNode)) }
+func (q *nodeQueue) Pop() interface{} {
+	old := *q
+	item := old[len(old)-1]
+	*q = old[:len(old)-1]
+	return item
+}
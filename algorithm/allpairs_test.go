@@ -0,0 +1,105 @@
+package algorithm
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"testing"
+)
+
+func TestGraphLabels(t *testing.T) {
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	graph.Connect("BRC", "SCL", 5)
+	graph.Connect("GRU", "CDG", 75)
+
+	expected := []string{"BRC", "CDG", "GRU", "SCL"}
+	labels := graph.Labels()
+	if !equalLabels(labels, expected) {
+		t.Errorf("graph.Labels expected %v, got %v", expected, labels)
+	}
+}
+
+func TestGraphAllPairsCosts(t *testing.T) {
+	inf := float32(math.Inf(1))
+	labels := []string{"GRU", "CDG", "SCL", "asdf"}
+	expected := [][]float32{
+		{0, 40, 15, inf},
+		{inf, 0, inf, inf},
+		{inf, 25, 0, inf},
+		{inf, inf, inf, 0},
+	}
+
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	graph.Connect("BRC", "SCL", 5)
+	graph.Connect("GRU", "CDG", 75)
+	graph.Connect("GRU", "SCL", 20)
+	graph.Connect("GRU", "ORL", 56)
+	graph.Connect("ORL", "CDG", 5)
+	graph.Connect("SCL", "ORL", 20)
+
+	costs, err := graph.AllPairsCosts(labels)
+	if err != nil {
+		t.Fatalf("graph.AllPairsCosts unexpected error: %v", err)
+	}
+
+	var tests = []struct {
+		name  string
+		costs [][]float32
+	}{
+		{"AllPairsCosts", costs},
+		{"FloydWarshall", graph.floydWarshall(labels)},
+		{"ParallelDijkstra", graph.parallelDijkstra(labels)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for i := range expected {
+				for j := range expected[i] {
+					if tt.costs[i][j] != expected[i][j] {
+						t.Errorf("costs[%v][%v] expected %v, got %v", labels[i], labels[j], expected[i][j], tt.costs[i][j])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestGraphAllPairsCostsLargeGraph(t *testing.T) {
+	// A line graph too big for Floyd-Warshall
+	graph := NewGraph()
+	size := floydWarshallLimit + 10
+	for i := 1; i < size; i++ {
+		graph.Connect(fmt.Sprintf("N%03d", i-1), fmt.Sprintf("N%03d", i), 1)
+	}
+
+	labels := []string{"N000", fmt.Sprintf("N%03d", size-1)}
+	costs, err := graph.AllPairsCosts(labels)
+	if err != nil {
+		t.Fatalf("graph.AllPairsCosts unexpected error: %v", err)
+	}
+	if costs[0][1] != float32(size-1) {
+		t.Errorf("costs[0][1] expected %v, got %v", size-1, costs[0][1])
+	}
+	if !math.IsInf(float64(costs[1][0]), 1) {
+		t.Errorf("costs[1][0] expected %v, got %v", math.Inf(1), costs[1][0])
+	}
+}
+
+func TestGraphAllPairsCostsNegativeCycle(t *testing.T) {
+	graph := NewGraph()
+	graph.Connect("A", "B", 1)
+	graph.Connect("B", "C", -5)
+	graph.Connect("C", "B", 1)
+	graph.Connect("C", "D", 1)
+
+	_, err := graph.AllPairsCosts([]string{"A", "D"})
+	var cycleErr *NegativeCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("graph.AllPairsCosts expected NegativeCycleError, got %v", err)
+	}
+	if len(cycleErr.Cycle) != 3 {
+		t.Errorf("NegativeCycleError expected the cycle B > C > B, got %v", cycleErr.Cycle)
+	}
+}
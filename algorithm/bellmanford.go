@@ -0,0 +1,131 @@
+package algorithm
+
+import (
+	"fmt"
+	"strings"
+)
+
+// NegativeCycleError is returned when a search runs into a cycle whose total weight is negative
+// Such a cycle makes every path through it infinitely cheaper
+type NegativeCycleError struct {
+	// Cycle lists the node labels of the cycle, starting and ending at the same node
+	Cycle []string
+}
+
+func (e *NegativeCycleError) Error() string {
+	return fmt.Sprintf("negative cost cycle: %v", strings.Join(e.Cycle, " > "))
+}
+
+// HasNegativeWeights tells whether any connection of the graph has a negative weight
+func (g *Graph) HasNegativeWeights() bool {
+	for _, n := range g.nodes {
+		for _, connection := range n.connections {
+			if connection.weight < 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FindNegativeCycle looks for a negative cycle anywhere in the graph
+// Returns the cycle node labels, starting and ending at the same node
+// Returns an empty slice in case there is no negative cycle
+func (g *Graph) FindNegativeCycle() []string {
+	nodeCost := make(map[string]float32)
+	for label := range g.nodes {
+		nodeCost[label] = 0
+	}
+	_, cycle := g.bellmanFord(nodeCost, nil)
+	return cycle
+}
+
+// BellmanFord finds the shortest Path from origin to destination accepting negative weights
+// traversing only the legs allowed by every filter
+// Returns the list of node labels and the total cost
+// Return an empty slice and 0 in case there is no route
+// Returns a NegativeCycleError in case a negative cycle is reachable from origin
+func (g *Graph) BellmanFord(origin string, destination string, filters ...LegFilter) ([]string, float32, error) {
+	_, found := g.nodes[origin]
+	if !found {
+		return make([]string, 0), 0, nil
+	}
+	_, found = g.nodes[destination]
+	if !found {
+		return make([]string, 0), 0, nil
+	}
+
+	nodeCost := map[string]float32{origin: 0}
+	nodeBestOrig, cycle := g.bellmanFord(nodeCost, filters)
+	if len(cycle) != 0 {
+		return make([]string, 0), 0, &NegativeCycleError{cycle}
+	}
+
+	// No route to destination
+	if _, found := nodeBestOrig[destination]; !found || origin == destination {
+		return make([]string, 0), 0, nil
+	}
+
+	return buildPath(nodeBestOrig, origin, destination), nodeCost[destination], nil
+}
+
+// bellmanFord relaxes every connection starting from the nodes already in nodeCost
+// Updates nodeCost and returns the best previous node of every reached node
+// Returns a negative cycle as well in case one is reached
+func (g *Graph) bellmanFord(nodeCost map[string]float32, filters []LegFilter) (map[string]string, []string) {
+	nodeBestOrig := make(map[string]string)
+
+	// A shortest path has at most len(nodes)-1 connections,
+	// anything still improving after that is caused by a negative cycle
+	for i := 0; i < len(g.nodes); i++ {
+		relaxed, updated := g.relax(nodeCost, nodeBestOrig, filters)
+		if !updated {
+			return nodeBestOrig, make([]string, 0)
+		}
+		if i == len(g.nodes)-1 {
+			return nodeBestOrig, findCycle(nodeBestOrig, relaxed, len(g.nodes))
+		}
+	}
+
+	return nodeBestOrig, make([]string, 0)
+}
+
+// relax runs a relaxation pass over every connection of the reached nodes
+// Returns the label of a relaxed node and if any node was relaxed
+func (g *Graph) relax(nodeCost map[string]float32, nodeBestOrig map[string]string, filters []LegFilter) (string, bool) {
+	relaxed, updated := "", false
+	for label, n := range g.nodes {
+		visitCost, found := nodeCost[label]
+		if !found {
+			continue
+		}
+		for destination, connection := range n.connections {
+			_, weight, allowed := connection.cheapest(label, filters)
+			if !allowed {
+				continue
+			}
+			currCost, found := nodeCost[destination]
+			if !found || (visitCost+weight) < currCost {
+				nodeCost[destination] = visitCost + weight
+				nodeBestOrig[destination] = label
+				relaxed, updated = destination, true
+			}
+		}
+	}
+	return relaxed, updated
+}
+
+// findCycle walks back the best previous nodes from a node relaxed after len(nodes) passes
+// Returns the cycle node labels in path order, starting and ending at the same node
+func findCycle(nodeBestOrig map[string]string, relaxed string, size int) []string {
+	// Walking back len(nodes) times surely lands inside the cycle
+	for i := 0; i < size; i++ {
+		relaxed = nodeBestOrig[relaxed]
+	}
+
+	cycle := []string{relaxed}
+	for previous := nodeBestOrig[relaxed]; previous != relaxed; previous = nodeBestOrig[previous] {
+		cycle = append([]string{previous}, cycle...)
+	}
+	return append([]string{relaxed}, cycle...)
+}
@@ -0,0 +1,115 @@
+package algorithm
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestGraphHasNegativeWeights(t *testing.T) {
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	if graph.HasNegativeWeights() {
+		t.Errorf("graph.HasNegativeWeights expected %v, got %v", false, true)
+	}
+
+	graph.Connect("BRC", "SCL", -5)
+	if !graph.HasNegativeWeights() {
+		t.Errorf("graph.HasNegativeWeights expected %v, got %v", true, false)
+	}
+}
+
+func TestGraphBellmanFord(t *testing.T) {
+	var tests = []struct {
+		origin        string
+		destination   string
+		expectedRoute []string
+		expectedCost  float32
+	}{
+		{"GRU", "CDG", []string{"GRU", "BRC", "SCL", "ORL", "CDG"}, float32(10)},
+		{"GRU", "SCL", []string{"GRU", "BRC", "SCL"}, float32(-20)},
+		{"BRC", "GRU", []string{}, float32(0)},
+		{"GRU", "GRU", []string{}, float32(0)},
+		{"asfd", "CDG", []string{}, float32(0)},
+	}
+
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	graph.Connect("BRC", "SCL", -30)
+	graph.Connect("GRU", "CDG", 75)
+	graph.Connect("GRU", "SCL", 20)
+	graph.Connect("GRU", "ORL", 56)
+	graph.Connect("ORL", "CDG", 5)
+	graph.Connect("SCL", "ORL", 25)
+
+	for _, test := range tests {
+		route, cost, err := graph.BellmanFord(test.origin, test.destination)
+		if err != nil {
+			t.Fatalf("graph.BellmanFord unexpected error: %v", err)
+		}
+		if !equalLabels(route, test.expectedRoute) {
+			t.Errorf("graph.BellmanFord expected route %v, got %v", test.expectedRoute, route)
+		}
+		if cost != test.expectedCost {
+			t.Errorf("graph.BellmanFord expected cost %v, got %v", test.expectedCost, cost)
+		}
+
+		// ShortestPath falls back to Bellman-Ford
+		route, cost = graph.ShortestPath(test.origin, test.destination)
+		if !equalLabels(route, test.expectedRoute) || cost != test.expectedCost {
+			t.Errorf("graph.ShortestPath expected %v %v, got %v %v", test.expectedRoute, test.expectedCost, route, cost)
+		}
+	}
+}
+
+func TestGraphNegativeCycle(t *testing.T) {
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	graph.Connect("BRC", "SCL", 5)
+	graph.Connect("SCL", "ORL", -20)
+	graph.Connect("ORL", "BRC", 5)
+	graph.Connect("ORL", "CDG", 5)
+	graph.Connect("CDG", "FCO", 5)
+
+	expectedCycle := map[string]bool{"BRC": true, "SCL": true, "ORL": true}
+
+	cycle := graph.FindNegativeCycle()
+	if len(cycle) != 4 || cycle[0] != cycle[3] {
+		t.Fatalf("graph.FindNegativeCycle expected a 3 nodes cycle, got %v", cycle)
+	}
+	for _, label := range cycle {
+		if !expectedCycle[label] {
+			t.Errorf("graph.FindNegativeCycle unexpected node %v in %v", label, cycle)
+		}
+	}
+
+	_, _, err := graph.BellmanFord("GRU", "CDG")
+	var cycleErr *NegativeCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("graph.BellmanFord expected NegativeCycleError, got %v", err)
+	}
+	if len(cycleErr.Cycle) != 4 {
+		t.Errorf("NegativeCycleError expected a 3 nodes cycle, got %v", cycleErr.Cycle)
+	}
+
+	// Not reachable from the cycle
+	_, _, err = graph.BellmanFord("CDG", "FCO")
+	if err != nil {
+		t.Errorf("graph.BellmanFord unexpected error: %v", err)
+	}
+
+	// Must not hang
+	route, cost := graph.ShortestPath("GRU", "CDG")
+	if len(route) != 0 || cost != 0 {
+		t.Errorf("graph.ShortestPath expected no route, got %v %v", route, cost)
+	}
+	routes, _, err := graph.ShortestPathTree("GRU")
+	if len(routes) != 0 || !errors.As(err, &cycleErr) {
+		t.Errorf("graph.ShortestPathTree expected no route and a NegativeCycleError, got %v %v", routes, err)
+	}
+
+	graph = NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	if cycle := graph.FindNegativeCycle(); len(cycle) != 0 {
+		t.Errorf("graph.FindNegativeCycle expected no cycle, got %v", cycle)
+	}
+}
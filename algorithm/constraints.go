@@ -0,0 +1,40 @@
+package algorithm
+
+// AvoidNodes builds a LegFilter masking the nodes, so no search goes through them
+func AvoidNodes(labels ...string) LegFilter {
+	avoided := make(map[string]bool, len(labels))
+	for _, label := range labels {
+		avoided[label] = true
+	}
+	return func(origin string, destination string, leg string) bool {
+		return !avoided[origin] && !avoided[destination]
+	}
+}
+
+// ShortestPathVia finds the shortest Path from origin to destination stopping at every via node in order
+// traversing only the legs allowed by every filter
+// Each stretch between stops is solved by ShortestPath and chained to the next one
+// Returns the list of node labels and the total cost
+// Return an empty slice and 0 in case any stretch has no route
+func (g *Graph) ShortestPathVia(origin string, destination string, via []string, filters ...LegFilter) ([]string, float32) {
+	stops := append(append([]string{origin}, via...), destination)
+
+	route := []string{origin}
+	var cost float32
+	for i := 1; i < len(stops); i++ {
+		if stops[i-1] == stops[i] {
+			continue
+		}
+		stretch, stretchCost := g.ShortestPath(stops[i-1], stops[i], filters...)
+		if len(stretch) == 0 {
+			return make([]string, 0), 0
+		}
+		route = append(route, stretch[1:]...)
+		cost += stretchCost
+	}
+
+	if len(route) < 2 {
+		return make([]string, 0), 0
+	}
+	return route, cost
+}
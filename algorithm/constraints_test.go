@@ -0,0 +1,47 @@
+package algorithm
+
+import "testing"
+
+func TestGraphConstrainedShortestPath(t *testing.T) {
+	var tests = []struct {
+		name          string
+		destination   string
+		via           []string
+		avoid         []string
+		expectedRoute []string
+		expectedCost  float32
+	}{
+		{"NoConstraint", "CDG", []string{}, []string{}, []string{"GRU", "BRC", "SCL", "ORL", "CDG"}, 40},
+		{"AvoidORL", "CDG", []string{}, []string{"ORL"}, []string{"GRU", "CDG"}, 75},
+		{"AvoidBRC", "CDG", []string{}, []string{"BRC"}, []string{"GRU", "SCL", "ORL", "CDG"}, 45},
+		{"ViaSCL", "ORL", []string{"SCL"}, []string{}, []string{"GRU", "BRC", "SCL", "ORL"}, 35},
+		{"ViaCDGAndBack", "ORL", []string{"CDG"}, []string{}, []string{"GRU", "BRC", "SCL", "ORL", "CDG", "GRU", "BRC", "SCL", "ORL"}, 125},
+		{"ViaAvoided", "CDG", []string{"ORL"}, []string{"ORL"}, []string{}, 0},
+		{"ViaUnreachable", "CDG", []string{"FCO"}, []string{}, []string{}, 0},
+		{"ViaOrigin", "BRC", []string{"GRU"}, []string{}, []string{"GRU", "BRC"}, 10},
+		{"AvoidDestination", "CDG", []string{}, []string{"CDG"}, []string{}, 0},
+	}
+
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	graph.Connect("BRC", "SCL", 5)
+	graph.Connect("GRU", "CDG", 75)
+	graph.Connect("GRU", "SCL", 20)
+	graph.Connect("GRU", "ORL", 56)
+	graph.Connect("ORL", "CDG", 5)
+	graph.Connect("SCL", "ORL", 20)
+	graph.Connect("CDG", "GRU", 50)
+	graph.Connect("FCO", "CDG", 5)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, cost := graph.ShortestPathVia("GRU", tt.destination, tt.via, AvoidNodes(tt.avoid...))
+			if !equalLabels(route, tt.expectedRoute) {
+				t.Errorf("graph.ShortestPathVia expected route %v, got %v", tt.expectedRoute, route)
+			}
+			if cost != tt.expectedCost {
+				t.Errorf("graph.ShortestPathVia expected cost %v, got %v", tt.expectedCost, cost)
+			}
+		})
+	}
+}
@@ -17,6 +17,13 @@ func NewGraph() *Graph {
 
 // Connect makes a connection between origin and destination with the weigth
 func (g *Graph) Connect(origin string, destination string, weigth float32) {
+	g.ConnectLeg(origin, destination, "", weigth)
+}
+
+// ConnectLeg makes a connection between origin and destination through the leg with the weigth
+// The same origin and destination may be connected by several legs, searches use the cheapest one
+// Connecting the same leg again replaces its weigth
+func (g *Graph) ConnectLeg(origin string, destination string, leg string, weigth float32) {
 	originNode, found := g.nodes[origin]
 	if !found {
 		originNode = newNode(origin)
@@ -29,13 +36,45 @@ func (g *Graph) Connect(origin string, destination string, weigth float32) {
 		g.nodes[destination] = destinationNode
 	}
 
-	originNode.connect(destinationNode, weigth)
+	originNode.connectLeg(destinationNode, leg, weigth)
+}
+
+// LegFilter tells whether a search may traverse the leg connecting origin to destination
+type LegFilter func(origin string, destination string, leg string) bool
+
+// PathLegs finds the cheapest leg of each hop of route allowed by every filter
+// Returns the legs, one less than the route nodes
+func (g *Graph) PathLegs(route []string, filters ...LegFilter) []string {
+	legs := make([]string, 0, len(route))
+	for i := 1; i < len(route); i++ {
+		originNode, found := g.nodes[route[i-1]]
+		if !found {
+			return make([]string, 0)
+		}
+		connection, found := originNode.connections[route[i]]
+		if !found {
+			return make([]string, 0)
+		}
+		leg, _, allowed := connection.cheapest(originNode.label, filters)
+		if !allowed {
+			return make([]string, 0)
+		}
+		legs = append(legs, leg)
+	}
+	return legs
 }
 
 // ShortestPath finds the shortest Path from origin to destination
+// traversing only the legs allowed by every filter
 // Returns the list of node labels and the total cost
 // Return an empty slice and 0 in case there is no route
-func (g *Graph) ShortestPath(origin string, destination string) ([]string, float32) {
+// Graphs with negative weights are searched by BellmanFord, a negative cycle counts as no route
+func (g *Graph) ShortestPath(origin string, destination string, filters ...LegFilter) ([]string, float32) {
+	if g.HasNegativeWeights() {
+		route, cost, _ := g.BellmanFord(origin, destination, filters...)
+		return route, cost
+	}
+
 	// Invalid input
 	originNode, found := g.nodes[origin]
 	if !found {
@@ -54,8 +93,12 @@ func (g *Graph) ShortestPath(origin string, destination string) ([]string, float
 	// Fisrt pass over connections
 	nodeCost[origin] = 0
 	for label, connection := range originNode.connections {
+		_, weight, allowed := connection.cheapest(originNode.label, filters)
+		if !allowed {
+			continue
+		}
 		toVisit.PushBack(connection.destination)
-		nodeCost[label] = connection.weight
+		nodeCost[label] = weight
 		nodeBestOrig[label] = originNode.label
 	}
 
@@ -64,13 +107,17 @@ func (g *Graph) ShortestPath(origin string, destination string) ([]string, float
 		visitCost, _ := nodeCost[n.Value.(*node).label]
 		visitLabel := n.Value.(*node).label
 		for label, connection := range n.Value.(*node).connections {
+			_, weight, allowed := connection.cheapest(visitLabel, filters)
+			if !allowed {
+				continue
+			}
 			currCost, found := nodeCost[label]
 			// New or better connection
-			if !found || (visitCost+connection.weight) < currCost {
+			if !found || (visitCost+weight) < currCost {
 				// Needs a first or extra visit
 				toVisit.PushBack(connection.destination)
 				// Update cost and best route
-				nodeCost[label] = visitCost + connection.weight
+				nodeCost[label] = visitCost + weight
 				nodeBestOrig[label] = visitLabel
 			}
 		}
@@ -80,49 +127,148 @@ func (g *Graph) ShortestPath(origin string, destination string) ([]string, float
 		toVisit.Remove(oldN)
 	}
 
-	// Reverse best route from destination
-	BestOrigin, found := nodeBestOrig[destination]
-	if !found {
-		// No route to destination
+	// No route to destination
+	if _, found := nodeBestOrig[destination]; !found {
 		return make([]string, 0), 0
 	}
 
+	return buildPath(nodeBestOrig, origin, destination), nodeCost[destination]
+}
+
+// ShortestPathTree finds the shortest path from origin to every reachable node
+// Returns the list of node labels and the total cost of each reachable node, indexed by its label
+// The origin itself is not included
+// Returns a NegativeCycleError in case a negative cycle is reachable from origin
+func (g *Graph) ShortestPathTree(origin string) (map[string][]string, map[string]float32, error) {
+	routes := make(map[string][]string)
+	costs := make(map[string]float32)
+	originNode, found := g.nodes[origin]
+	if !found {
+		return routes, costs, nil
+	}
+
+	nodeCost, nodeBestOrig, cycle := g.singleSource(originNode)
+	if len(cycle) != 0 {
+		return routes, costs, &NegativeCycleError{cycle}
+	}
+	for label := range nodeBestOrig {
+		if label == origin {
+			continue
+		}
+		routes[label] = buildPath(nodeBestOrig, origin, label)
+		costs[label] = nodeCost[label]
+	}
+	return routes, costs, nil
+}
+
+// singleSource finds the cheapest cost from origin to every reachable node
+// Graphs with negative weights are searched by Bellman-Ford, a negative cycle counts as no route
+// Returns the cost and the best previous node of every reachable node,
+// along with the negative cycle reachable from origin, if any
+func (g *Graph) singleSource(origin *node) (map[string]float32, map[string]string, []string) {
+	if !g.HasNegativeWeights() {
+		nodeCost, nodeBestOrig := g.dijkstra(origin)
+		return nodeCost, nodeBestOrig, nil
+	}
+
+	nodeCost := map[string]float32{origin.label: 0}
+	nodeBestOrig, cycle := g.bellmanFord(nodeCost, nil)
+	if len(cycle) != 0 {
+		return map[string]float32{origin.label: 0}, make(map[string]string), cycle
+	}
+	return nodeCost, nodeBestOrig, nil
+}
+
+// buildPath reverses the best route from destination back to origin
+func buildPath(nodeBestOrig map[string]string, origin string, destination string) []string {
 	route := []string{destination}
+	BestOrigin := nodeBestOrig[destination]
 	for BestOrigin != origin {
 		route = append([]string{BestOrigin}, route...)
 		BestOrigin, _ = nodeBestOrig[BestOrigin]
 	}
 	route = append([]string{BestOrigin}, route...)
-
-	return route, nodeCost[destination]
+	return route
 }
 
 // connection represents a weighted oriented conenection
+// It holds every leg between its nodes and weighs as the cheapest of them
 type connection struct {
 	destination *node
 	weight      float32
+	leg         string
+	legs        map[string]float32
 }
 
 func newConnection(destination *node, weight float32) *connection {
-	return &connection{destination: destination, weight: weight}
+	return &connection{destination: destination, weight: weight, legs: make(map[string]float32)}
+}
+
+// setLeg adds or replaces a leg and updates the cheapest leg
+// Ties are broken by the leg name so the choice does not depend on insertion order
+func (c *connection) setLeg(leg string, weight float32) {
+	c.legs[leg] = weight
+
+	first := true
+	for label, legWeight := range c.legs {
+		if first || legWeight < c.weight || (legWeight == c.weight && label < c.leg) {
+			c.leg, c.weight = label, legWeight
+			first = false
+		}
+	}
+}
+
+// cheapest finds the cheapest leg allowed by every filter
+// Returns the leg, its weight and false in case no leg is allowed
+func (c *connection) cheapest(origin string, filters []LegFilter) (string, float32, bool) {
+	if len(filters) == 0 {
+		return c.leg, c.weight, true
+	}
+
+	leg, weight, allowed := "", float32(0), false
+	for label, legWeight := range c.legs {
+		if !allowedLeg(filters, origin, c.destination.label, label) {
+			continue
+		}
+		if !allowed || legWeight < weight || (legWeight == weight && label < leg) {
+			leg, weight, allowed = label, legWeight, true
+		}
+	}
+	return leg, weight, allowed
+}
+
+// allowedLeg tells whether every filter allows the leg
+func allowedLeg(filters []LegFilter, origin string, destination string, leg string) bool {
+	for _, filter := range filters {
+		if !filter(origin, destination, leg) {
+			return false
+		}
+	}
+	return true
 }
 
 // node represents a graph elemment that has weighted oriented conenections
 type node struct {
 	label       string
 	connections map[string]*connection
+	// inbound holds the nodes connected to this one, indexed by their label
+	inbound map[string]*node
 }
 
 func newNode(label string) *node {
-	return &node{label: label, connections: make(map[string]*connection)}
+	return &node{label: label, connections: make(map[string]*connection), inbound: make(map[string]*node)}
 }
 
 func (n *node) connect(destination *node, weigth float32) {
+	n.connectLeg(destination, "", weigth)
+}
+
+func (n *node) connectLeg(destination *node, leg string, weigth float32) {
 	connection, found := n.connections[destination.label]
 	if !found {
 		connection = newConnection(destination, weigth)
 		n.connections[destination.label] = connection
-	} else {
-		connection.weight = weigth
+		destination.inbound[n.label] = n
 	}
+	connection.setLeg(leg, weigth)
 }
@@ -120,3 +120,158 @@ func TestGraphShortestPath(t *testing.T) {
 		}
 	}
 }
+
+func TestGraphShortestPathTree(t *testing.T) {
+	var tests = []struct {
+		origin         string
+		expectedRoutes map[string][]string
+		expectedCosts  map[string]float32
+	}{
+		{"BRC",
+			map[string][]string{
+				"SCL": {"BRC", "SCL"},
+				"ORL": {"BRC", "SCL", "ORL"},
+				"CDG": {"BRC", "SCL", "ORL", "CDG"}},
+			map[string]float32{"SCL": 5, "ORL": 25, "CDG": 30}},
+		{"CDG", map[string][]string{}, map[string]float32{}},
+		{"asdf", map[string][]string{}, map[string]float32{}},
+	}
+
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	graph.Connect("BRC", "SCL", 5)
+	graph.Connect("GRU", "CDG", 75)
+	graph.Connect("GRU", "SCL", 20)
+	graph.Connect("GRU", "ORL", 56)
+	graph.Connect("ORL", "CDG", 5)
+	graph.Connect("SCL", "ORL", 20)
+
+	for _, test := range tests {
+		routes, costs, err := graph.ShortestPathTree(test.origin)
+		if err != nil {
+			t.Fatalf("graph.ShortestPathTree unexpected error: %v", err)
+		}
+		if len(routes) != len(test.expectedRoutes) || len(costs) != len(test.expectedCosts) {
+			t.Fatalf("graph.ShortestPathTree expected %v destinations, got %v", len(test.expectedRoutes), len(routes))
+		}
+
+		for label, expectedRoute := range test.expectedRoutes {
+			if !equalLabels(routes[label], expectedRoute) {
+				t.Errorf("graph.ShortestPathTree expected route %v, got %v", expectedRoute, routes[label])
+			}
+			if costs[label] != test.expectedCosts[label] {
+				t.Errorf("graph.ShortestPathTree expected cost %v, got %v", test.expectedCosts[label], costs[label])
+			}
+		}
+	}
+}
+
+func TestNodeConnectLegs(t *testing.T) {
+	nodeOrig := newNode("GRU")
+	nodeDest := newNode("CDG")
+
+	var tests = []struct {
+		leg          string
+		weight       float32
+		expectedLeg  string
+		expectedCost float32
+		expectedLegs int
+	}{
+		{"AF", 75, "AF", 75, 1},
+		{"LA", 60, "LA", 60, 2},
+		{"AF", 50, "AF", 50, 2},
+		{"AF", 90, "LA", 60, 2},
+		{"", 60, "", 60, 3},
+	}
+
+	for _, test := range tests {
+		nodeOrig.connectLeg(nodeDest, test.leg, test.weight)
+		if len(nodeOrig.connections) != 1 {
+			t.Fatalf("node.connections expected size %v, got %v", 1, len(nodeOrig.connections))
+		}
+
+		connection := nodeOrig.connections[nodeDest.label]
+		if connection.leg != test.expectedLeg || connection.weight != test.expectedCost {
+			t.Errorf("connection expected leg %v with %v, got %v with %v", test.expectedLeg, test.expectedCost, connection.leg, connection.weight)
+		}
+		if len(connection.legs) != test.expectedLegs {
+			t.Errorf("connection.legs expected size %v, got %v", test.expectedLegs, len(connection.legs))
+		}
+	}
+}
+
+func TestGraphPathLegs(t *testing.T) {
+	graph := NewGraph()
+	graph.ConnectLeg("GRU", "BRC", "G3", 10)
+	graph.ConnectLeg("GRU", "BRC", "LA", 8)
+	graph.ConnectLeg("BRC", "SCL", "LA", 5)
+	graph.ConnectLeg("GRU", "SCL", "LA", 20)
+	graph.ConnectLeg("GRU", "SCL", "JJ", 12)
+
+	route, cost := graph.ShortestPath("GRU", "SCL")
+	if !equalLabels(route, []string{"GRU", "SCL"}) || cost != 12 {
+		t.Errorf("graph.ShortestPath expected %v %v, got %v %v", []string{"GRU", "SCL"}, 12, route, cost)
+	}
+
+	var tests = []struct {
+		route        []string
+		expectedLegs []string
+	}{
+		{[]string{"GRU", "BRC", "SCL"}, []string{"LA", "LA"}},
+		{[]string{"GRU", "SCL"}, []string{"JJ"}},
+		{[]string{"GRU"}, []string{}},
+		{[]string{"SCL", "GRU"}, []string{}},
+	}
+
+	for _, test := range tests {
+		legs := graph.PathLegs(test.route)
+		if !equalLabels(legs, test.expectedLegs) {
+			t.Errorf("graph.PathLegs expected %v, got %v", test.expectedLegs, legs)
+		}
+	}
+}
+
+func TestGraphShortestPathFiltered(t *testing.T) {
+	graph := NewGraph()
+	graph.ConnectLeg("GRU", "CDG", "AF", 75)
+	graph.ConnectLeg("GRU", "CDG", "LA", 60)
+	graph.ConnectLeg("GRU", "SCL", "LA", 10)
+	graph.ConnectLeg("SCL", "CDG", "JJ", 40)
+
+	notLA := func(origin string, destination string, leg string) bool { return leg != "LA" }
+	onlyLA := func(origin string, destination string, leg string) bool { return leg == "LA" }
+	notSCL := func(origin string, destination string, leg string) bool { return destination != "SCL" }
+
+	var tests = []struct {
+		name          string
+		filters       []LegFilter
+		expectedRoute []string
+		expectedLegs  []string
+		expectedCost  float32
+	}{
+		{"NoFilter", []LegFilter{}, []string{"GRU", "SCL", "CDG"}, []string{"LA", "JJ"}, 50},
+		{"Exclude", []LegFilter{notLA}, []string{"GRU", "CDG"}, []string{"AF"}, 75},
+		{"Include", []LegFilter{onlyLA}, []string{"GRU", "CDG"}, []string{"LA"}, 60},
+		{"Combined", []LegFilter{notLA, notSCL}, []string{"GRU", "CDG"}, []string{"AF"}, 75},
+		{"Nothing", []LegFilter{notLA, onlyLA}, []string{}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			route, cost := graph.ShortestPath("GRU", "CDG", tt.filters...)
+			if !equalLabels(route, tt.expectedRoute) || cost != tt.expectedCost {
+				t.Errorf("graph.ShortestPath expected %v %v, got %v %v", tt.expectedRoute, tt.expectedCost, route, cost)
+			}
+
+			legs := graph.PathLegs(route, tt.filters...)
+			if !equalLabels(legs, tt.expectedLegs) {
+				t.Errorf("graph.PathLegs expected %v, got %v", tt.expectedLegs, legs)
+			}
+
+			route, cost, err := graph.BellmanFord("GRU", "CDG", tt.filters...)
+			if err != nil || !equalLabels(route, tt.expectedRoute) || cost != tt.expectedCost {
+				t.Errorf("graph.BellmanFord expected %v %v, got %v %v %v", tt.expectedRoute, tt.expectedCost, route, cost, err)
+			}
+		})
+	}
+}
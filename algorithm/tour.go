@@ -0,0 +1,212 @@
+package algorithm
+
+import "math"
+
+// heldKarpLimit is the biggest amount of cities solved exactly by Held-Karp
+// Bigger sets are solved by the nearest neighbour heuristic improved by 2-opt
+const heldKarpLimit = 12
+
+// Tour represents an order to visit a set of nodes
+type Tour struct {
+	// Order lists the requested nodes in visiting order
+	Order []string
+	// Route lists every node traversed by the tour
+	Route []string
+	Cost  float32
+}
+
+// CheapestTour finds the cheapest order to visit every city starting from the first one
+// When roundTrip is set the tour goes back to the first city in the end
+// Returns a Tour with empty slices and 0 in case there is no tour
+func (g *Graph) CheapestTour(cities []string, roundTrip bool) Tour {
+	cities = uniqueLabels(cities)
+	for _, city := range cities {
+		if _, found := g.nodes[city]; !found {
+			return Tour{Order: make([]string, 0), Route: make([]string, 0)}
+		}
+	}
+	if len(cities) < 2 {
+		return Tour{Order: cities, Route: cities}
+	}
+
+	paths, costs := g.pairwisePaths(cities)
+
+	var order []int
+	if len(cities) <= heldKarpLimit {
+		order = heldKarp(costs, roundTrip)
+	} else {
+		order = twoOpt(costs, nearestNeighbour(costs), roundTrip)
+	}
+
+	cost := tourCost(costs, order, roundTrip)
+	if order == nil || math.IsInf(float64(cost), 1) {
+		return Tour{Order: make([]string, 0), Route: make([]string, 0)}
+	}
+
+	tour := Tour{Order: make([]string, 0, len(order)), Route: []string{cities[order[0]]}, Cost: cost}
+	for i, city := range order {
+		tour.Order = append(tour.Order, cities[city])
+		if i > 0 {
+			tour.Route = append(tour.Route, paths[order[i-1]][city][1:]...)
+		}
+	}
+	if roundTrip {
+		tour.Route = append(tour.Route, paths[order[len(order)-1]][order[0]][1:]...)
+	}
+
+	return tour
+}
+
+// uniqueLabels removes repeated labels keeping the first occurrence
+func uniqueLabels(labels []string) []string {
+	seen := make(map[string]bool)
+	unique := make([]string, 0, len(labels))
+	for _, label := range labels {
+		if !seen[label] {
+			seen[label] = true
+			unique = append(unique, label)
+		}
+	}
+	return unique
+}
+
+// pairwisePaths computes the shortest path between every pair of cities
+// Returns the paths and their costs, +Inf when there is no path
+func (g *Graph) pairwisePaths(cities []string) ([][][]string, [][]float32) {
+	inf := float32(math.Inf(1))
+	paths := make([][][]string, len(cities))
+	costs := make([][]float32, len(cities))
+	for i, origin := range cities {
+		paths[i] = make([][]string, len(cities))
+		costs[i] = make([]float32, len(cities))
+		for j, destination := range cities {
+			if i == j {
+				continue
+			}
+			paths[i][j], costs[i][j] = g.ShortestPath(origin, destination)
+			if len(paths[i][j]) == 0 {
+				costs[i][j] = inf
+			}
+		}
+	}
+	return paths, costs
+}
+
+// tourCost sums the costs of visiting the cities in order
+func tourCost(costs [][]float32, order []int, roundTrip bool) float32 {
+	var cost float32
+	for i := 1; i < len(order); i++ {
+		cost += costs[order[i-1]][order[i]]
+	}
+	if roundTrip && len(order) > 0 {
+		cost += costs[order[len(order)-1]][order[0]]
+	}
+	return cost
+}
+
+// heldKarp solves exactly the cheapest visiting order starting from city 0
+// Returns nil in case there is no order visiting every city
+func heldKarp(costs [][]float32, roundTrip bool) []int {
+	n := len(costs)
+	inf := float32(math.Inf(1))
+	full := 1<<uint(n) - 1
+
+	// best[mask][j] is the cost of starting at 0, visiting the cities in mask and ending at j
+	best := make([][]float32, full+1)
+	prev := make([][]int, full+1)
+	for mask := range best {
+		best[mask] = make([]float32, n)
+		prev[mask] = make([]int, n)
+		for j := range best[mask] {
+			best[mask][j] = inf
+			prev[mask][j] = -1
+		}
+	}
+	best[1][0] = 0
+
+	for mask := 1; mask <= full; mask += 2 {
+		for j := 0; j < n; j++ {
+			if mask&(1<<uint(j)) == 0 || math.IsInf(float64(best[mask][j]), 1) {
+				continue
+			}
+			for k := 1; k < n; k++ {
+				if mask&(1<<uint(k)) != 0 {
+					continue
+				}
+				next := mask | 1<<uint(k)
+				if cost := best[mask][j] + costs[j][k]; cost < best[next][k] {
+					best[next][k] = cost
+					prev[next][k] = j
+				}
+			}
+		}
+	}
+
+	last, lastCost := -1, inf
+	for j := 1; j < n; j++ {
+		cost := best[full][j]
+		if roundTrip {
+			cost += costs[j][0]
+		}
+		if cost < lastCost {
+			last, lastCost = j, cost
+		}
+	}
+	if last == -1 {
+		return nil
+	}
+
+	// Reverse the order from the last city
+	order := make([]int, n)
+	for i, mask := n-1, full; i >= 0; i-- {
+		order[i] = last
+		last, mask = prev[mask][last], mask&^(1<<uint(last))
+	}
+	return order
+}
+
+// nearestNeighbour builds a visiting order starting from city 0 always going to the cheapest unvisited city
+func nearestNeighbour(costs [][]float32) []int {
+	visited := make([]bool, len(costs))
+	order := []int{0}
+	visited[0] = true
+	for len(order) < len(costs) {
+		current := order[len(order)-1]
+		next := -1
+		for j := range costs {
+			if !visited[j] && (next == -1 || costs[current][j] < costs[current][next]) {
+				next = j
+			}
+		}
+		visited[next] = true
+		order = append(order, next)
+	}
+	return order
+}
+
+// twoOpt improves the visiting order by reversing sections while it gets cheaper
+// The first city is kept in place
+func twoOpt(costs [][]float32, order []int, roundTrip bool) []int {
+	bestCost := tourCost(costs, order, roundTrip)
+	for improved := true; improved; {
+		improved = false
+		for i := 1; i < len(order)-1; i++ {
+			for j := i + 1; j < len(order); j++ {
+				reverse(order[i : j+1])
+				if cost := tourCost(costs, order, roundTrip); cost < bestCost {
+					bestCost = cost
+					improved = true
+				} else {
+					reverse(order[i : j+1])
+				}
+			}
+		}
+	}
+	return order
+}
+
+func reverse(order []int) {
+	for i, j := 0, len(order)-1; i < j; i, j = i+1, j-1 {
+		order[i], order[j] = order[j], order[i]
+	}
+}
@@ -0,0 +1,112 @@
+package algorithm
+
+import (
+	"fmt"
+	"testing"
+)
+
+func equalLabels(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestCheapestTour(t *testing.T) {
+	var tests = []struct {
+		name          string
+		cities        []string
+		roundTrip     bool
+		expectedOrder []string
+		expectedRoute []string
+		expectedCost  float32
+	}{
+		{"RoundTrip", []string{"GRU", "CDG", "SCL"}, true,
+			[]string{"GRU", "SCL", "CDG"}, []string{"GRU", "BRC", "SCL", "ORL", "CDG", "GRU"}, float32(90)},
+		{"OneWay", []string{"GRU", "CDG", "SCL"}, false,
+			[]string{"GRU", "SCL", "CDG"}, []string{"GRU", "BRC", "SCL", "ORL", "CDG"}, float32(40)},
+		{"Repeated", []string{"GRU", "BRC", "GRU", "BRC"}, false,
+			[]string{"GRU", "BRC"}, []string{"GRU", "BRC"}, float32(10)},
+		{"NoTour", []string{"GRU", "AAA"}, false, []string{}, []string{}, float32(0)},
+		{"Unknown", []string{"GRU", "asdf"}, false, []string{}, []string{}, float32(0)},
+	}
+
+	graph := NewGraph()
+	graph.Connect("GRU", "BRC", 10)
+	graph.Connect("BRC", "SCL", 5)
+	graph.Connect("GRU", "CDG", 75)
+	graph.Connect("GRU", "SCL", 20)
+	graph.Connect("GRU", "ORL", 56)
+	graph.Connect("ORL", "CDG", 5)
+	graph.Connect("SCL", "ORL", 20)
+	graph.Connect("CDG", "GRU", 50)
+	graph.Connect("AAA", "BBB", 1)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := graph.CheapestTour(tt.cities, tt.roundTrip)
+			if !equalLabels(tour.Order, tt.expectedOrder) {
+				t.Errorf("graph.CheapestTour expected order %v, got %v", tt.expectedOrder, tour.Order)
+			}
+			if !equalLabels(tour.Route, tt.expectedRoute) {
+				t.Errorf("graph.CheapestTour expected route %v, got %v", tt.expectedRoute, tour.Route)
+			}
+			if tour.Cost != tt.expectedCost {
+				t.Errorf("graph.CheapestTour expected cost %v, got %v", tt.expectedCost, tour.Cost)
+			}
+		})
+	}
+}
+
+func TestCheapestTourHeuristic(t *testing.T) {
+	// A ring where the cheapest way around is the sequential order, but the cities are requested shuffled
+	graph := NewGraph()
+	cities := make([]string, 0)
+	size := heldKarpLimit + 4
+	for i := 0; i < size; i++ {
+		cities = append(cities, fmt.Sprintf("C%02d", (i*7)%size))
+	}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if i == j {
+				continue
+			}
+			weight := float32(100)
+			if j == (i+1)%size {
+				weight = 1
+			}
+			graph.Connect(fmt.Sprintf("C%02d", i), fmt.Sprintf("C%02d", j), weight)
+		}
+	}
+
+	tour := graph.CheapestTour(cities, true)
+	if len(tour.Order) != size {
+		t.Fatalf("graph.CheapestTour expected %v cities, got %v", size, len(tour.Order))
+	}
+	if tour.Cost != float32(size) {
+		t.Errorf("graph.CheapestTour expected cost %v, got %v", size, tour.Cost)
+	}
+}
+
+func TestHeldKarpMatchesTwoOpt(t *testing.T) {
+	costs := [][]float32{
+		{0, 2, 9, 10},
+		{1, 0, 6, 4},
+		{15, 7, 0, 8},
+		{6, 3, 12, 0},
+	}
+
+	exact := heldKarp(costs, true)
+	heuristic := twoOpt(costs, nearestNeighbour(costs), true)
+	if tourCost(costs, exact, true) != 21 {
+		t.Errorf("heldKarp expected cost %v, got %v", 21, tourCost(costs, exact, true))
+	}
+	if tourCost(costs, heuristic, true) < tourCost(costs, exact, true) {
+		t.Errorf("twoOpt cost %v is cheaper than the exact %v", tourCost(costs, heuristic, true), tourCost(costs, exact, true))
+	}
+}
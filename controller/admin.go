@@ -0,0 +1,39 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"net/http"
+	"time"
+)
+
+type compactResponse struct {
+	LinesDropped int
+}
+
+// compactHandler handles requests directed to "/admin/compact"
+func (ws *webServer) compactHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		retention := dal.DefaultRetention
+		if param := r.FormValue("retention"); param != "" {
+			var err error
+			if retention, err = time.ParseDuration(param); err != nil || retention < 0 {
+				invalidParam(w, "retention", param)
+				return
+			}
+		}
+
+		dropped, err := ws.routeDB.Compact(retention)
+		if err == dal.ErrNotFileBacked {
+			writeError(w, http.StatusConflict, codeNotFileBacked, err.Error(), nil)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, compactResponse{dropped})
+	default:
+		methodNotAllowed(w, r)
+	}
+}
@@ -0,0 +1,126 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompact(t *testing.T) {
+	dir, err := ioutil.TempDir("", "routes")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "routes.csv")
+	if err := ioutil.WriteFile(path, []byte("GRU,BRC,10\nBRC,SCL,5\nGRU,BRC,12\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+
+	routeDB, err := dal.OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("dal.OpenFileDB error: %v", err)
+	}
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("BRC", "SCL", 6))
+
+	status, body := postJSON(t, "/admin/compact?retention=0s", nil)
+	if status != http.StatusOK {
+		t.Errorf("/admin/compact expected status %v, got %v", http.StatusOK, status)
+	}
+	if expected := `{"LinesDropped":2}`; body != expected {
+		t.Errorf("/admin/compact expected %v, got %v", expected, body)
+	}
+
+	routes := routeDB.GetRoutes()
+	if len(routes) != 2 {
+		t.Errorf("getRoutes expected size 2, got %v", len(routes))
+	}
+
+	status, _ = getBody(t, "/admin/compact")
+	if status != http.StatusMethodNotAllowed {
+		t.Errorf("/admin/compact expected status %v, got %v", http.StatusMethodNotAllowed, status)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestCompactKeepsHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "routes")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "routes.csv")
+	if err := ioutil.WriteFile(path, []byte("GRU,BRC,10\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+
+	routeDB, err := dal.OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("dal.OpenFileDB error: %v", err)
+	}
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 12))
+	recorded, _ := json.Marshal(routeDB.GetRoutes()[1].Time)
+	asOf := url.QueryEscape(time.Now().Format(time.RFC3339Nano))
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 14))
+
+	var tests = []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{"InvalidRetention", "/admin/compact?retention=week", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'retention' param: week","details":{"param":"retention","value":"week"}}`},
+		{"Compact", "/admin/compact?retention=1h", http.StatusOK, `{"LinesDropped":0}`},
+	}
+	for _, tt := range tests {
+		if status, body := postJSON(t, tt.path, nil); status != tt.expectStatus || body != tt.expectBody {
+			t.Errorf("%v expected %v %v, got %v %v", tt.name, tt.expectStatus, tt.expectBody, status, body)
+		}
+	}
+
+	// The versions recorded within the retention are kept, along with when they were recorded
+	expected := `[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"GRU","Destination":"BRC","Cost":12,"Time":` + string(recorded) + `}]`
+	if status, body := getBody(t, "/route?AsOf="+asOf); status != http.StatusOK || body != expected {
+		t.Errorf("/route expected %v, got %v %v", expected, status, body)
+	}
+
+	status, body := getBody(t, "/route/best?Origin=GRU&Destination=BRC&AsOf=2000-01-01T00:00:00Z")
+	if prefix := `{"code":"invalid_param","message":"Invalid 'AsOf' param: history compacted`; status != http.StatusBadRequest || !strings.HasPrefix(body, prefix) {
+		t.Errorf("/route/best expected %v, got %v %v", prefix, status, body)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestCompactNotFileBacked(t *testing.T) {
+	srv := StartWebServer(dal.NewDB(&bytes.Buffer{}), 8080)
+
+	status, _ := postJSON(t, "/admin/compact", nil)
+	if status != http.StatusConflict {
+		t.Errorf("/admin/compact expected status %v, got %v", http.StatusConflict, status)
+	}
+
+	StopWebServer(srv)
+}
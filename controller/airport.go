@@ -0,0 +1,55 @@
+package controller
+
+import (
+	"TravelRoute/domain"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// airportsHandler handles requests directed to "/airport"
+func (ws *webServer) airportsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		routes, ok := ws.routesAsOf(w, r)
+		if !ok {
+			return
+		}
+		writeJSON(w, http.StatusOK, domain.ListAirports(routes))
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// airportHandler handles requests directed to "/airport/{code}/outbound" and "/airport/{code}/inbound"
+func (ws *webServer) airportHandler(w http.ResponseWriter, r *http.Request) {
+	parts := strings.Split(strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, apiPrefix), "/airport/"), "/")
+	if len(parts) != 2 || parts[0] == "" || (parts[1] != "outbound" && parts[1] != "inbound") {
+		notFoundHandler(w, r)
+		return
+	}
+	code, direction := parts[0], parts[1]
+
+	switch r.Method {
+	case http.MethodGet:
+		routes, ok := ws.routesAsOf(w, r)
+		if !ok {
+			return
+		}
+
+		airport, found := domain.FindAirport(routes, code)
+		if !found {
+			writeError(w, http.StatusNotFound, codeAirportNotFound, fmt.Sprintf("No route connects %v", code),
+				map[string]string{"code": code})
+			return
+		}
+
+		if direction == "outbound" {
+			writeJSON(w, http.StatusOK, airport.Outbound)
+		} else {
+			writeJSON(w, http.StatusOK, airport.Inbound)
+		}
+	default:
+		methodNotAllowed(w, r)
+	}
+}
@@ -0,0 +1,59 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestAirport(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, dal.Route{Origin: "BRC", Destination: "SCL", Cost: 5, Bidirectional: true})
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+
+	var tests = []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{"List", "/airport", http.StatusOK,
+			`[{"Code":"BRC","OutDegree":1,"InDegree":2},{"Code":"CDG","OutDegree":0,"InDegree":1},` +
+				`{"Code":"GRU","OutDegree":2,"InDegree":0},{"Code":"SCL","OutDegree":1,"InDegree":1}]`},
+		{"Outbound", "/api/v1/airport/GRU/outbound", http.StatusOK,
+			`[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"GRU","Destination":"CDG","Cost":75}]`},
+		{"Inbound", "/airport/BRC/inbound", http.StatusOK,
+			`[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"SCL","Destination":"BRC","Cost":5,"Bidirectional":true}]`},
+		{"NoOutbound", "/airport/CDG/outbound", http.StatusOK, `[]`},
+		{"Unknown", "/airport/FCO/inbound", http.StatusNotFound,
+			`{"code":"airport_not_found","message":"No route connects FCO","details":{"code":"FCO"}}`},
+		{"UnknownDirection", "/airport/GRU/sideways", http.StatusNotFound,
+			`{"code":"not_found","message":"/airport/GRU/sideways: Not found"}`},
+		{"MissingDirection", "/api/v1/airport/GRU", http.StatusNotFound,
+			`{"code":"not_found","message":"/api/v1/airport/GRU: Not found"}`},
+		{"InvalidAsOf", "/airport?AsOf=yesterday", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'AsOf' param: yesterday","details":{"param":"AsOf","value":"yesterday"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := getBody(t, tt.path)
+			if status != tt.expectStatus {
+				t.Errorf("%v expected status %v, got %v", tt.path, tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("%v expected %v, got %v", tt.path, tt.expectBody, body)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+}
@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// Roles granted by the API keys
+// Read-only keys may query the routes, only admin keys may also change them
+const (
+	RoleReadOnly = "read-only"
+	RoleAdmin    = "admin"
+)
+
+// KeyStore holds the API keys accepted by the webserver and the role each one grants
+type KeyStore struct {
+	// roles maps the SHA-256 of each key to its role, so the keys themselves are never compared
+	roles map[[sha256.Size]byte]string
+}
+
+// NewKeyStore constructs a KeyStore from the keys and their roles
+// Returns an error in case a key is empty or a role is unknown
+func NewKeyStore(keys map[string]string) (*KeyStore, error) {
+	ks := &KeyStore{roles: make(map[[sha256.Size]byte]string)}
+	for key, role := range keys {
+		if err := checkKey(key, role); err != nil {
+			return nil, err
+		}
+		ks.roles[sha256.Sum256([]byte(key))] = role
+	}
+	return ks, nil
+}
+
+// checkKey checks the key is not empty and the role is known
+func checkKey(key string, role string) error {
+	if key == "" {
+		return errors.New("empty key")
+	}
+	if role != RoleReadOnly && role != RoleAdmin {
+		return fmt.Errorf("unknown role %q", role)
+	}
+	return nil
+}
+
+// LoadKeys loads the API keys from the file at path
+// Each line holds a key and its role, comma separated
+// Blank lines and lines starting with '#' are skipped
+func LoadKeys(path string) (*KeyStore, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	keys := make(map[string]string)
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		fields := strings.Split(text, ",")
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("%v:%v: expected KEY,ROLE", path, line)
+		}
+		key, role := strings.TrimSpace(fields[0]), strings.TrimSpace(fields[1])
+		if err := checkKey(key, role); err != nil {
+			return nil, fmt.Errorf("%v:%v: %v", path, line, err)
+		}
+		if _, found := keys[key]; found {
+			return nil, fmt.Errorf("%v:%v: duplicated key", path, line)
+		}
+		keys[key] = role
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return NewKeyStore(keys)
+}
+
+// Role returns the role granted by the key of the request
+// The key is sent either in the 'X-API-Key' header or as a bearer token in the 'Authorization' header
+// Returns false in case the key is missing or unknown
+func (ks *KeyStore) Role(r *http.Request) (string, bool) {
+	key := r.Header.Get("X-API-Key")
+	if auth := r.Header.Get("Authorization"); key == "" && auth != "" {
+		const scheme = "Bearer "
+		if len(auth) > len(scheme) && strings.EqualFold(auth[:len(scheme)], scheme) {
+			key = strings.TrimSpace(auth[len(scheme):])
+		}
+	}
+	if key == "" {
+		return "", false
+	}
+
+	role, found := ks.roles[sha256.Sum256([]byte(key))]
+	return role, found
+}
+
+// authorize checks the key of the request grants its role
+// Returns false in case the request is not allowed, after replying with the error
+func (ks *KeyStore) authorize(w http.ResponseWriter, r *http.Request) bool {
+	if public(r) {
+		return true
+	}
+
+	role, found := ks.Role(r)
+	if !found {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="TravelRoute"`)
+		writeError(w, http.StatusUnauthorized, codeUnauthorized, "Missing or unknown API key", nil)
+		return false
+	}
+	if action := adminAction(r); role != RoleAdmin && action != "" {
+		writeError(w, http.StatusForbidden, codeForbidden, fmt.Sprintf("The %v role can not %v", role, action),
+			map[string]string{"role": role})
+		return false
+	}
+	return true
+}
+
+// public tells whether the request may be served without a key
+func public(r *http.Request) bool {
+	return strings.TrimPrefix(r.URL.Path, apiPrefix) == "/openapi.json"
+}
+
+// adminAction describes what the request does that only admin keys may do
+// Watches are admin only, as their callbacks make the server send requests anywhere
+// Returns "" in case any key may make the request
+func adminAction(r *http.Request) string {
+	path := strings.TrimPrefix(r.URL.Path, apiPrefix)
+	switch path {
+	case "/route", "/route/batch", "/admin/compact":
+		if r.Method != http.MethodGet && r.Method != http.MethodHead && r.Method != http.MethodOptions {
+			return "change the routes"
+		}
+	case "/watch":
+		return "manage the watches"
+	}
+	if strings.HasPrefix(path, "/watch/") {
+		return "manage the watches"
+	}
+	return ""
+}
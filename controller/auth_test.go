@@ -0,0 +1,230 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// authRequest sends the request with the key, as a bearer token when bearer is set
+func authRequest(t *testing.T, method string, path string, body interface{}, key string, bearer bool) (int, string) {
+	var reader *bytes.Reader
+	if body != nil {
+		js, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("json.Marshal error: %v\n", err.Error())
+		}
+		reader = bytes.NewReader(js)
+	} else {
+		reader = bytes.NewReader(nil)
+	}
+
+	req, err := http.NewRequest(method, "http://localhost:8080"+path, reader)
+	if err != nil {
+		t.Fatalf("http.NewRequest error: %v\n", err.Error())
+	}
+	if key != "" && bearer {
+		req.Header.Set("Authorization", "Bearer "+key)
+	} else if key != "" {
+		req.Header.Set("X-API-Key", key)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("http.Do error: %v\n", err.Error())
+	}
+	defer resp.Body.Close()
+
+	// Streams never end, so only the status is read from them
+	if resp.Header.Get("Content-Type") == "text/event-stream" {
+		return resp.StatusCode, ""
+	}
+	data, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll error: %v\n", err.Error())
+	}
+	return resp.StatusCode, string(data)
+}
+
+func TestAuth(t *testing.T) {
+	keys, err := NewKeyStore(map[string]string{"r3ad": RoleReadOnly, "adm1n": RoleAdmin})
+	if err != nil {
+		t.Fatalf("NewKeyStore error: %v", err)
+	}
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "CDG", 20))
+
+	srv := StartWebServerWithOptions(routeDB, 8080, Options{Keys: keys})
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	route := dal.NewRoute("GRU", "SCL", 20)
+	watchReq := watchRequest{"GRU", "CDG", "http://localhost:8090/notify"}
+	var tests = []struct {
+		path      string
+		method    string
+		url       string
+		body      interface{}
+		adminOnly string
+	}{
+		{"/route", http.MethodGet, "/route?Origin=GRU", nil, ""},
+		{"/route", http.MethodPost, "/route", route, "change the routes"},
+		{"/route", http.MethodPut, "/api/v1/route", route, "change the routes"},
+		{"/route/best", http.MethodGet, "/route/best?Origin=GRU&Destination=CDG", nil, ""},
+		{"/route/batch", http.MethodPost, "/route/batch", batchRequest{[]batchOperation{{"insert", *route}}}, "change the routes"},
+		{"/route/events", http.MethodGet, "/route/events", nil, ""},
+		{"/route/matrix", http.MethodGet, "/route/matrix?airports=GRU,CDG", nil, ""},
+		{"/route/from", http.MethodGet, "/route/from?Origin=GRU", nil, ""},
+		{"/itinerary", http.MethodPost, "/itinerary", itineraryRequest{[]string{"GRU", "CDG"}}, ""},
+		{"/itinerary/tour", http.MethodPost, "/itinerary/tour", tourRequest{[]string{"GRU", "CDG"}, false}, ""},
+		{"/airport", http.MethodGet, "/airport", nil, ""},
+		{"/airport/{code}/outbound", http.MethodGet, "/airport/GRU/outbound", nil, ""},
+		{"/airport/{code}/inbound", http.MethodGet, "/api/v1/airport/CDG/inbound", nil, ""},
+		{"/watch", http.MethodGet, "/watch", nil, "manage the watches"},
+		{"/watch", http.MethodPost, "/watch", watchReq, "manage the watches"},
+		{"/watch/{id}", http.MethodDelete, "/api/v1/watch/1", nil, "manage the watches"},
+		{"/graphql", http.MethodPost, "/graphql", gqlRequest{Query: "{ routes { cost } }"}, ""},
+		{"/admin/compact", http.MethodPost, "/admin/compact", nil, "change the routes"},
+		{"/openapi.json", http.MethodGet, "/openapi.json", nil, ""},
+	}
+
+	// Every handler registered is checked
+	tested := make(map[string]bool)
+	for _, tt := range tests {
+		tested[tt.path] = true
+	}
+	for _, path := range newWebServer(routeDB).paths {
+		if !tested[path] {
+			t.Errorf("%v expected to be tested", path)
+		}
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method+" "+tt.url, func(t *testing.T) {
+			public := tt.path == "/openapi.json"
+			for _, key := range []string{"", "unknown"} {
+				status, body := authRequest(t, tt.method, tt.url, tt.body, key, false)
+				if public && status == http.StatusUnauthorized {
+					t.Errorf("key %q expected to be allowed, got %v %v", key, status, body)
+				}
+				if expected := `{"code":"unauthorized","message":"Missing or unknown API key"}`; !public && (status != http.StatusUnauthorized || body != expected) {
+					t.Errorf("key %q expected %v, got %v %v", key, expected, status, body)
+				}
+			}
+
+			status, body := authRequest(t, tt.method, tt.url, tt.body, "r3ad", false)
+			expected := `{"code":"forbidden","message":"The read-only role can not ` + tt.adminOnly + `","details":{"role":"read-only"}}`
+			if tt.adminOnly != "" && (status != http.StatusForbidden || body != expected) {
+				t.Errorf("read-only key expected %v, got %v %v", expected, status, body)
+			}
+			if tt.adminOnly == "" && (status == http.StatusUnauthorized || status == http.StatusForbidden) {
+				t.Errorf("read-only key expected to be allowed, got %v %v", status, body)
+			}
+
+			status, body = authRequest(t, tt.method, tt.url, tt.body, "adm1n", true)
+			if status == http.StatusUnauthorized || status == http.StatusForbidden {
+				t.Errorf("admin key expected to be allowed, got %v %v", status, body)
+			}
+		})
+	}
+
+	// Only the admin changed the routes, once by POST, PUT and batch
+	expected := `[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"BRC","Destination":"CDG","Cost":20},` +
+		`{"Origin":"GRU","Destination":"SCL","Cost":20},{"Origin":"GRU","Destination":"SCL","Cost":20},{"Origin":"GRU","Destination":"SCL","Cost":20}]`
+	if status, body := authRequest(t, http.MethodGet, "/route", nil, "r3ad", true); status != http.StatusOK || body != expected {
+		t.Errorf("GET /route expected %v, got %v %v", expected, status, body)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestAuthorizationHeader(t *testing.T) {
+	keys, err := NewKeyStore(map[string]string{"adm1n": RoleAdmin})
+	if err != nil {
+		t.Fatalf("NewKeyStore error: %v", err)
+	}
+
+	var tests = []struct {
+		name       string
+		header     string
+		value      string
+		authorized bool
+	}{
+		{"api key", "X-API-Key", "adm1n", true},
+		{"bearer", "Authorization", "Bearer adm1n", true},
+		{"bearer lower case", "Authorization", "bearer adm1n", true},
+		{"basic", "Authorization", "Basic adm1n", false},
+		{"empty bearer", "Authorization", "Bearer ", false},
+		{"prefix of key", "X-API-Key", "adm", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/route", nil)
+			req.Header.Set(tt.header, tt.value)
+			w := httptest.NewRecorder()
+			if authorized := keys.authorize(w, req); authorized != tt.authorized {
+				t.Errorf("authorize expected %v, got %v", tt.authorized, authorized)
+			}
+			if challenge := w.Header().Get("WWW-Authenticate"); !tt.authorized && challenge != `Bearer realm="TravelRoute"` {
+				t.Errorf("authorize expected a challenge, got %q", challenge)
+			}
+		})
+	}
+}
+
+func TestLoadKeys(t *testing.T) {
+	dir, err := ioutil.TempDir("", "keys")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	var tests = []struct {
+		name    string
+		content string
+		err     string
+	}{
+		{"valid", "# Operations team\nadm1n,admin\n\n r3ad , read-only\n", ""},
+		{"unknown role", "adm1n,root\n", `:1: unknown role "root"`},
+		{"missing role", "adm1n,admin\nr3ad\n", ":2: expected KEY,ROLE"},
+		{"empty key", ",admin\n", ":1: empty key"},
+		{"duplicated key", "adm1n,admin\nadm1n,read-only\n", ":2: duplicated key"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, strings.Replace(tt.name, " ", "_", -1))
+			if err := ioutil.WriteFile(path, []byte(tt.content), 0600); err != nil {
+				t.Fatalf("ioutil.WriteFile error: %v", err)
+			}
+
+			keys, err := LoadKeys(path)
+			if tt.err != "" {
+				if err == nil || !strings.HasSuffix(err.Error(), tt.err) {
+					t.Errorf("LoadKeys expected error %v, got %v", tt.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("LoadKeys error: %v", err)
+			}
+			if len(keys.roles) != 2 {
+				t.Errorf("LoadKeys expected 2 keys, got %v", len(keys.roles))
+			}
+		})
+	}
+
+	if _, err := LoadKeys(filepath.Join(dir, "missing")); !os.IsNotExist(err) {
+		t.Errorf("LoadKeys expected not exist error, got %v", err)
+	}
+}
@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"errors"
+	"net/http"
+)
+
+// Operations accepted by "/route/batch"
+const (
+	insertOperation = "insert"
+	updateOperation = "update"
+	deleteOperation = "delete"
+)
+
+type batchOperation struct {
+	Op    string
+	Route dal.Route
+}
+
+type batchRequest struct {
+	Operations []batchOperation
+}
+
+type batchResponse struct {
+	Applied int
+}
+
+// batchHandler handles requests directed to "/route/batch"
+// The operations are applied atomically, either all of them or none
+func (ws *webServer) batchHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req batchRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		// Every route is checked first, so no transaction is left to roll back
+		for i := range req.Operations {
+			if !validRoute(w, &req.Operations[i].Route) {
+				return
+			}
+		}
+
+		tx := ws.routeDB.Begin()
+		for _, op := range req.Operations {
+			switch op.Op {
+			case insertOperation:
+				tx.InsertRoute(op.Route)
+			case updateOperation:
+				tx.UpdateRoute(op.Route)
+			case deleteOperation:
+				tx.DeleteRoute(op.Route)
+			default:
+				tx.Rollback()
+				invalidParam(w, "Op", op.Op)
+				return
+			}
+		}
+
+		err := tx.Commit()
+		var notFound *dal.RouteNotFoundError
+		if errors.As(err, &notFound) {
+			writeError(w, http.StatusUnprocessableEntity, codeRouteNotFound, err.Error(), notFound.Route)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, batchResponse{len(req.Operations)})
+	default:
+		methodNotAllowed(w, r)
+	}
+}
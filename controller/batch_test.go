@@ -0,0 +1,64 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+
+	var tests = []struct {
+		name         string
+		req          batchRequest
+		expectStatus int
+		expectBody   string
+		expectRoutes string
+	}{
+		{"Applied", batchRequest{[]batchOperation{
+			{"insert", *dal.NewRoute("BRC", "SCL", 5)},
+			{"update", *dal.NewRoute("GRU", "BRC", 12)},
+			{"delete", *dal.NewRoute("GRU", "CDG", 0)},
+		}}, http.StatusOK, `{"Applied":3}`,
+			`[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"BRC","Destination":"SCL","Cost":5},{"Origin":"GRU","Destination":"BRC","Cost":12}]`},
+		{"NotFound", batchRequest{[]batchOperation{
+			{"insert", *dal.NewRoute("SCL", "ORL", 20)},
+			{"delete", *dal.NewRoute("GRU", "CDG", 0)},
+		}}, http.StatusUnprocessableEntity,
+			`{"code":"route_not_found","message":"route not found: GRU > CDG","details":{"Origin":"GRU","Destination":"CDG","Cost":0}}`,
+			`[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"BRC","Destination":"SCL","Cost":5},{"Origin":"GRU","Destination":"BRC","Cost":12}]`},
+		{"InvalidOp", batchRequest{[]batchOperation{
+			{"insert", *dal.NewRoute("SCL", "ORL", 20)},
+			{"upsert", *dal.NewRoute("GRU", "CDG", 60)},
+		}}, http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'Op' param: upsert","details":{"param":"Op","value":"upsert"}}`,
+			`[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"BRC","Destination":"SCL","Cost":5},{"Origin":"GRU","Destination":"BRC","Cost":12}]`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postJSON(t, "/route/batch", tt.req)
+			if status != tt.expectStatus {
+				t.Errorf("/route/batch expected status %v, got %v", tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("/route/batch expected %v, got %v", tt.expectBody, body)
+			}
+			if routes := getRoutes(t); routes != tt.expectRoutes {
+				t.Errorf("getRoutes expected %v, got %v", tt.expectRoutes, routes)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+}
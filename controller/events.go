@@ -0,0 +1,117 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// keepAliveInterval is the interval between comments sent to keep idle streams open
+const keepAliveInterval = 15 * time.Second
+
+// resetEvent tells the client events were lost, so the routes must be fetched again
+const resetEvent = "reset"
+
+// eventsHandler handles requests directed to "/route/events"
+// Route events are streamed as Server-Sent Events, their IDs are the resume token:
+// reconnecting with the Last-Event-ID header, or the 'LastEventID' param,
+// delivers the events missed in between
+func (ws *webServer) eventsHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			writeError(w, http.StatusInternalServerError, codeInternal, "Streaming not supported", nil)
+			return
+		}
+
+		token := r.Header.Get("Last-Event-ID")
+		if token == "" {
+			token = r.FormValue("LastEventID")
+		}
+		var after uint64
+		if token != "" {
+			var err error
+			after, err = strconv.ParseUint(token, 10, 64)
+			if err != nil {
+				invalidParam(w, "Last-Event-ID", token)
+				return
+			}
+		}
+
+		reset := false
+		s, missed, err := ws.routeDB.Subscribe(after)
+		if err == dal.ErrEventsLost {
+			reset = true
+			s, missed, err = ws.routeDB.Subscribe(0)
+		}
+		if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+			return
+		}
+		defer s.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		if reset {
+			fmt.Fprintf(w, "id: %v\nevent: %v\ndata: {}\n\n", s.Last(), resetEvent)
+		} else if len(missed) == 0 {
+			// Hands the client a resume token before any event
+			fmt.Fprintf(w, "id: %v\n\n", s.Last())
+		}
+		for _, event := range missed {
+			if !writeEvent(w, event) {
+				return
+			}
+		}
+		flusher.Flush()
+
+		keepAlive := time.NewTicker(keepAliveInterval)
+		defer keepAlive.Stop()
+		// The server cuts the response at its write timeout, so the stream is ended just before
+		var timeout <-chan time.Time
+		if ws.writeTimeout > 0 {
+			timer := time.NewTimer(ws.writeTimeout * 9 / 10)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		for {
+			select {
+			case event, ok := <-s.Events():
+				if !ok {
+					// Fell behind, the client resumes from the last event received
+					return
+				}
+				if !writeEvent(w, event) {
+					return
+				}
+			case <-keepAlive.C:
+				fmt.Fprint(w, ": keep-alive\n\n")
+			case <-timeout:
+				// The client resumes from the last event received
+				return
+			case <-r.Context().Done():
+				return
+			case <-ws.shutdown:
+				return
+			}
+			flusher.Flush()
+		}
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// writeEvent writes the route event in the Server-Sent Events format
+// Returns false in case it could not be written
+func writeEvent(w http.ResponseWriter, event dal.Event) bool {
+	js, err := json.Marshal(event.Route)
+	if err != nil {
+		return false
+	}
+	_, err = fmt.Fprintf(w, "id: %v\nevent: %v\ndata: %s\n\n", event.ID, event.Type, js)
+	return err == nil
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bufio"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+// openEvents connects to the events stream, resuming from lastEventID when set
+func openEvents(t *testing.T, lastEventID string) (*http.Response, *bufio.Reader) {
+	req, err := http.NewRequest(http.MethodGet, "http://localhost:8080/route/events", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest error: %v\n", err.Error())
+	}
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("client.Do error: %v\n", err.Error())
+	}
+	return resp, bufio.NewReader(resp.Body)
+}
+
+// readEvent reads the lines of the next event, up to the blank line ending it
+func readEvent(t *testing.T, reader *bufio.Reader) string {
+	lines := make([]string, 0)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("reader.ReadString error: %v\n", err.Error())
+		}
+		line = strings.TrimSuffix(line, "\n")
+		if line == "" {
+			return strings.Join(lines, "\n")
+		}
+		lines = append(lines, line)
+	}
+}
+
+func TestEvents(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+
+	resp, reader := openEvents(t, "")
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Errorf("Content-Type expected text/event-stream, got %v", contentType)
+	}
+	if event := readEvent(t, reader); event != "id: 1" {
+		t.Errorf("event expected the resume token, got %q", event)
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 12))
+	expected := "id: 2\nevent: updated\ndata: {\"Origin\":\"GRU\",\"Destination\":\"BRC\",\"Cost\":12}"
+	if event := readEvent(t, reader); event != expected {
+		t.Errorf("event expected %q, got %q", expected, event)
+	}
+	resp.Body.Close()
+
+	// Events missed while disconnected are delivered on reconnection
+	addRoute(t, *dal.NewRoute("BRC", "SCL", 5))
+	postJSON(t, "/route/batch", batchRequest{[]batchOperation{{"delete", *dal.NewRoute("GRU", "BRC", 0)}}})
+
+	resp, reader = openEvents(t, "2")
+	expected = "id: 3\nevent: inserted\ndata: {\"Origin\":\"BRC\",\"Destination\":\"SCL\",\"Cost\":5}"
+	if event := readEvent(t, reader); event != expected {
+		t.Errorf("event expected %q, got %q", expected, event)
+	}
+	expected = "id: 4\nevent: deleted\ndata: {\"Origin\":\"GRU\",\"Destination\":\"BRC\",\"Cost\":0}"
+	if event := readEvent(t, reader); event != expected {
+		t.Errorf("event expected %q, got %q", expected, event)
+	}
+	resp.Body.Close()
+
+	// Clients are told to fetch the routes again when events were lost
+	resp, reader = openEvents(t, "99")
+	if event := readEvent(t, reader); event != "id: 4\nevent: reset\ndata: {}" {
+		t.Errorf("event expected a reset, got %q", event)
+	}
+
+	status, body := getBody(t, "/route/events?LastEventID=abc")
+	if status != http.StatusBadRequest || body != `{"code":"invalid_param","message":"Invalid 'Last-Event-ID' param: abc",`+
+		`"details":{"param":"Last-Event-ID","value":"abc"}}` {
+		t.Errorf("/route/events expected status %v, got %v %v", http.StatusBadRequest, status, body)
+	}
+
+	// Open streams do not hold the server from stopping
+	StopWebServer(srv)
+	resp.Body.Close()
+}
@@ -0,0 +1,471 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"TravelRoute/domain"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"reflect"
+	"strings"
+)
+
+// The GraphQL schema served:
+//
+//	type Query {
+//	  routes: [Route!]!
+//	  bestRoute(origin: String!, destination: String!): BestRoute
+//	  airport(code: String!): Airport
+//	}
+//	type Route { origin: String! destination: String! cost: Float! carrier: String fareId: String bidirectional: Boolean! }
+//	type BestRoute { route: [String!]! legs: [Route!]! cost: Float! }
+//	type Airport { code: String! outbound: [Route!]! inbound: [Route!]! }
+
+// gqlRequest defines the body of a GraphQL request
+type gqlRequest struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// gqlError defines an error of a GraphQL response
+type gqlError struct {
+	Message   string        `json:"message"`
+	Locations []gqlLocation `json:"locations,omitempty"`
+	Path      []interface{} `json:"path,omitempty"`
+}
+
+// gqlResponse defines the body of a GraphQL response
+// Data is missing when the request could not be executed at all
+type gqlResponse struct {
+	Data   interface{} `json:"data,omitempty"`
+	Errors []gqlError  `json:"errors,omitempty"`
+}
+
+// gqlObjectType defines an object type of the schema
+type gqlObjectType struct {
+	name   string
+	fields map[string]*gqlField
+}
+
+// gqlField defines a field of an object type
+type gqlField struct {
+	// typeName describes the type returned, as in "[Route!]!"
+	typeName string
+	// objectType is the object type returned, or listed, it is nil for scalars
+	objectType *gqlObjectType
+	// arguments lists the arguments accepted, all of them String!
+	arguments []string
+	resolve   func(source interface{}, args map[string]string) (interface{}, error)
+}
+
+// maxGraphQLFields is the amount of fields an operation may select at most, counting the nested ones
+const maxGraphQLFields = 100
+
+// gqlRoot is the source of the Query fields
+// The routes are retrieved once, so every field of a request sees the same routes
+type gqlRoot struct {
+	routes []dal.Route
+	// network holds the graph of the routes, built by the first search and shared by the others
+	network *domain.RouteNetwork
+}
+
+// routeNetwork returns the graph of the routes, building it once
+func (root *gqlRoot) routeNetwork() *domain.RouteNetwork {
+	if root.network == nil {
+		root.network = domain.NewRouteNetwork(root.routes)
+	}
+	return root.network
+}
+
+// gqlObject holds the fields of a response object, keeping the order they were selected
+type gqlObject struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+// MarshalJSON encodes the fields in the order they were selected
+func (o *gqlObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range o.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, _ := marshal(key)
+		v, err := marshal(o.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// graphQLSchema builds the Query type
+func graphQLSchema() *gqlObjectType {
+	routeType := &gqlObjectType{"Route", map[string]*gqlField{
+		"origin": {"String!", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(dal.Route).Origin, nil
+		}},
+		"destination": {"String!", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(dal.Route).Destination, nil
+		}},
+		"cost": {"Float!", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(dal.Route).Cost, nil
+		}},
+		"carrier": {"String", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return nullable(source.(dal.Route).Carrier), nil
+		}},
+		"fareId": {"String", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return nullable(source.(dal.Route).FareID), nil
+		}},
+		"bidirectional": {"Boolean!", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(dal.Route).Bidirectional, nil
+		}},
+	}}
+
+	bestRouteType := &gqlObjectType{"BestRoute", map[string]*gqlField{
+		"route": {"[String!]!", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(domain.BestRoute).Route, nil
+		}},
+		"legs": {"[Route!]!", routeType, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(domain.BestRoute).Legs, nil
+		}},
+		"cost": {"Float!", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(domain.BestRoute).Cost, nil
+		}},
+	}}
+
+	airportType := &gqlObjectType{"Airport", map[string]*gqlField{
+		"code": {"String!", nil, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(domain.Airport).Code, nil
+		}},
+		"outbound": {"[Route!]!", routeType, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(domain.Airport).Outbound, nil
+		}},
+		"inbound": {"[Route!]!", routeType, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(domain.Airport).Inbound, nil
+		}},
+	}}
+
+	return &gqlObjectType{"Query", map[string]*gqlField{
+		"routes": {"[Route!]!", routeType, nil, func(source interface{}, args map[string]string) (interface{}, error) {
+			return source.(*gqlRoot).routes, nil
+		}},
+		"bestRoute": {"BestRoute", bestRouteType, []string{"origin", "destination"},
+			func(source interface{}, args map[string]string) (interface{}, error) {
+				best, err := source.(*gqlRoot).routeNetwork().FindBestRoute(args["origin"], args["destination"], domain.SearchOptions{})
+				if err != nil || len(best.Route) == 0 {
+					return nil, err
+				}
+				return best, nil
+			}},
+		"airport": {"Airport", airportType, []string{"code"},
+			func(source interface{}, args map[string]string) (interface{}, error) {
+				airport, found := domain.FindAirport(source.(*gqlRoot).routes, args["code"])
+				if !found {
+					return nil, nil
+				}
+				return airport, nil
+			}},
+	}}
+}
+
+// nullable maps an empty string to null
+func nullable(value string) interface{} {
+	if value == "" {
+		return nil
+	}
+	return value
+}
+
+// graphQLHandler handles requests directed to "/graphql"
+// Queries are read from the body of POST requests or from the params of GET requests
+func (ws *webServer) graphQLHandler(w http.ResponseWriter, r *http.Request) {
+	var req gqlRequest
+	switch r.Method {
+	case http.MethodGet:
+		req.Query = r.FormValue("query")
+		req.OperationName = r.FormValue("operationName")
+		if variables := r.FormValue("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				writeGraphQL(w, http.StatusBadRequest, gqlResponse{Errors: []gqlError{{Message: "Invalid variables: " + err.Error()}}})
+				return
+			}
+		}
+	case http.MethodPost:
+		if err := json.NewDecoder(r.Body).Decode(&req); bodyTooLarge(err) {
+			writeGraphQL(w, http.StatusRequestEntityTooLarge, gqlResponse{Errors: []gqlError{{Message: "Request body too large."}}})
+			return
+		} else if err != nil {
+			writeGraphQL(w, http.StatusBadRequest, gqlResponse{Errors: []gqlError{{Message: "Invalid request: " + err.Error()}}})
+			return
+		}
+	default:
+		methodNotAllowed(w, r)
+		return
+	}
+
+	if strings.TrimSpace(req.Query) == "" {
+		writeGraphQL(w, http.StatusBadRequest, gqlResponse{Errors: []gqlError{{Message: "Must provide query string."}}})
+		return
+	}
+	doc, err := parseQuery(req.Query)
+	if err != nil {
+		syntaxErr := err.(*gqlSyntaxError)
+		writeGraphQL(w, http.StatusBadRequest, gqlResponse{Errors: []gqlError{
+			{Message: syntaxErr.Error(), Locations: []gqlLocation{syntaxErr.Location}}}})
+		return
+	}
+
+	e := &gqlExecutor{query: graphQLSchema()}
+	op, args := e.prepare(doc, req.OperationName, req.Variables)
+	if len(e.errors) != 0 {
+		writeGraphQL(w, http.StatusBadRequest, gqlResponse{Errors: e.errors})
+		return
+	}
+
+	data := e.executeSelections(e.query, &gqlRoot{routes: ws.routeDB.GetRoutes()}, op.selections, args, []interface{}{})
+	writeGraphQL(w, http.StatusOK, gqlResponse{data, e.errors})
+}
+
+// writeGraphQL replies with the GraphQL response, which has its own error format
+func writeGraphQL(w http.ResponseWriter, status int, resp gqlResponse) {
+	js, err := marshal(resp)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// gqlExecutor validates and executes a document against the schema, collecting the errors
+type gqlExecutor struct {
+	query  *gqlObjectType
+	errors []gqlError
+	// fields counts the fields selected by the operation
+	fields int
+}
+
+func (e *gqlExecutor) fail(location gqlLocation, path []interface{}, format string, a ...interface{}) {
+	e.errors = append(e.errors, gqlError{fmt.Sprintf(format, a...), []gqlLocation{location}, path})
+}
+
+// prepare selects the operation, coerces its variables and validates its selections
+// Returns the operation and the values of its variables, the errors are collected
+func (e *gqlExecutor) prepare(doc *gqlDocument, operationName string, variables map[string]interface{}) (*gqlOperation, map[string]string) {
+	var op *gqlOperation
+	for _, candidate := range doc.operations {
+		if operationName == "" || candidate.name == operationName {
+			if op != nil {
+				e.errors = append(e.errors, gqlError{Message: "Must provide operation name if query contains multiple operations."})
+				return nil, nil
+			}
+			op = candidate
+		}
+	}
+	if op == nil {
+		e.errors = append(e.errors, gqlError{Message: fmt.Sprintf("Unknown operation named %q.", operationName)})
+		return nil, nil
+	}
+	if op.kind != "query" {
+		e.fail(op.location, nil, "Only queries are supported, %v is not.", op.kind)
+		return nil, nil
+	}
+
+	args := make(map[string]string)
+	declared := make(map[string]*gqlVariable)
+	for _, v := range op.variables {
+		if _, found := declared[v.name]; found {
+			e.fail(v.location, nil, "There can be only one variable named \"$%v\".", v.name)
+			continue
+		}
+		declared[v.name] = v
+		typeName := v.typeName
+		if v.nonNull {
+			typeName += "!"
+		}
+		if v.typeName != "String" {
+			e.fail(v.location, nil, "Variable \"$%v\" of type %q is not supported, only String is.", v.name, typeName)
+			continue
+		}
+
+		value, provided := variables[v.name]
+		if !provided && v.defaultValue != nil {
+			value, provided = v.defaultValue.literal, true
+		}
+		if !provided || value == nil {
+			if v.nonNull {
+				e.fail(v.location, nil, "Variable \"$%v\" of required type %q was not provided.", v.name, typeName)
+			}
+			continue
+		}
+		s, ok := value.(string)
+		if !ok {
+			e.fail(v.location, nil, "Variable \"$%v\" got invalid value %v; String cannot represent a non string value.", v.name, value)
+			continue
+		}
+		args[v.name] = s
+	}
+
+	e.validate(e.query, op.selections, declared)
+	if e.fields > maxGraphQLFields {
+		e.errors = append(e.errors, gqlError{Message: fmt.Sprintf("Operation selects %v fields, at most %v are allowed.", e.fields, maxGraphQLFields)})
+	}
+	return op, args
+}
+
+// validate checks the selections against the type, the way the GraphQL validation rules would
+func (e *gqlExecutor) validate(objectType *gqlObjectType, selections []*gqlSelection, declared map[string]*gqlVariable) {
+	selected := make(map[string]*gqlSelection)
+	for _, s := range selections {
+		e.fields++
+		if other, found := selected[s.responseKey()]; found && (other.name != s.name || !sameArguments(other, s)) {
+			e.fail(s.location, nil, "Fields %q conflict because they are different fields or have different arguments. Use different aliases on the fields to fetch both if this was intentional.", s.responseKey())
+		}
+		selected[s.responseKey()] = s
+
+		if s.name == "__typename" {
+			if len(s.arguments) != 0 || s.selections != nil {
+				e.fail(s.location, nil, "Field \"__typename\" must not have arguments or a selection.")
+			}
+			continue
+		}
+		field, found := objectType.fields[s.name]
+		if !found {
+			e.fail(s.location, nil, "Cannot query field %q on type %q.", s.name, objectType.name)
+			continue
+		}
+
+		for name, value := range s.arguments {
+			if !contains(field.arguments, name) {
+				e.fail(value.location, nil, "Unknown argument %q on field \"%v.%v\".", name, objectType.name, s.name)
+			} else if value.variable != "" {
+				if v, found := declared[value.variable]; !found {
+					e.fail(value.location, nil, "Variable \"$%v\" is not defined.", value.variable)
+				} else if !v.nonNull && v.defaultValue == nil {
+					e.fail(value.location, nil, "Variable \"$%v\" of type \"String\" used in position expecting type \"String!\".", value.variable)
+				}
+			} else if _, ok := value.literal.(string); !ok {
+				e.fail(value.location, nil, "Argument %q of type \"String!\" got invalid value %v.", name, value.literal)
+			}
+		}
+		for _, name := range field.arguments {
+			if _, found := s.arguments[name]; !found {
+				e.fail(s.location, nil, "Field %q argument %q of type \"String!\" is required, but it was not provided.", s.name, name)
+			}
+		}
+
+		if field.objectType == nil && s.selections != nil {
+			e.fail(s.location, nil, "Field %q must not have a selection since type %q has no subfields.", s.name, field.typeName)
+		} else if field.objectType != nil && s.selections == nil {
+			e.fail(s.location, nil, "Field %q of type %q must have a selection of subfields. Did you mean \"%v { ... }\"?", s.name, field.typeName, s.name)
+		} else if field.objectType != nil {
+			e.validate(field.objectType, s.selections, declared)
+		}
+	}
+}
+
+// executeSelections resolves the selections on the source, fields failing to resolve are null
+func (e *gqlExecutor) executeSelections(objectType *gqlObjectType, source interface{}, selections []*gqlSelection, args map[string]string, path []interface{}) *gqlObject {
+	result := &gqlObject{values: make(map[string]interface{})}
+	keys, fields := collectFields(selections)
+	for _, key := range keys {
+		s := fields[key][0]
+		result.keys = append(result.keys, key)
+		if s.name == "__typename" {
+			result.values[key] = objectType.name
+			continue
+		}
+
+		field := objectType.fields[s.name]
+		fieldPath := append(append([]interface{}{}, path...), key)
+		fieldArgs := make(map[string]string, len(s.arguments))
+		for name, value := range s.arguments {
+			if value.variable != "" {
+				fieldArgs[name] = args[value.variable]
+			} else {
+				fieldArgs[name] = value.literal.(string)
+			}
+		}
+
+		value, err := field.resolve(source, fieldArgs)
+		if err != nil {
+			e.fail(s.location, fieldPath, "%v", err)
+			result.values[key] = nil
+			continue
+		}
+
+		// A field selected more than once is resolved once, with all their subselections
+		subselections := make([]*gqlSelection, 0)
+		for _, same := range fields[key] {
+			subselections = append(subselections, same.selections...)
+		}
+		result.values[key] = e.complete(field, value, subselections, args, fieldPath)
+	}
+	return result
+}
+
+// collectFields groups the selections by their response key
+// Returns the keys in the order they were first selected
+func collectFields(selections []*gqlSelection) ([]string, map[string][]*gqlSelection) {
+	keys := make([]string, 0, len(selections))
+	fields := make(map[string][]*gqlSelection)
+	for _, s := range selections {
+		key := s.responseKey()
+		if _, found := fields[key]; !found {
+			keys = append(keys, key)
+		}
+		fields[key] = append(fields[key], s)
+	}
+	return keys, fields
+}
+
+// complete converts the value resolved into the response value, resolving the subselections of objects
+func (e *gqlExecutor) complete(field *gqlField, value interface{}, selections []*gqlSelection, args map[string]string, path []interface{}) interface{} {
+	if value == nil || field.objectType == nil {
+		return value
+	}
+
+	list := reflect.ValueOf(value)
+	if list.Kind() != reflect.Slice {
+		return e.executeSelections(field.objectType, value, selections, args, path)
+	}
+	items := make([]interface{}, list.Len())
+	for i := range items {
+		itemPath := append(append([]interface{}{}, path...), i)
+		items[i] = e.executeSelections(field.objectType, list.Index(i).Interface(), selections, args, itemPath)
+	}
+	return items
+}
+
+// sameArguments tells whether both selections have the same arguments
+func sameArguments(a *gqlSelection, b *gqlSelection) bool {
+	if len(a.arguments) != len(b.arguments) {
+		return false
+	}
+	for name, value := range a.arguments {
+		other, found := b.arguments[name]
+		if !found || other.variable != value.variable || other.literal != value.literal {
+			return false
+		}
+	}
+	return true
+}
+
+// contains tells whether the value is in values
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
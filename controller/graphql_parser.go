@@ -0,0 +1,475 @@
+package controller
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// The subset of the GraphQL query language supported:
+// queries, named or anonymous, with variables, aliases and arguments
+// Fragments, directives and mutations are rejected with an error
+
+// Kinds of the tokens read by the lexer
+const (
+	tokenEOF = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+// gqlLocation defines a position in the query, both starting at 1
+type gqlLocation struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+// gqlSyntaxError is returned when the query can not be parsed
+type gqlSyntaxError struct {
+	Message  string
+	Location gqlLocation
+}
+
+func (e *gqlSyntaxError) Error() string {
+	return fmt.Sprintf("Syntax Error: %v", e.Message)
+}
+
+type gqlToken struct {
+	kind     int
+	value    string
+	location gqlLocation
+}
+
+// gqlDocument holds the operations of a query
+type gqlDocument struct {
+	operations []*gqlOperation
+}
+
+// gqlOperation defines a single operation of the document
+type gqlOperation struct {
+	kind       string
+	name       string
+	variables  []*gqlVariable
+	selections []*gqlSelection
+	location   gqlLocation
+}
+
+// gqlVariable defines a variable declared by an operation
+type gqlVariable struct {
+	name         string
+	typeName     string
+	nonNull      bool
+	defaultValue *gqlValue
+	location     gqlLocation
+}
+
+// gqlSelection defines a field selected, along with its arguments and subselections
+type gqlSelection struct {
+	alias      string
+	name       string
+	arguments  map[string]*gqlValue
+	selections []*gqlSelection
+	location   gqlLocation
+}
+
+// gqlValue holds either a literal or a reference to a variable
+type gqlValue struct {
+	variable string
+	literal  interface{}
+	location gqlLocation
+}
+
+// responseKey is the key of the field in the response, its alias when present
+func (s *gqlSelection) responseKey() string {
+	if s.alias != "" {
+		return s.alias
+	}
+	return s.name
+}
+
+// gqlParser is a recursive descent parser over the tokens of the query
+type gqlParser struct {
+	source string
+	offset int
+	line   int
+	column int
+	token  gqlToken
+}
+
+// parseQuery parses the query into a document
+// Returns a *gqlSyntaxError in case the query is invalid
+func parseQuery(query string) (doc *gqlDocument, err error) {
+	p := &gqlParser{source: query, line: 1, column: 1}
+	defer func() {
+		// Errors are raised as panics so the parser is not cluttered by error checks
+		if r := recover(); r != nil {
+			syntaxErr, ok := r.(*gqlSyntaxError)
+			if !ok {
+				panic(r)
+			}
+			doc, err = nil, syntaxErr
+		}
+	}()
+
+	p.next()
+	doc = &gqlDocument{}
+	for p.token.kind != tokenEOF {
+		doc.operations = append(doc.operations, p.parseOperation())
+	}
+	if len(doc.operations) == 0 {
+		p.fail(p.token.location, "Unexpected <EOF>.")
+	}
+	return doc, nil
+}
+
+func (p *gqlParser) fail(location gqlLocation, format string, a ...interface{}) {
+	panic(&gqlSyntaxError{fmt.Sprintf(format, a...), location})
+}
+
+func (p *gqlParser) unexpected() {
+	p.fail(p.token.location, "Unexpected %v.", p.describe())
+}
+
+// describe names the current token in error messages
+func (p *gqlParser) describe() string {
+	if p.token.kind == tokenEOF {
+		return "<EOF>"
+	}
+	return strconv.Quote(p.token.value)
+}
+
+// peek tells whether the current token is the punctuator
+func (p *gqlParser) peek(punctuator string) bool {
+	return p.token.kind == tokenPunctuator && p.token.value == punctuator
+}
+
+// skip consumes the punctuator, telling whether it was found
+func (p *gqlParser) skip(punctuator string) bool {
+	if p.peek(punctuator) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// expect consumes the punctuator, failing in case it is not the current token
+func (p *gqlParser) expect(punctuator string) {
+	if !p.skip(punctuator) {
+		p.fail(p.token.location, "Expected %q, found %v.", punctuator, p.describe())
+	}
+}
+
+// expectName consumes a name, returning it
+func (p *gqlParser) expectName() string {
+	if p.token.kind != tokenName {
+		p.fail(p.token.location, "Expected Name, found %v.", p.describe())
+	}
+	name := p.token.value
+	p.next()
+	return name
+}
+
+func (p *gqlParser) parseOperation() *gqlOperation {
+	op := &gqlOperation{kind: "query", location: p.token.location}
+	if p.peek("{") {
+		op.selections = p.parseSelectionSet()
+		return op
+	}
+
+	if p.token.kind != tokenName {
+		p.unexpected()
+	}
+	switch p.token.value {
+	case "query", "mutation", "subscription":
+		op.kind = p.token.value
+	case "fragment":
+		p.fail(p.token.location, "Fragments are not supported.")
+	default:
+		p.unexpected()
+	}
+	p.next()
+
+	if p.token.kind == tokenName {
+		op.name = p.expectName()
+	}
+	if p.skip("(") {
+		for !p.skip(")") {
+			op.variables = append(op.variables, p.parseVariable())
+		}
+	}
+	if p.peek("@") {
+		p.fail(p.token.location, "Directives are not supported.")
+	}
+	op.selections = p.parseSelectionSet()
+	return op
+}
+
+func (p *gqlParser) parseVariable() *gqlVariable {
+	v := &gqlVariable{location: p.token.location}
+	p.expect("$")
+	v.name = p.expectName()
+	p.expect(":")
+	if p.peek("[") {
+		p.fail(p.token.location, "List types are not supported.")
+	}
+	v.typeName = p.expectName()
+	v.nonNull = p.skip("!")
+	if p.skip("=") {
+		v.defaultValue = p.parseValue(true)
+	}
+	return v
+}
+
+func (p *gqlParser) parseSelectionSet() []*gqlSelection {
+	p.expect("{")
+	selections := make([]*gqlSelection, 0)
+	for len(selections) == 0 || !p.skip("}") {
+		if p.peek("...") {
+			p.fail(p.token.location, "Fragments are not supported.")
+		}
+		selections = append(selections, p.parseField())
+	}
+	return selections
+}
+
+func (p *gqlParser) parseField() *gqlSelection {
+	s := &gqlSelection{location: p.token.location, arguments: make(map[string]*gqlValue)}
+	s.name = p.expectName()
+	if p.skip(":") {
+		s.alias, s.name = s.name, p.expectName()
+	}
+
+	if p.skip("(") {
+		for !p.skip(")") {
+			location := p.token.location
+			name := p.expectName()
+			if _, found := s.arguments[name]; found {
+				p.fail(location, "There can be only one argument named %q.", name)
+			}
+			p.expect(":")
+			s.arguments[name] = p.parseValue(false)
+		}
+	}
+	if p.peek("@") {
+		p.fail(p.token.location, "Directives are not supported.")
+	}
+	if p.peek("{") {
+		s.selections = p.parseSelectionSet()
+	}
+	return s
+}
+
+// parseValue parses a literal, or a variable unless constant is set
+// Lists and input objects are not supported, as no argument accepts them
+func (p *gqlParser) parseValue(constant bool) *gqlValue {
+	v := &gqlValue{location: p.token.location}
+	switch p.token.kind {
+	case tokenPunctuator:
+		if p.token.value != "$" || constant {
+			p.unexpected()
+		}
+		p.next()
+		v.variable = p.expectName()
+		return v
+	case tokenString:
+		v.literal = p.token.value
+	case tokenInt:
+		n, err := strconv.ParseInt(p.token.value, 10, 32)
+		if err != nil {
+			p.fail(p.token.location, "Int cannot represent value %v.", p.token.value)
+		}
+		v.literal = int(n)
+	case tokenFloat:
+		n, _ := strconv.ParseFloat(p.token.value, 64)
+		v.literal = n
+	case tokenName:
+		switch p.token.value {
+		case "true":
+			v.literal = true
+		case "false":
+			v.literal = false
+		case "null":
+			v.literal = nil
+		default:
+			p.fail(p.token.location, "Enum values are not supported.")
+		}
+	default:
+		p.unexpected()
+	}
+	p.next()
+	return v
+}
+
+// next reads the following token, skipping whitespace, commas and comments
+func (p *gqlParser) next() {
+	for p.offset < len(p.source) {
+		c := p.source[p.offset]
+		if c == '#' {
+			for p.offset < len(p.source) && p.source[p.offset] != '\n' && p.source[p.offset] != '\r' {
+				p.advance(1)
+			}
+		} else if c == ' ' || c == '\t' || c == ',' || c == '\n' || c == '\r' {
+			p.advance(1)
+		} else if strings.HasPrefix(p.source[p.offset:], "\ufeff") {
+			p.advance(len("\ufeff"))
+		} else {
+			break
+		}
+	}
+
+	location := gqlLocation{p.line, p.column}
+	if p.offset == len(p.source) {
+		p.token = gqlToken{tokenEOF, "", location}
+		return
+	}
+
+	rest := p.source[p.offset:]
+	c := rest[0]
+	switch {
+	case strings.HasPrefix(rest, "..."):
+		p.token = gqlToken{tokenPunctuator, "...", location}
+		p.advance(3)
+	case strings.IndexByte("!$&():=@[]{}|", c) >= 0:
+		p.token = gqlToken{tokenPunctuator, string(c), location}
+		p.advance(1)
+	case c == '_' || isLetter(c):
+		end := 1
+		for end < len(rest) && (rest[end] == '_' || isLetter(rest[end]) || isDigit(rest[end])) {
+			end++
+		}
+		p.token = gqlToken{tokenName, rest[:end], location}
+		p.advance(end)
+	case c == '-' || isDigit(c):
+		p.readNumber(location)
+	case c == '"':
+		p.readString(location)
+	default:
+		r, _ := utf8.DecodeRuneInString(rest)
+		p.fail(location, "Unexpected character %q.", r)
+	}
+}
+
+func (p *gqlParser) readNumber(location gqlLocation) {
+	rest := p.source[p.offset:]
+	end := 0
+	if rest[end] == '-' {
+		end++
+	}
+	digits := func() {
+		start := end
+		for end < len(rest) && isDigit(rest[end]) {
+			end++
+		}
+		if end == start {
+			p.fail(gqlLocation{location.Line, location.Column + end}, "Invalid number, expected digit.")
+		}
+	}
+
+	kind := tokenInt
+	digits()
+	if end < len(rest) && rest[end] == '.' {
+		kind = tokenFloat
+		end++
+		digits()
+	}
+	if end < len(rest) && (rest[end] == 'e' || rest[end] == 'E') {
+		kind = tokenFloat
+		end++
+		if end < len(rest) && (rest[end] == '+' || rest[end] == '-') {
+			end++
+		}
+		digits()
+	}
+	if end < len(rest) && (rest[end] == '_' || rest[end] == '.' || isLetter(rest[end])) {
+		p.fail(gqlLocation{location.Line, location.Column + end}, "Invalid number, unexpected %q.", rest[end])
+	}
+
+	p.token = gqlToken{kind, rest[:end], location}
+	p.advance(end)
+}
+
+// readString reads a quoted string, decoding its escape sequences
+// Block strings are not supported
+func (p *gqlParser) readString(location gqlLocation) {
+	if strings.HasPrefix(p.source[p.offset:], `"""`) {
+		p.fail(location, "Block strings are not supported.")
+	}
+	p.advance(1)
+
+	var value strings.Builder
+	for {
+		if p.offset == len(p.source) || p.source[p.offset] == '\n' || p.source[p.offset] == '\r' {
+			p.fail(gqlLocation{p.line, p.column}, "Unterminated string.")
+		}
+		c := p.source[p.offset]
+		if c == '"' {
+			p.advance(1)
+			break
+		}
+		if c != '\\' {
+			r, size := utf8.DecodeRuneInString(p.source[p.offset:])
+			value.WriteRune(r)
+			p.advance(size)
+			continue
+		}
+
+		escape := gqlLocation{p.line, p.column}
+		if p.offset+1 == len(p.source) {
+			p.fail(escape, "Unterminated string.")
+		}
+		switch e := p.source[p.offset+1]; e {
+		case '"', '\\', '/':
+			value.WriteByte(e)
+		case 'b':
+			value.WriteByte('\b')
+		case 'f':
+			value.WriteByte('\f')
+		case 'n':
+			value.WriteByte('\n')
+		case 'r':
+			value.WriteByte('\r')
+		case 't':
+			value.WriteByte('\t')
+		case 'u':
+			if p.offset+6 > len(p.source) {
+				p.fail(escape, "Invalid Unicode escape sequence.")
+			}
+			code, err := strconv.ParseUint(p.source[p.offset+2:p.offset+6], 16, 32)
+			if err != nil {
+				p.fail(escape, "Invalid Unicode escape sequence: \"\\u%v\".", p.source[p.offset+2:p.offset+6])
+			}
+			value.WriteRune(rune(code))
+			p.advance(4)
+		default:
+			p.fail(escape, "Invalid character escape sequence: \"\\%c\".", e)
+		}
+		p.advance(2)
+	}
+	p.token = gqlToken{tokenString, value.String(), location}
+}
+
+// advance moves the offset forward, keeping track of the line and column
+// Columns count bytes, which matches characters for ASCII queries
+func (p *gqlParser) advance(n int) {
+	for i := 0; i < n; i++ {
+		if p.source[p.offset] == '\n' || (p.source[p.offset] == '\r' &&
+			(p.offset+1 == len(p.source) || p.source[p.offset+1] != '\n')) {
+			p.line++
+			p.column = 1
+		} else {
+			p.column++
+		}
+		p.offset++
+	}
+}
+
+func isLetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
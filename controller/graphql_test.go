@@ -0,0 +1,160 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestGraphQL(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, dal.Route{Origin: "BRC", Destination: "SCL", Cost: 5, Carrier: "LA", FareID: "LA100"})
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+	addRoute(t, dal.Route{Origin: "SCL", Destination: "CDG", Cost: 20, Bidirectional: true})
+
+	var tooManyFields strings.Builder
+	for i := 0; i <= maxGraphQLFields/2; i++ {
+		fmt.Fprintf(&tooManyFields, `b%v: bestRoute(origin: "GRU", destination: "CDG") { cost } `, i)
+	}
+
+	var tests = []struct {
+		name     string
+		req      gqlRequest
+		status   int
+		expected string
+	}{
+		{"routes", gqlRequest{Query: "{ routes { origin destination cost } }"}, http.StatusOK,
+			`{"data":{"routes":[{"origin":"GRU","destination":"BRC","cost":10},{"origin":"BRC","destination":"SCL","cost":5},` +
+				`{"origin":"GRU","destination":"CDG","cost":75},{"origin":"SCL","destination":"CDG","cost":20}]}}`},
+		{"one request", gqlRequest{Query: `
+			# Everything the trip page needs
+			query Trip($origin: String!, $destination: String = "CDG") {
+				best: bestRoute(origin: $origin, destination: $destination) {
+					route
+					cost
+					legs { origin destination carrier fareId }
+				}
+				airport(code: "SCL") { code outbound { destination cost bidirectional } inbound { origin } }
+			}`, Variables: map[string]interface{}{"origin": "GRU"}}, http.StatusOK,
+			`{"data":{"best":{"route":["GRU","BRC","SCL","CDG"],"cost":35,"legs":[` +
+				`{"origin":"GRU","destination":"BRC","carrier":null,"fareId":null},` +
+				`{"origin":"BRC","destination":"SCL","carrier":"LA","fareId":"LA100"},` +
+				`{"origin":"SCL","destination":"CDG","carrier":null,"fareId":null}]},` +
+				`"airport":{"code":"SCL","outbound":[{"destination":"CDG","cost":20,"bidirectional":true}],"inbound":[{"origin":"BRC"},{"origin":"CDG"}]}}}`},
+		{"no route", gqlRequest{Query: `{ bestRoute(origin: "CDG", destination: "GRU") { cost } airport(code: "FCO") { code } }`}, http.StatusOK,
+			`{"data":{"bestRoute":null,"airport":null}}`},
+		{"aliases", gqlRequest{Query: `{ a: bestRoute(origin: "GRU", destination: "CDG") { cost } b: bestRoute(origin: "CDG", destination: "BRC") { route } }`}, http.StatusOK,
+			`{"data":{"a":{"cost":35},"b":null}}`},
+		{"too many fields", gqlRequest{Query: "{ " + tooManyFields.String() + "}"}, http.StatusBadRequest,
+			fmt.Sprintf(`{"errors":[{"message":"Operation selects %v fields, at most %v are allowed."}]}`, maxGraphQLFields+2, maxGraphQLFields)},
+		{"same field twice", gqlRequest{Query: `{ __typename airport(code: "CDG") { code } airport(code: "CDG") { __typename inbound { origin } } }`}, http.StatusOK,
+			`{"data":{"__typename":"Query","airport":{"code":"CDG","__typename":"Airport","inbound":[{"origin":"GRU"},{"origin":"SCL"}]}}}`},
+		{"operation name", gqlRequest{Query: `query A { routes { cost } } query B { airport(code: "GRU") { code } }`, OperationName: "B"}, http.StatusOK,
+			`{"data":{"airport":{"code":"GRU"}}}`},
+		{"syntax error", gqlRequest{Query: "{ routes { origin }"}, http.StatusBadRequest,
+			`{"errors":[{"message":"Syntax Error: Expected Name, found <EOF>.","locations":[{"line":1,"column":20}]}]}`},
+		{"unknown field", gqlRequest{Query: `{ bestRoute(origin: "GRU", destination: "CDG") { route price } }`}, http.StatusBadRequest,
+			`{"errors":[{"message":"Cannot query field \"price\" on type \"BestRoute\".","locations":[{"line":1,"column":56}]}]}`},
+		{"missing argument", gqlRequest{Query: `{ bestRoute(origin: "GRU") { cost } }`}, http.StatusBadRequest,
+			`{"errors":[{"message":"Field \"bestRoute\" argument \"destination\" of type \"String!\" is required, but it was not provided.","locations":[{"line":1,"column":3}]}]}`},
+		{"missing selection", gqlRequest{Query: `{ routes }`}, http.StatusBadRequest,
+			`{"errors":[{"message":"Field \"routes\" of type \"[Route!]!\" must have a selection of subfields. Did you mean \"routes { ... }\"?","locations":[{"line":1,"column":3}]}]}`},
+		{"missing variable", gqlRequest{Query: `query ($code: String!) { airport(code: $code) { code } }`}, http.StatusBadRequest,
+			`{"errors":[{"message":"Variable \"$code\" of required type \"String!\" was not provided.","locations":[{"line":1,"column":8}]}]}`},
+		{"mutation", gqlRequest{Query: `mutation { routes { cost } }`}, http.StatusBadRequest,
+			`{"errors":[{"message":"Only queries are supported, mutation is not.","locations":[{"line":1,"column":1}]}]}`},
+		{"ambiguous operation", gqlRequest{Query: `query A { routes { cost } } query B { routes { cost } }`}, http.StatusBadRequest,
+			`{"errors":[{"message":"Must provide operation name if query contains multiple operations."}]}`},
+		{"empty query", gqlRequest{}, http.StatusBadRequest, `{"errors":[{"message":"Must provide query string."}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postJSON(t, "/api/v1/graphql", tt.req)
+			if status != tt.status || body != tt.expected {
+				t.Errorf("/graphql expected %v %v, got %v %v", tt.status, tt.expected, status, body)
+			}
+		})
+	}
+
+	// Queries are also accepted as params
+	params := url.Values{"query": {"query ($code: String!) { airport(code: $code) { inbound { origin } } }"}, "variables": {`{"code":"BRC"}`}}
+	status, body := getBody(t, "/api/v1/graphql?"+params.Encode())
+	if expected := `{"data":{"airport":{"inbound":[{"origin":"GRU"}]}}}`; status != http.StatusOK || body != expected {
+		t.Errorf("GET /graphql expected %v, got %v %v", expected, status, body)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestGraphQLNegativeCycle(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", -5))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "BRC", 2))
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	// The field failing is null, the others are still resolved
+	status, body := postJSON(t, "/api/v1/graphql", gqlRequest{Query: `{ bestRoute(origin: "GRU", destination: "SCL") { cost } routes { cost } }`})
+	expected := `{"data":{"bestRoute":null,"routes":[{"cost":10},{"cost":-5},{"cost":2}]},` +
+		`"errors":[{"message":"negative cost cycle: %v","locations":[{"line":1,"column":3}],"path":["bestRoute"]}]}`
+	if status != http.StatusOK || (body != fmt.Sprintf(expected, "BRC > SCL > BRC") && body != fmt.Sprintf(expected, "SCL > BRC > SCL")) {
+		t.Errorf("/graphql expected %v, got %v %v", expected, status, body)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestParseQuery(t *testing.T) {
+	var tests = []struct {
+		name     string
+		query    string
+		expected string
+		location gqlLocation
+	}{
+		{"unterminated string", `{ airport(code: "GRU) { code } }`, "Syntax Error: Unterminated string.", gqlLocation{1, 33}},
+		{"invalid escape", `{ airport(code: "G\qU") { code } }`, `Syntax Error: Invalid character escape sequence: "\q".`, gqlLocation{1, 19}},
+		{"fragment", "{\n  ...Trip\n}", "Syntax Error: Fragments are not supported.", gqlLocation{2, 3}},
+		{"directive", `{ routes @skip(if: true) { cost } }`, "Syntax Error: Directives are not supported.", gqlLocation{1, 10}},
+		{"empty selection", `{ }`, `Syntax Error: Expected Name, found "}".`, gqlLocation{1, 3}},
+		{"invalid number", `{ airport(code: 1.) { code } }`, "Syntax Error: Invalid number, expected digit.", gqlLocation{1, 19}},
+		{"unexpected character", `{ routes ? }`, "Syntax Error: Unexpected character '?'.", gqlLocation{1, 10}},
+		{"empty", "  # nothing", "Syntax Error: Unexpected <EOF>.", gqlLocation{1, 12}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseQuery(tt.query)
+			syntaxErr, ok := err.(*gqlSyntaxError)
+			if !ok || syntaxErr.Error() != tt.expected || syntaxErr.Location != tt.location {
+				t.Errorf("parseQuery expected %v at %v, got %v", tt.expected, tt.location, err)
+			}
+		})
+	}
+
+	doc, err := parseQuery(`query Trip($o: String! = "GRU") { a: airport(code: "GRU", n: -1.5e2) { code } }`)
+	if err != nil {
+		t.Fatalf("parseQuery error: %v", err)
+	}
+	op := doc.operations[0]
+	s := op.selections[0]
+	if op.name != "Trip" || op.variables[0].defaultValue.literal != "GRU" || s.alias != "a" || s.name != "airport" ||
+		s.arguments["code"].literal != "GRU" || s.arguments["n"].literal != -150.0 || s.selections[0].name != "code" {
+		t.Errorf("parseQuery decoded %+v %+v", op, s)
+	}
+}
@@ -0,0 +1,86 @@
+package controller
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/domain"
+	"errors"
+	"net/http"
+)
+
+type itineraryRequest struct {
+	Stops []string
+}
+
+type itineraryResponse struct {
+	Segments []domain.Segment
+	Cost     float32
+}
+
+type tourRequest struct {
+	Cities    []string
+	RoundTrip bool
+}
+
+// itineraryHandler handles requests directed to "/itinerary"
+func (ws *webServer) itineraryHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req itineraryRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		segments, cost, err := domain.FindItinerary(ws.routeDB.GetRoutes(), req.Stops)
+		var unreachable *domain.UnreachableError
+		var cycleErr *algorithm.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			writeError(w, http.StatusConflict, codeNegativeCycle, err.Error(),
+				map[string][]string{"cycle": cycleErr.Cycle})
+			return
+		} else if errors.As(err, &unreachable) {
+			writeError(w, http.StatusUnprocessableEntity, codeUnreachable, err.Error(),
+				map[string]string{"origin": unreachable.Origin, "destination": unreachable.Destination})
+			return
+		} else if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+			return
+		}
+
+		resp := itineraryResponse{Segments: segments, Cost: cost}
+		writeJSON(w, http.StatusOK, resp)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// tourHandler handles requests directed to "/itinerary/tour"
+func (ws *webServer) tourHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodPost:
+		var req tourRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+
+		tour, err := domain.FindCheapestTour(ws.routeDB.GetRoutes(), req.Cities, req.RoundTrip)
+		var cycleErr *algorithm.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			writeError(w, http.StatusConflict, codeNegativeCycle, err.Error(),
+				map[string][]string{"cycle": cycleErr.Cycle})
+			return
+		} else if err == domain.ErrTooManyCities {
+			writeError(w, http.StatusBadRequest, codeInvalidParam, err.Error(), map[string]string{"param": "Cities"})
+			return
+		} else if err == domain.ErrNoTour {
+			writeError(w, http.StatusUnprocessableEntity, codeNoTour, err.Error(), nil)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+			return
+		}
+
+		writeJSON(w, http.StatusOK, tour)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
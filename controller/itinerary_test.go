@@ -0,0 +1,142 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"TravelRoute/domain"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func postJSON(t *testing.T, path string, req interface{}) (int, string) {
+	js, err := json.Marshal(req)
+	if err != nil {
+		t.Fatalf("json.Marshal error: %v\n", err.Error())
+	}
+
+	resp, err := http.Post("http://localhost:8080"+path, "application/json", bytes.NewBuffer(js))
+	if err != nil {
+		t.Fatalf("http.Post error: %v\n", err.Error())
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll error: %v\n", err.Error())
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestItinerary(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, *dal.NewRoute("BRC", "CDG", 5))
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+	addRoute(t, *dal.NewRoute("CDG", "FCO", 30))
+	addRoute(t, *dal.NewRoute("FCO", "GRU", 80))
+
+	var tests = []struct {
+		name         string
+		stops        []string
+		expectStatus int
+		expectBody   string
+	}{
+		{"RoundTrip", []string{"GRU", "CDG", "FCO", "GRU"}, http.StatusOK,
+			`{"Segments":[{"Origin":"GRU","Destination":"CDG","Route":["GRU","BRC","CDG"],"Cost":15},` +
+				`{"Origin":"CDG","Destination":"FCO","Route":["CDG","FCO"],"Cost":30},` +
+				`{"Origin":"FCO","Destination":"GRU","Route":["FCO","GRU"],"Cost":80}],"Cost":125}`},
+		{"Unreachable", []string{"GRU", "CDG", "SCL"}, http.StatusUnprocessableEntity,
+			`{"code":"unreachable","message":"no route from CDG to SCL","details":{"destination":"SCL","origin":"CDG"}}`},
+		{"SingleStop", []string{"GRU"}, http.StatusBadRequest,
+			`{"code":"bad_request","message":"an itinerary needs at least 2 stops"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postJSON(t, "/itinerary", itineraryRequest{Stops: tt.stops})
+			if status != tt.expectStatus {
+				t.Errorf("/itinerary expected status %v, got %v", tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("/itinerary expected %v, got %v", tt.expectBody, body)
+			}
+		})
+	}
+
+	addRoute(t, *dal.NewRoute("CDG", "BRC", -10))
+	status, body := postJSON(t, "/itinerary", itineraryRequest{Stops: []string{"GRU", "CDG"}})
+	if status != http.StatusConflict || !strings.HasPrefix(body, `{"code":"negative_cycle","message":"negative cost cycle: `) {
+		t.Errorf("/itinerary expected %v negative_cycle, got %v %v", http.StatusConflict, status, body)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestTour(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+	addRoute(t, *dal.NewRoute("GRU", "FCO", 20))
+	addRoute(t, *dal.NewRoute("FCO", "CDG", 10))
+	addRoute(t, *dal.NewRoute("CDG", "GRU", 50))
+
+	tooManyCities := make([]string, domain.MaxTourCities+1)
+	for i := range tooManyCities {
+		tooManyCities[i] = fmt.Sprintf("C%02d", i)
+	}
+
+	var tests = []struct {
+		name         string
+		req          tourRequest
+		expectStatus int
+		expectBody   string
+	}{
+		{"RoundTrip", tourRequest{[]string{"GRU", "CDG", "FCO"}, true}, http.StatusOK,
+			`{"Order":["GRU","FCO","CDG"],"Route":["GRU","FCO","CDG","GRU"],"Cost":80}`},
+		{"OneWay", tourRequest{[]string{"GRU", "CDG", "FCO"}, false}, http.StatusOK,
+			`{"Order":["GRU","FCO","CDG"],"Route":["GRU","FCO","CDG"],"Cost":30}`},
+		{"NoTour", tourRequest{[]string{"CDG", "SCL"}, false}, http.StatusUnprocessableEntity,
+			`{"code":"no_tour","message":"no tour visits every city"}`},
+		{"SingleCity", tourRequest{[]string{"GRU"}, false}, http.StatusBadRequest,
+			`{"code":"bad_request","message":"a tour needs at least 2 distinct cities"}`},
+		{"TooManyCities", tourRequest{tooManyCities, false}, http.StatusBadRequest,
+			`{"code":"invalid_param","message":"a tour visits at most 30 distinct cities","details":{"param":"Cities"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postJSON(t, "/itinerary/tour", tt.req)
+			if status != tt.expectStatus {
+				t.Errorf("/itinerary/tour expected status %v, got %v", tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("/itinerary/tour expected %v, got %v", tt.expectBody, body)
+			}
+		})
+	}
+
+	addRoute(t, *dal.NewRoute("FCO", "BRC", 5))
+	addRoute(t, *dal.NewRoute("BRC", "FCO", -10))
+	status, body := postJSON(t, "/itinerary/tour", tourRequest{[]string{"GRU", "CDG"}, false})
+	if status != http.StatusConflict || !strings.HasPrefix(body, `{"code":"negative_cycle","message":"negative cost cycle: `) {
+		t.Errorf("/itinerary/tour expected %v negative_cycle, got %v %v", http.StatusConflict, status, body)
+	}
+
+	StopWebServer(srv)
+}
@@ -0,0 +1,134 @@
+package controller
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Timeouts defines the timeouts of the http.Server, no timeout when 0
+// ReadTimeout covers reading the whole request, WriteTimeout writing the response,
+// and IdleTimeout how long a connection is kept waiting for the next request
+type Timeouts struct {
+	ReadTimeout  time.Duration
+	WriteTimeout time.Duration
+	IdleTimeout  time.Duration
+}
+
+// DefaultTimeouts are the timeouts used when none is set
+var DefaultTimeouts = Timeouts{ReadTimeout: 15 * time.Second, WriteTimeout: 30 * time.Second, IdleTimeout: 2 * time.Minute}
+
+// DefaultMaxBodyBytes is the size the request bodies are capped to when none is set
+const DefaultMaxBodyBytes = 1 << 20
+
+// RateLimit defines how many requests each client may make, as a token bucket
+// The bucket holds up to Burst requests, refilled at Rate requests per second
+// Clients are told apart by the address of the connection, see clientKey,
+// so the clients behind a proxy share its bucket: X-Forwarded-For is not trusted
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// bodyTooLarge tells whether err is the one http.MaxBytesReader fails with once reading past the cap
+// The error has no type of its own, so it is told apart by its message
+func bodyTooLarge(err error) bool {
+	return err != nil && err.Error() == "http: request body too large"
+}
+
+// bucket holds the tokens left to a client
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// RateLimiter keeps a token bucket per client
+// It is shared by the webserver and the RPC server
+type RateLimiter struct {
+	limit   RateLimit
+	mutex   sync.Mutex
+	buckets map[string]*bucket
+	// swept is when the buckets full were last dropped, they are created again when needed
+	swept time.Time
+}
+
+// NewRateLimiter constructs a RateLimiter allowing each client the limit
+func NewRateLimiter(limit RateLimit) *RateLimiter {
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+	return &RateLimiter{limit: limit, buckets: make(map[string]*bucket)}
+}
+
+// allow takes a token from the bucket of the client at the instant
+// Returns false in case there is none left, along with how long until there is
+func (rl *RateLimiter) allow(client string, at time.Time) (bool, time.Duration) {
+	rl.mutex.Lock()
+	defer rl.mutex.Unlock()
+
+	burst := float64(rl.limit.Burst)
+	// Buckets idle long enough to be refilled are the same as new ones
+	refill := time.Duration(burst / rl.limit.Rate * float64(time.Second))
+	if at.Sub(rl.swept) > refill {
+		for c, b := range rl.buckets {
+			if at.Sub(b.last) > refill {
+				delete(rl.buckets, c)
+			}
+		}
+		rl.swept = at
+	}
+
+	b, found := rl.buckets[client]
+	if !found {
+		b = &bucket{burst, at}
+		rl.buckets[client] = b
+	}
+	b.tokens = math.Min(burst, b.tokens+at.Sub(b.last).Seconds()*rl.limit.Rate)
+	b.last = at
+
+	if b.tokens < 1 {
+		return false, time.Duration((1 - b.tokens) / rl.limit.Rate * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// Allow takes a token from the bucket of the client of the request
+// Returns false in case there is none left, along with how long until there is
+func (rl *RateLimiter) Allow(r *http.Request) (bool, time.Duration) {
+	return rl.allow(clientKey(r.RemoteAddr), time.Now())
+}
+
+// clientKey identifies the client connected from the address
+// IPv4 clients, IPv4-mapped IPv6 ones included, are told apart by their address, IPv6 ones by its /64 prefix,
+// as a single host is usually handed a whole /64 to pick addresses from
+func clientKey(remoteAddr string) string {
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		host = remoteAddr
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return host
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.String()
+	}
+	return ip.Mask(net.CIDRMask(64, 128)).String() + "/64"
+}
+
+// throttle checks the client of the request has tokens left
+// Returns false in case it does not, after replying with the error
+func (rl *RateLimiter) throttle(w http.ResponseWriter, r *http.Request) bool {
+	allowed, wait := rl.Allow(r)
+	if !allowed {
+		retry := int(math.Ceil(wait.Seconds()))
+		w.Header().Set("Retry-After", strconv.Itoa(retry))
+		writeError(w, http.StatusTooManyRequests, codeRateLimited, "Too many requests, try again later",
+			map[string]int{"retryAfter": retry})
+	}
+	return allowed
+}
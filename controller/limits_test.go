@@ -0,0 +1,196 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"errors"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+	rl := NewRateLimiter(RateLimit{Rate: 2, Burst: 3})
+	start := time.Date(2020, 6, 2, 15, 4, 5, 0, time.UTC)
+
+	var tests = []struct {
+		name    string
+		client  string
+		at      time.Duration
+		allowed bool
+		wait    time.Duration
+	}{
+		{"burst", "10.0.0.1", 0, true, 0},
+		{"burst", "10.0.0.1", 0, true, 0},
+		{"burst", "10.0.0.1", 0, true, 0},
+		{"empty", "10.0.0.1", 0, false, 500 * time.Millisecond},
+		{"other client", "10.0.0.2", 0, true, 0},
+		{"half token", "10.0.0.1", 250 * time.Millisecond, false, 250 * time.Millisecond},
+		{"refilled", "10.0.0.1", 500 * time.Millisecond, true, 0},
+		{"empty again", "10.0.0.1", 500 * time.Millisecond, false, 500 * time.Millisecond},
+		{"full", "10.0.0.1", time.Minute, true, 0},
+	}
+
+	for _, tt := range tests {
+		allowed, wait := rl.allow(tt.client, start.Add(tt.at))
+		if allowed != tt.allowed || wait != tt.wait {
+			t.Errorf("%v: allow expected %v %v, got %v %v", tt.name, tt.allowed, tt.wait, allowed, wait)
+		}
+	}
+
+	// Buckets refilled are dropped, the one just used is kept
+	if _, found := rl.buckets["10.0.0.2"]; found || len(rl.buckets) != 1 {
+		t.Errorf("idle buckets expected to be dropped, got %v", rl.buckets)
+	}
+}
+
+func TestClientKey(t *testing.T) {
+	var tests = []struct {
+		name       string
+		remoteAddr string
+		expected   string
+	}{
+		{"IPv4", "10.0.0.1:1234", "10.0.0.1"},
+		{"IPv6", "[2001:db8:1:2:3:4:5:6]:1234", "2001:db8:1:2::/64"},
+		{"IPv6 same prefix", "[2001:db8:1:2:ffff::1]:443", "2001:db8:1:2::/64"},
+		{"IPv4 mapped", "[::ffff:10.0.0.1]:1234", "10.0.0.1"},
+		{"no port", "2001:db8::1", "2001:db8::/64"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if key := clientKey(tt.remoteAddr); key != tt.expected {
+				t.Errorf("clientKey expected %v, got %v", tt.expected, key)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServerWithOptions(routeDB, 8080, Options{RateLimit: &RateLimit{Rate: 0.1, Burst: 2}})
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	for i := 0; i < 2; i++ {
+		if status, body := getBody(t, "/route"); status != http.StatusOK {
+			t.Errorf("GET /route expected %v, got %v %v", http.StatusOK, status, body)
+		}
+	}
+
+	resp, err := http.Get("http://localhost:8080/route/best?Origin=GRU&Destination=CDG")
+	if err != nil {
+		t.Fatalf("http.Get error: %v\n", err.Error())
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	expected := `{"code":"rate_limited","message":"Too many requests, try again later","details":{"retryAfter":10}}`
+	if resp.StatusCode != http.StatusTooManyRequests || string(body) != expected || resp.Header.Get("Retry-After") != "10" {
+		t.Errorf("GET /route/best expected %v, got %v %v %q", expected, resp.StatusCode, string(body), resp.Header.Get("Retry-After"))
+	}
+
+	StopWebServer(srv)
+}
+
+func TestMaxBodyBytes(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServerWithOptions(routeDB, 8080, Options{MaxBodyBytes: 128})
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	large := dal.Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: strings.Repeat("A", 128)}
+	tooLarge := `{"code":"body_too_large","message":"Request body too large"}`
+	var tests = []struct {
+		name     string
+		path     string
+		req      interface{}
+		status   int
+		expected string
+	}{
+		{"route", "/route", dal.NewRoute("GRU", "CDG", 75), http.StatusCreated, `{"Origin":"GRU","Destination":"CDG","Cost":75}`},
+		{"large route", "/route", large, http.StatusRequestEntityTooLarge, tooLarge},
+		{"batch", "/route/batch", batchRequest{[]batchOperation{{"insert", large}}}, http.StatusRequestEntityTooLarge, tooLarge},
+		{"itinerary", "/itinerary", itineraryRequest{strings.Split(strings.Repeat("GRU,", 40), ",")}, http.StatusRequestEntityTooLarge, tooLarge},
+		{"tour", "/itinerary/tour", tourRequest{strings.Split(strings.Repeat("GRU,", 40), ","), false}, http.StatusRequestEntityTooLarge, tooLarge},
+		{"watch", "/watch", watchRequest{Origin: strings.Repeat("G", 128)}, http.StatusRequestEntityTooLarge, tooLarge},
+		{"graphql", "/graphql", gqlRequest{Query: "{ routes { " + strings.Repeat("cost ", 40) + "} }"}, http.StatusRequestEntityTooLarge,
+			`{"errors":[{"message":"Request body too large."}]}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postJSON(t, tt.path, tt.req)
+			if status != tt.status || body != tt.expected {
+				t.Errorf("%v expected %v %v, got %v %v", tt.path, tt.status, tt.expected, status, body)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+}
+
+func TestBodyTooLarge(t *testing.T) {
+	var tests = []struct {
+		name     string
+		body     string
+		tooLarge bool
+	}{
+		{"smaller", "GRU", false},
+		{"exact", "GRUBRC", false},
+		{"larger", "GRUBRCSCL", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body := http.MaxBytesReader(httptest.NewRecorder(), ioutil.NopCloser(strings.NewReader(tt.body)), 6)
+			if _, err := ioutil.ReadAll(body); bodyTooLarge(err) != tt.tooLarge {
+				t.Errorf("bodyTooLarge expected %v, got %v for %v", tt.tooLarge, !tt.tooLarge, err)
+			}
+		})
+	}
+
+	if bodyTooLarge(errors.New("unexpected EOF")) {
+		t.Errorf("bodyTooLarge expected false for other errors, got true")
+	}
+}
+
+func TestTimeouts(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv.srv.ReadTimeout != DefaultTimeouts.ReadTimeout || srv.srv.WriteTimeout != DefaultTimeouts.WriteTimeout ||
+		srv.srv.IdleTimeout != DefaultTimeouts.IdleTimeout {
+		t.Errorf("http.Server expected timeouts %+v, got %v %v %v", DefaultTimeouts, srv.srv.ReadTimeout, srv.srv.WriteTimeout, srv.srv.IdleTimeout)
+	}
+	StopWebServer(srv)
+
+	timeouts := &Timeouts{ReadTimeout: time.Second, WriteTimeout: 200 * time.Millisecond}
+	srv = StartWebServerWithOptions(routeDB, 8080, Options{Timeouts: timeouts})
+	if srv.srv.ReadTimeout != time.Second || srv.srv.WriteTimeout != 200*time.Millisecond || srv.srv.IdleTimeout != 0 {
+		t.Errorf("http.Server expected timeouts %+v, got %v %v %v", timeouts, srv.srv.ReadTimeout, srv.srv.WriteTimeout, srv.srv.IdleTimeout)
+	}
+
+	// Streams are ended before the write timeout, for the client to resume them
+	start := time.Now()
+	resp, reader := openEvents(t, "")
+	defer resp.Body.Close()
+	if event := readEvent(t, reader); event != "id: 0" {
+		t.Errorf("first event expected the resume token, got %v", event)
+	}
+	if _, err := reader.ReadString('\n'); err != io.EOF {
+		t.Errorf("stream expected to end, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 200*time.Millisecond {
+		t.Errorf("stream expected to end before the write timeout, ended after %v", elapsed)
+	}
+
+	StopWebServer(srv)
+}
@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/domain"
+	"encoding/csv"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+type matrixResponse struct {
+	Airports []string
+	// Costs holds null where there is no route
+	Costs [][]*float32
+}
+
+// matrixHandler handles requests directed to "/route/matrix"
+func (ws *webServer) matrixHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		airports, costs, err := domain.FindCostMatrix(ws.routeDB.GetRoutes(), listParam(r, "airports"))
+		var cycleErr *algorithm.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			writeError(w, http.StatusConflict, codeNegativeCycle, err.Error(),
+				map[string][]string{"cycle": cycleErr.Cycle})
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+			return
+		}
+		if r.FormValue("format") == "csv" || r.Header.Get("Accept") == "text/csv" {
+			writeMatrixCSV(w, airports, costs)
+			return
+		}
+
+		resp := matrixResponse{Airports: airports, Costs: make([][]*float32, len(costs))}
+		for i := range costs {
+			resp.Costs[i] = make([]*float32, len(costs[i]))
+			for j := range costs[i] {
+				if !math.IsInf(float64(costs[i][j]), 1) {
+					resp.Costs[i][j] = &costs[i][j]
+				}
+			}
+		}
+		writeJSON(w, http.StatusOK, resp)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// writeMatrixCSV writes the cost matrix as CSV with the airports as header row and column
+// Cells without route are left empty
+func writeMatrixCSV(w http.ResponseWriter, airports []string, costs [][]float32) {
+	w.Header().Set("Content-Type", "text/csv")
+	w.Header().Set("Content-Disposition", `attachment; filename="matrix.csv"`)
+
+	writer := csv.NewWriter(w)
+	writer.Write(append([]string{""}, airports...))
+	for i, origin := range airports {
+		record := []string{origin}
+		for _, cost := range costs[i] {
+			if math.IsInf(float64(cost), 1) {
+				record = append(record, "")
+			} else {
+				record = append(record, strconv.FormatFloat(float64(cost), 'f', -1, 32))
+			}
+		}
+		writer.Write(record)
+	}
+	writer.Flush()
+}
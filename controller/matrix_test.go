@@ -0,0 +1,73 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func getBody(t *testing.T, path string) (int, string) {
+	resp, err := http.Get("http://localhost:8080" + path)
+	if err != nil {
+		t.Fatalf("http.Get error: %v\n", err.Error())
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll error: %v\n", err.Error())
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestMatrix(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, *dal.NewRoute("BRC", "SCL", 5.5))
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+	addRoute(t, *dal.NewRoute("SCL", "CDG", 20))
+
+	var tests = []struct {
+		name   string
+		path   string
+		expect string
+	}{
+		{"Requested", "/route/matrix?airports=GRU,CDG,SCL",
+			`{"Airports":["GRU","CDG","SCL"],"Costs":[[0,35.5,15.5],[null,0,null],[null,20,0]]}`},
+		{"All", "/route/matrix",
+			`{"Airports":["BRC","CDG","GRU","SCL"],"Costs":[[0,25.5,null,5.5],[null,0,null,null],[10,35.5,0,15.5],[null,20,null,0]]}`},
+		{"CSV", "/route/matrix?airports=GRU,CDG,SCL&format=csv",
+			",GRU,CDG,SCL\nGRU,0,35.5,15.5\nCDG,,0,\nSCL,,20,0\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := getBody(t, tt.path)
+			if status != http.StatusOK {
+				t.Errorf("%v expected status %v, got %v", tt.path, http.StatusOK, status)
+			}
+			if body != tt.expect {
+				t.Errorf("%v expected %v, got %v", tt.path, tt.expect, body)
+			}
+		})
+	}
+
+	// A negative cycle leaves the costs through it unbounded
+	addRoute(t, *dal.NewRoute("CDG", "SCL", -30))
+	status, body := getBody(t, "/route/matrix")
+	if status != http.StatusConflict || !strings.HasPrefix(body, `{"code":"negative_cycle","message":"negative cost cycle: `) {
+		t.Errorf("/route/matrix expected %v negative_cycle, got %v %v", http.StatusConflict, status, body)
+	}
+
+	StopWebServer(srv)
+}
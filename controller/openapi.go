@@ -0,0 +1,1055 @@
+package controller
+
+import (
+	"net/http"
+)
+
+// openAPIHandler handles requests directed to "/openapi.json"
+func (ws *webServer) openAPIHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(openAPIDocument))
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// openAPIDocument describes every endpoint registered by newWebServer
+// It must be kept up to date along with the handlers, which is verified by the tests
+const openAPIDocument = `{
+  "openapi": "3.0.3",
+  "info": {
+    "title": "TravelRoute",
+    "description": "Finds the cheapest route between airports.",
+    "version": "1.0.0"
+  },
+  "servers": [
+    {"url": "/api/v1"},
+    {"url": "/", "description": "Legacy aliases"}
+  ],
+  "security": [
+    {"ApiKey": []},
+    {"Bearer": []}
+  ],
+  "paths": {
+    "/route": {
+      "get": {
+        "summary": "Lists the stored routes",
+        "description": "Routes are listed in the order they were stored, unless sorted. When limited, the next page is linked by the Link header. Listed as of an instant, the routes carry when they were recorded.",
+        "parameters": [
+          {"name": "Origin", "in": "query", "description": "Only routes leaving this airport", "schema": {"type": "string"}, "example": "GRU"},
+          {"name": "Destination", "in": "query", "description": "Only routes arriving at this airport", "schema": {"type": "string"}, "example": "CDG"},
+          {"name": "MinCost", "in": "query", "description": "Only routes costing at least this much", "schema": {"type": "number"}, "example": 10},
+          {"name": "MaxCost", "in": "query", "description": "Only routes costing at most this much", "schema": {"type": "number"}, "example": 50},
+          {"name": "sort", "in": "query", "description": "Field the routes are sorted by, prefixed by '-' for descending order",
+            "schema": {"type": "string", "enum": ["origin", "-origin", "destination", "-destination", "cost", "-cost"]}, "example": "cost"},
+          {"name": "limit", "in": "query", "description": "Maximum routes listed", "schema": {"type": "integer", "minimum": 1}, "example": 50},
+          {"name": "cursor", "in": "query", "description": "Cursor of the page, taken from the Link header of the previous one", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/AsOf"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Stored routes",
+            "headers": {
+              "Link": {
+                "description": "Next page, present when there are routes left",
+                "schema": {"type": "string"},
+                "example": "</api/v1/route?cursor=MTZhYmMuMQ&limit=50>; rel=\"next\""
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/RouteVersion"}},
+                "example": [
+                  {"Origin": "GRU", "Destination": "BRC", "Cost": 10},
+                  {"Origin": "GRU", "Destination": "CDG", "Cost": 75, "Carrier": "AF", "FareID": "AF457", "Time": "2020-06-02T15:04:05Z"}
+                ]
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      },
+      "post": {
+        "summary": "Inserts a route",
+        "requestBody": {"$ref": "#/components/requestBodies/Route"},
+        "responses": {
+          "201": {"$ref": "#/components/responses/Route"},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "413": {"$ref": "#/components/responses/BodyTooLarge"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      },
+      "put": {
+        "summary": "Inserts a route, same as POST",
+        "requestBody": {"$ref": "#/components/requestBodies/Route"},
+        "responses": {
+          "201": {"$ref": "#/components/responses/Route"},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "413": {"$ref": "#/components/responses/BodyTooLarge"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/route/best": {
+      "get": {
+        "summary": "Finds the cheapest route between two airports",
+        "parameters": [
+          {"name": "Origin", "in": "query", "required": true, "schema": {"type": "string"}, "example": "GRU"},
+          {"name": "Destination", "in": "query", "required": true, "schema": {"type": "string"}, "example": "CDG"},
+          {"name": "IncludeCarriers", "in": "query", "description": "Comma separated or repeated", "schema": {"type": "string"}},
+          {"name": "ExcludeCarriers", "in": "query", "description": "Comma separated or repeated", "schema": {"type": "string"}},
+          {"name": "Avoid", "in": "query", "description": "Airports never gone through, comma separated or repeated", "schema": {"type": "string"}},
+          {"name": "Via", "in": "query", "description": "Airports stopped at in order, comma separated or repeated", "schema": {"type": "string"}},
+          {"$ref": "#/components/parameters/AsOf"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Cheapest route, empty in case there is none",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/BestRoute"},
+                "example": {
+                  "Route": ["GRU", "BRC", "SCL", "ORL", "CDG"],
+                  "Legs": [
+                    {"Origin": "GRU", "Destination": "BRC", "Cost": 10},
+                    {"Origin": "BRC", "Destination": "SCL", "Cost": 5},
+                    {"Origin": "SCL", "Destination": "ORL", "Cost": 20},
+                    {"Origin": "ORL", "Destination": "CDG", "Cost": 5}
+                  ],
+                  "Cost": 40
+                }
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "409": {"$ref": "#/components/responses/NegativeCycle"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/route/batch": {
+      "post": {
+        "summary": "Applies route operations atomically",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/BatchRequest"},
+              "example": {
+                "Operations": [
+                  {"Op": "insert", "Route": {"Origin": "BRC", "Destination": "SCL", "Cost": 5}},
+                  {"Op": "update", "Route": {"Origin": "GRU", "Destination": "BRC", "Cost": 12}},
+                  {"Op": "delete", "Route": {"Origin": "GRU", "Destination": "CDG"}}
+                ]
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Every operation was applied",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/BatchResponse"},
+                "example": {"Applied": 3}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "413": {"$ref": "#/components/responses/BodyTooLarge"},
+          "422": {"$ref": "#/components/responses/Unprocessable"},
+          "429": {"$ref": "#/components/responses/RateLimited"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/route/events": {
+      "get": {
+        "summary": "Streams the route changes as Server-Sent Events",
+        "description": "Events are typed inserted, updated, deleted or reset, their data is the route changed. Their IDs are resume tokens.",
+        "parameters": [
+          {"name": "Last-Event-ID", "in": "header", "schema": {"type": "string"}},
+          {"name": "LastEventID", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Event stream",
+            "content": {
+              "text/event-stream": {
+                "schema": {"type": "string"},
+                "example": "id: 2\nevent: updated\ndata: {\"Origin\":\"GRU\",\"Destination\":\"BRC\",\"Cost\":12}\n\n"
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/route/matrix": {
+      "get": {
+        "summary": "Finds the cheapest cost between every pair of airports",
+        "parameters": [
+          {"name": "airports", "in": "query", "description": "Comma separated or repeated, every airport when missing", "schema": {"type": "string"}},
+          {"name": "format", "in": "query", "schema": {"type": "string", "enum": ["csv"]}}
+        ],
+        "responses": {
+          "200": {
+            "description": "Cost matrix, null where there is no route",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Matrix"},
+                "example": {"Airports": ["BRC", "GRU", "SCL"], "Costs": [[0, null, 5], [10, 0, 15], [null, null, 0]]}
+              },
+              "text/csv": {
+                "schema": {"type": "string"},
+                "example": ",BRC,GRU,SCL\nBRC,0,,5\nGRU,10,0,15\nSCL,,,0\n"
+              }
+            }
+          },
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "409": {"$ref": "#/components/responses/NegativeCycle"},
+          "429": {"$ref": "#/components/responses/RateLimited"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/route/from": {
+      "get": {
+        "summary": "Lists the airports reachable from an origin within a budget",
+        "parameters": [
+          {"name": "Origin", "in": "query", "required": true, "schema": {"type": "string"}, "example": "GRU"},
+          {"name": "MaxCost", "in": "query", "schema": {"type": "number"}, "example": 50}
+        ],
+        "responses": {
+          "200": {
+            "description": "Reachable airports ordered by cost",
+            "content": {
+              "application/json": {
+                "schema": {"type": "array", "items": {"$ref": "#/components/schemas/Reachable"}},
+                "example": [
+                  {"Destination": "BRC", "Route": ["GRU", "BRC"], "Cost": 10},
+                  {"Destination": "SCL", "Route": ["GRU", "BRC", "SCL"], "Cost": 15}
+                ]
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "409": {"$ref": "#/components/responses/NegativeCycle"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/itinerary": {
+      "post": {
+        "summary": "Finds the cheapest route for each segment of an ordered list of stops",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/ItineraryRequest"},
+              "example": {"Stops": ["GRU", "CDG", "FCO", "GRU"]}
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Itinerary",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Itinerary"},
+                "example": {
+                  "Segments": [
+                    {"Origin": "GRU", "Destination": "CDG", "Route": ["GRU", "BRC", "CDG"], "Cost": 15},
+                    {"Origin": "CDG", "Destination": "FCO", "Route": ["CDG", "FCO"], "Cost": 30}
+                  ],
+                  "Cost": 45
+                }
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "409": {"$ref": "#/components/responses/NegativeCycle"},
+          "413": {"$ref": "#/components/responses/BodyTooLarge"},
+          "422": {"$ref": "#/components/responses/Unprocessable"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/itinerary/tour": {
+      "post": {
+        "summary": "Finds the cheapest order to visit every city",
+        "description": "At most 30 distinct cities are visited, more are rejected with invalid_param.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/TourRequest"},
+              "example": {"Cities": ["GRU", "CDG", "SCL"], "RoundTrip": false}
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "Tour",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Tour"},
+                "example": {"Order": ["GRU", "SCL", "CDG"], "Route": ["GRU", "BRC", "SCL", "ORL", "CDG"], "Cost": 40}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "409": {"$ref": "#/components/responses/NegativeCycle"},
+          "413": {"$ref": "#/components/responses/BodyTooLarge"},
+          "422": {"$ref": "#/components/responses/Unprocessable"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/airport": {
+      "get": {
+        "summary": "Lists the airports connected by the routes, with the number of routes leaving and arriving at each",
+        "parameters": [
+          {"$ref": "#/components/parameters/AsOf"}
+        ],
+        "responses": {
+          "200": {
+            "description": "Airports ordered by code",
+            "content": {
+              "application/json": {
+                "schema": {"type": "array", "items": {"$ref": "#/components/schemas/AirportDegree"}},
+                "example": [
+                  {"Code": "BRC", "OutDegree": 1, "InDegree": 1},
+                  {"Code": "GRU", "OutDegree": 4, "InDegree": 0}
+                ]
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/airport/{code}/outbound": {
+      "get": {
+        "summary": "Lists the routes leaving an airport",
+        "description": "Bidirectional routes are listed on both directions, reversed when needed.",
+        "parameters": [
+          {"$ref": "#/components/parameters/Code"},
+          {"$ref": "#/components/parameters/AsOf"}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Routes"},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/AirportNotFound"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/airport/{code}/inbound": {
+      "get": {
+        "summary": "Lists the routes arriving at an airport",
+        "description": "Bidirectional routes are listed on both directions, reversed when needed.",
+        "parameters": [
+          {"$ref": "#/components/parameters/Code"},
+          {"$ref": "#/components/parameters/AsOf"}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/Routes"},
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "404": {"$ref": "#/components/responses/AirportNotFound"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/watch": {
+      "get": {
+        "summary": "Lists the watched pairs of airports",
+        "responses": {
+          "200": {
+            "description": "Watches",
+            "content": {
+              "application/json": {
+                "schema": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Watch"}},
+                "example": [
+                  {"ID": 1, "Origin": "GRU", "Destination": "CDG", "Callback": "http://pricing.example.com/notify", "Route": ["GRU", "CDG"], "Cost": 75}
+                ]
+              }
+            }
+          },
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      },
+      "post": {
+        "summary": "Watches the cheapest route between two airports",
+        "description": "The notification is posted to the callback whenever the cheapest route changes. The notifications of a watch are posted one at a time, in order. At most 100 watches are registered by default.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/WatchRequest"},
+              "example": {"Origin": "GRU", "Destination": "CDG", "Callback": "http://pricing.example.com/notify"}
+            }
+          }
+        },
+        "responses": {
+          "201": {
+            "description": "Watch registered, with the current cheapest route",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Watch"},
+                "example": {"ID": 1, "Origin": "GRU", "Destination": "CDG", "Callback": "http://pricing.example.com/notify", "Route": ["GRU", "CDG"], "Cost": 75}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "409": {
+            "description": "A negative cost cycle is reachable from the origin (negative_cycle), or the maximum amount of watches is registered (too_many_watches)",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Error"},
+                "example": {"code": "too_many_watches", "message": "At most 100 watches may be registered"}
+              }
+            }
+          },
+          "413": {"$ref": "#/components/responses/BodyTooLarge"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        },
+        "callbacks": {
+          "cheapestRouteChanged": {
+            "{$request.body#/Callback}": {
+              "post": {
+                "requestBody": {
+                  "required": true,
+                  "content": {
+                    "application/json": {
+                      "schema": {"$ref": "#/components/schemas/WatchNotification"},
+                      "example": {
+                        "ID": 1,
+                        "Origin": "GRU",
+                        "Destination": "CDG",
+                        "Old": {"Route": ["GRU", "CDG"], "Cost": 75},
+                        "New": {"Route": ["GRU", "BRC", "CDG"], "Cost": 30}
+                      }
+                    }
+                  }
+                },
+                "responses": {
+                  "200": {"description": "Notification received, any other status is retried"}
+                }
+              }
+            }
+          }
+        }
+      }
+    },
+    "/watch/{id}": {
+      "delete": {
+        "summary": "Stops watching a pair of airports",
+        "description": "Notifications not posted yet are dropped.",
+        "parameters": [
+          {"name": "id", "in": "path", "required": true, "description": "ID of the watch", "schema": {"type": "integer"}, "example": 1}
+        ],
+        "responses": {
+          "204": {"description": "Watch removed"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "404": {
+            "description": "No watch has the ID",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Error"},
+                "example": {"code": "watch_not_found", "message": "No watch 1", "details": {"id": 1}}
+              }
+            }
+          },
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/graphql": {
+      "get": {
+        "summary": "Executes a GraphQL query",
+        "description": "An operation selects at most 100 fields, counting the nested and aliased ones.",
+        "parameters": [
+          {"name": "query", "in": "query", "required": true, "schema": {"type": "string"}, "example": "{ bestRoute(origin: \"GRU\", destination: \"CDG\") { route cost } }"},
+          {"name": "variables", "in": "query", "description": "JSON encoded", "schema": {"type": "string"}},
+          {"name": "operationName", "in": "query", "schema": {"type": "string"}}
+        ],
+        "responses": {
+          "200": {"$ref": "#/components/responses/GraphQL"},
+          "400": {"$ref": "#/components/responses/GraphQLError"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      },
+      "post": {
+        "summary": "Executes a GraphQL query",
+        "description": "An operation selects at most 100 fields, counting the nested and aliased ones.",
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {"$ref": "#/components/schemas/GraphQLRequest"},
+              "example": {
+                "query": "query Trip($code: String!) { airport(code: $code) { outbound { destination cost } } }",
+                "variables": {"code": "GRU"}
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {"$ref": "#/components/responses/GraphQL"},
+          "400": {"$ref": "#/components/responses/GraphQLError"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "413": {"$ref": "#/components/responses/GraphQLTooLarge"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    },
+    "/admin/compact": {
+      "post": {
+        "summary": "Rewrites the routes file dropping the versions of the routes older than the retention",
+        "description": "The routes in effect since then are kept, so they can still be listed as of any later instant. Earlier instants are rejected afterwards.",
+        "parameters": [
+          {"name": "retention", "in": "query", "description": "How long the versions are kept, 720h by default", "schema": {"type": "string"}, "example": "168h"}
+        ],
+        "responses": {
+          "200": {
+            "description": "File compacted",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/CompactResponse"},
+                "example": {"LinesDropped": 3}
+              }
+            }
+          },
+          "400": {"$ref": "#/components/responses/BadRequest"},
+          "401": {"$ref": "#/components/responses/Unauthorized"},
+          "403": {"$ref": "#/components/responses/Forbidden"},
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "409": {
+            "description": "The routes are not backed by a file",
+            "content": {
+              "application/json": {
+                "schema": {"$ref": "#/components/schemas/Error"},
+                "example": {"code": "not_file_backed", "message": "database is not backed by a routes file"}
+              }
+            }
+          },
+          "429": {"$ref": "#/components/responses/RateLimited"},
+          "500": {"$ref": "#/components/responses/InternalError"}
+        }
+      }
+    },
+    "/openapi.json": {
+      "get": {
+        "summary": "Describes the API",
+        "security": [],
+        "responses": {
+          "200": {
+            "description": "This document",
+            "content": {
+              "application/json": {
+                "schema": {"type": "object"}
+              }
+            }
+          },
+          "405": {"$ref": "#/components/responses/MethodNotAllowed"},
+          "429": {"$ref": "#/components/responses/RateLimited"}
+        }
+      }
+    }
+  },
+  "components": {
+    "securitySchemes": {
+      "ApiKey": {
+        "type": "apiKey",
+        "in": "header",
+        "name": "X-API-Key",
+        "description": "Required when the server is started with a key file"
+      },
+      "Bearer": {
+        "type": "http",
+        "scheme": "bearer",
+        "description": "The same keys, sent as bearer tokens"
+      }
+    },
+    "parameters": {
+      "AsOf": {
+        "name": "AsOf",
+        "in": "query",
+        "description": "Only routes recorded up to this instant, which can not be before the last compaction dropped the versions up to",
+        "schema": {"type": "string", "format": "date-time"},
+        "example": "2020-06-02T15:04:05Z"
+      },
+      "Code": {
+        "name": "code",
+        "in": "path",
+        "required": true,
+        "description": "Code of the airport",
+        "schema": {"type": "string"},
+        "example": "GRU"
+      }
+    },
+    "requestBodies": {
+      "Route": {
+        "required": true,
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Route"},
+            "example": {"Origin": "SCL", "Destination": "GRU", "Cost": 2}
+          }
+        }
+      }
+    },
+    "responses": {
+      "Route": {
+        "description": "Route stored",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Route"},
+            "example": {"Origin": "SCL", "Destination": "GRU", "Cost": 2}
+          }
+        }
+      },
+      "GraphQL": {
+        "description": "Query executed, errors hold the fields that failed to resolve",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/GraphQLResponse"},
+            "example": {"data": {"bestRoute": {"route": ["GRU", "BRC", "SCL", "ORL", "CDG"], "cost": 40}}}
+          }
+        }
+      },
+      "GraphQLError": {
+        "description": "Query invalid, it was not executed",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/GraphQLResponse"},
+            "example": {"errors": [{"message": "Cannot query field \"price\" on type \"BestRoute\".", "locations": [{"line": 1, "column": 54}]}]}
+          }
+        }
+      },
+      "GraphQLTooLarge": {
+        "description": "The request body is larger than the server accepts",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/GraphQLResponse"},
+            "example": {"errors": [{"message": "Request body too large."}]}
+          }
+        }
+      },
+      "Routes": {
+        "description": "Routes",
+        "content": {
+          "application/json": {
+            "schema": {"type": "array", "items": {"$ref": "#/components/schemas/Route"}},
+            "example": [
+              {"Origin": "GRU", "Destination": "BRC", "Cost": 10},
+              {"Origin": "GRU", "Destination": "CDG", "Cost": 75, "Carrier": "AF", "FareID": "AF457"}
+            ]
+          }
+        }
+      },
+      "AirportNotFound": {
+        "description": "No route connects the airport",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "airport_not_found", "message": "No route connects FCO", "details": {"code": "FCO"}}
+          }
+        }
+      },
+      "BadRequest": {
+        "description": "Invalid request",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "missing_param", "message": "Missing 'Origin' param", "details": {"param": "Origin"}}
+          }
+        }
+      },
+      "Unauthorized": {
+        "description": "The API key is missing or unknown",
+        "headers": {
+          "WWW-Authenticate": {"schema": {"type": "string"}, "example": "Bearer realm=\"TravelRoute\""}
+        },
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "unauthorized", "message": "Missing or unknown API key"}
+          }
+        }
+      },
+      "Forbidden": {
+        "description": "Only admin keys may change the routes and manage the watches",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "forbidden", "message": "The read-only role can not change the routes", "details": {"role": "read-only"}}
+          }
+        }
+      },
+      "BodyTooLarge": {
+        "description": "The request body is larger than the server accepts",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "body_too_large", "message": "Request body too large"}
+          }
+        }
+      },
+      "RateLimited": {
+        "description": "The client made too many requests, when the server limits them. Clients are told apart by the address they connect from, the /64 prefix for IPv6; X-Forwarded-For is not trusted",
+        "headers": {
+          "Retry-After": {"description": "Seconds until the next request is accepted", "schema": {"type": "integer"}, "example": 1}
+        },
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "rate_limited", "message": "Too many requests, try again later", "details": {"retryAfter": 1}}
+          }
+        }
+      },
+      "MethodNotAllowed": {
+        "description": "Method not handled",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "method_not_allowed", "message": "DELETE: Method not allowed"}
+          }
+        }
+      },
+      "NegativeCycle": {
+        "description": "A negative cost cycle is reachable from the origin",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "negative_cycle", "message": "negative cost cycle: BRC > SCL > BRC", "details": {"cycle": ["BRC", "SCL", "BRC"]}}
+          }
+        }
+      },
+      "Unprocessable": {
+        "description": "The request can not be fulfilled with the stored routes",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "unreachable", "message": "no route from CDG to SCL", "details": {"origin": "CDG", "destination": "SCL"}}
+          }
+        }
+      },
+      "InternalError": {
+        "description": "The routes could not be written",
+        "content": {
+          "application/json": {
+            "schema": {"$ref": "#/components/schemas/Error"},
+            "example": {"code": "internal_error", "message": "write routes.csv: no space left on device"}
+          }
+        }
+      }
+    },
+    "schemas": {
+      "Route": {
+        "type": "object",
+        "description": "Routes holding ',', '=' or line breaks in their fields, or without Origin or Destination, are rejected with invalid_param",
+        "required": ["Origin", "Destination", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "Origin": {"type": "string", "minLength": 1, "pattern": "^[^,=\\r\\n]+$"},
+          "Destination": {"type": "string", "minLength": 1, "pattern": "^[^,=\\r\\n]+$"},
+          "Cost": {"type": "number"},
+          "Carrier": {"type": "string", "pattern": "^[^,=\\r\\n]*$"},
+          "FareID": {"type": "string", "pattern": "^[^,=\\r\\n]*$"},
+          "Bidirectional": {"type": "boolean"}
+        }
+      },
+      "RouteVersion": {
+        "type": "object",
+        "required": ["Origin", "Destination", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "Origin": {"type": "string"},
+          "Destination": {"type": "string"},
+          "Cost": {"type": "number"},
+          "Carrier": {"type": "string"},
+          "FareID": {"type": "string"},
+          "Bidirectional": {"type": "boolean"},
+          "Time": {"type": "string", "format": "date-time", "description": "When the route was recorded, listed as of an instant only for routes not written by hand"}
+        }
+      },
+      "BestRoute": {
+        "type": "object",
+        "required": ["Route", "Legs", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "Route": {"type": "array", "nullable": true, "items": {"type": "string"}},
+          "Legs": {"type": "array", "nullable": true, "items": {"$ref": "#/components/schemas/Route"}},
+          "Cost": {"type": "number"}
+        }
+      },
+      "BatchRequest": {
+        "type": "object",
+        "required": ["Operations"],
+        "additionalProperties": false,
+        "properties": {
+          "Operations": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["Op", "Route"],
+              "additionalProperties": false,
+              "properties": {
+                "Op": {"type": "string", "enum": ["insert", "update", "delete"]},
+                "Route": {
+                  "type": "object",
+                  "required": ["Origin", "Destination"],
+                  "properties": {
+                    "Origin": {"type": "string"},
+                    "Destination": {"type": "string"},
+                    "Cost": {"type": "number"},
+                    "Carrier": {"type": "string"},
+                    "FareID": {"type": "string"},
+                    "Bidirectional": {"type": "boolean"}
+                  }
+                }
+              }
+            }
+          }
+        }
+      },
+      "BatchResponse": {
+        "type": "object",
+        "required": ["Applied"],
+        "additionalProperties": false,
+        "properties": {
+          "Applied": {"type": "integer"}
+        }
+      },
+      "Matrix": {
+        "type": "object",
+        "required": ["Airports", "Costs"],
+        "additionalProperties": false,
+        "properties": {
+          "Airports": {"type": "array", "items": {"type": "string"}},
+          "Costs": {"type": "array", "items": {"type": "array", "items": {"type": "number", "nullable": true}}}
+        }
+      },
+      "Reachable": {
+        "type": "object",
+        "required": ["Destination", "Route", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "Destination": {"type": "string"},
+          "Route": {"type": "array", "items": {"type": "string"}},
+          "Cost": {"type": "number"}
+        }
+      },
+      "AirportDegree": {
+        "type": "object",
+        "required": ["Code", "OutDegree", "InDegree"],
+        "additionalProperties": false,
+        "properties": {
+          "Code": {"type": "string"},
+          "OutDegree": {"type": "integer", "description": "Routes leaving the airport"},
+          "InDegree": {"type": "integer", "description": "Routes arriving at the airport"}
+        }
+      },
+      "ItineraryRequest": {
+        "type": "object",
+        "required": ["Stops"],
+        "additionalProperties": false,
+        "properties": {
+          "Stops": {"type": "array", "items": {"type": "string"}}
+        }
+      },
+      "Itinerary": {
+        "type": "object",
+        "required": ["Segments", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "Segments": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["Origin", "Destination", "Route", "Cost"],
+              "additionalProperties": false,
+              "properties": {
+                "Origin": {"type": "string"},
+                "Destination": {"type": "string"},
+                "Route": {"type": "array", "items": {"type": "string"}},
+                "Cost": {"type": "number"}
+              }
+            }
+          },
+          "Cost": {"type": "number"}
+        }
+      },
+      "TourRequest": {
+        "type": "object",
+        "required": ["Cities"],
+        "additionalProperties": false,
+        "properties": {
+          "Cities": {"type": "array", "items": {"type": "string"}, "description": "From 2 to 30 distinct cities, starting by the first one"},
+          "RoundTrip": {"type": "boolean"}
+        }
+      },
+      "Tour": {
+        "type": "object",
+        "required": ["Order", "Route", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "Order": {"type": "array", "items": {"type": "string"}},
+          "Route": {"type": "array", "items": {"type": "string"}},
+          "Cost": {"type": "number"}
+        }
+      },
+      "WatchRequest": {
+        "type": "object",
+        "required": ["Origin", "Destination", "Callback"],
+        "additionalProperties": false,
+        "properties": {
+          "Origin": {"type": "string"},
+          "Destination": {"type": "string"},
+          "Callback": {"type": "string", "format": "uri"}
+        }
+      },
+      "Watch": {
+        "type": "object",
+        "required": ["ID", "Origin", "Destination", "Callback", "Route", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "ID": {"type": "integer"},
+          "Origin": {"type": "string"},
+          "Destination": {"type": "string"},
+          "Callback": {"type": "string", "format": "uri"},
+          "Route": {"type": "array", "nullable": true, "items": {"type": "string"}},
+          "Cost": {"type": "number"}
+        }
+      },
+      "WatchedRoute": {
+        "type": "object",
+        "required": ["Route", "Cost"],
+        "additionalProperties": false,
+        "properties": {
+          "Route": {"type": "array", "nullable": true, "items": {"type": "string"}},
+          "Cost": {"type": "number"}
+        }
+      },
+      "WatchNotification": {
+        "type": "object",
+        "required": ["ID", "Origin", "Destination", "Old", "New"],
+        "additionalProperties": false,
+        "properties": {
+          "ID": {"type": "integer"},
+          "Origin": {"type": "string"},
+          "Destination": {"type": "string"},
+          "Old": {"$ref": "#/components/schemas/WatchedRoute"},
+          "New": {"$ref": "#/components/schemas/WatchedRoute"}
+        }
+      },
+      "CompactResponse": {
+        "type": "object",
+        "required": ["LinesDropped"],
+        "additionalProperties": false,
+        "properties": {
+          "LinesDropped": {"type": "integer"}
+        }
+      },
+      "GraphQLRequest": {
+        "type": "object",
+        "required": ["query"],
+        "additionalProperties": false,
+        "properties": {
+          "query": {"type": "string"},
+          "variables": {"type": "object", "nullable": true},
+          "operationName": {"type": "string", "nullable": true}
+        }
+      },
+      "GraphQLResponse": {
+        "type": "object",
+        "additionalProperties": false,
+        "properties": {
+          "data": {"type": "object", "nullable": true},
+          "errors": {
+            "type": "array",
+            "items": {
+              "type": "object",
+              "required": ["message"],
+              "additionalProperties": false,
+              "properties": {
+                "message": {"type": "string"},
+                "locations": {
+                  "type": "array",
+                  "items": {
+                    "type": "object",
+                    "required": ["line", "column"],
+                    "properties": {"line": {"type": "integer"}, "column": {"type": "integer"}}
+                  }
+                },
+                "path": {"type": "array", "items": {}}
+              }
+            }
+          }
+        }
+      },
+      "Error": {
+        "type": "object",
+        "required": ["code", "message"],
+        "additionalProperties": false,
+        "properties": {
+          "code": {
+            "type": "string",
+            "enum": ["bad_request", "missing_param", "invalid_param", "not_found", "method_not_allowed", "unauthorized", "forbidden", "rate_limited", "body_too_large", "negative_cycle",
+              "unreachable", "no_tour", "route_not_found", "airport_not_found", "watch_not_found", "too_many_watches", "not_file_backed", "internal_error"]
+          },
+          "message": {"type": "string"},
+          "details": {}
+        }
+      }
+    }
+  }
+}
+`
@@ -0,0 +1,337 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// openAPI decodes the document served
+func openAPI(t *testing.T) map[string]interface{} {
+	var doc map[string]interface{}
+	if err := json.Unmarshal([]byte(openAPIDocument), &doc); err != nil {
+		t.Fatalf("json.Unmarshal error: %v", err)
+	}
+	return doc
+}
+
+// resolve follows the "$ref" of node, which must point inside the document
+func resolve(doc map[string]interface{}, node interface{}) (interface{}, error) {
+	for {
+		object, ok := node.(map[string]interface{})
+		if !ok {
+			return node, nil
+		}
+		ref, ok := object["$ref"].(string)
+		if !ok {
+			return node, nil
+		}
+		if !strings.HasPrefix(ref, "#/") {
+			return nil, fmt.Errorf("external $ref %v", ref)
+		}
+
+		node = interface{}(doc)
+		for _, name := range strings.Split(ref[2:], "/") {
+			parent, ok := node.(map[string]interface{})
+			if !ok || parent[name] == nil {
+				return nil, fmt.Errorf("unresolved $ref %v", ref)
+			}
+			node = parent[name]
+		}
+	}
+}
+
+// validate checks value against the subset of the OpenAPI schema object used by the document
+func validate(doc map[string]interface{}, schema interface{}, value interface{}, at string) error {
+	resolved, err := resolve(doc, schema)
+	if err != nil {
+		return fmt.Errorf("%v: %v", at, err)
+	}
+	s, ok := resolved.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("%v: invalid schema %v", at, resolved)
+	}
+
+	if value == nil {
+		if s["type"] == nil || s["nullable"] == true {
+			return nil
+		}
+		return fmt.Errorf("%v: null is not nullable", at)
+	}
+
+	if enum, ok := s["enum"].([]interface{}); ok {
+		found := false
+		for _, e := range enum {
+			found = found || e == value
+		}
+		if !found {
+			return fmt.Errorf("%v: %v not in %v", at, value, enum)
+		}
+	}
+
+	switch s["type"] {
+	case nil:
+		return nil
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%v: %v is not a string", at, value)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%v: %v is not a boolean", at, value)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%v: %v is not a number", at, value)
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%v: %v is not an integer", at, value)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return fmt.Errorf("%v: %v is not an array", at, value)
+		}
+		for i, item := range items {
+			if err := validate(doc, s["items"], item, fmt.Sprintf("%v[%v]", at, i)); err != nil {
+				return err
+			}
+		}
+	case "object":
+		object, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("%v: %v is not an object", at, value)
+		}
+		properties, _ := s["properties"].(map[string]interface{})
+		if required, ok := s["required"].([]interface{}); ok {
+			for _, name := range required {
+				if _, ok := object[name.(string)]; !ok {
+					return fmt.Errorf("%v: missing %v", at, name)
+				}
+			}
+		}
+		for name, property := range object {
+			if schema, ok := properties[name]; ok {
+				if err := validate(doc, schema, property, at+"."+name); err != nil {
+					return err
+				}
+			} else if s["additionalProperties"] == false {
+				return fmt.Errorf("%v: unexpected %v", at, name)
+			}
+		}
+	default:
+		return fmt.Errorf("%v: unknown type %v", at, s["type"])
+	}
+	return nil
+}
+
+// examples walks the document collecting every node holding both a schema and an example
+func examples(node interface{}, at string, found map[string]map[string]interface{}) {
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if _, ok := n["schema"]; ok {
+			if _, ok := n["example"]; ok {
+				found[at] = n
+			}
+		}
+		for name, child := range n {
+			examples(child, at+"/"+name, found)
+		}
+	case []interface{}:
+		for i, child := range n {
+			examples(child, fmt.Sprintf("%v/%v", at, i), found)
+		}
+	}
+}
+
+// responseSchema retrieves the JSON schema documented for the response
+func responseSchema(t *testing.T, doc map[string]interface{}, path string, method string, status int) interface{} {
+	operation, ok := doc["paths"].(map[string]interface{})[path].(map[string]interface{})[strings.ToLower(method)]
+	if !ok {
+		t.Fatalf("%v %v not documented", method, path)
+	}
+	response, err := resolve(doc, operation.(map[string]interface{})["responses"].(map[string]interface{})[fmt.Sprint(status)])
+	if err != nil || response == nil {
+		t.Fatalf("%v %v response %v not documented", method, path, status)
+	}
+	content := response.(map[string]interface{})["content"].(map[string]interface{})
+	return content["application/json"].(map[string]interface{})["schema"]
+}
+
+func TestOpenAPIPaths(t *testing.T) {
+	doc := openAPI(t)
+	ws := newWebServer(dal.NewDB(&bytes.Buffer{}))
+
+	documented := make([]string, 0)
+	for path, item := range doc["paths"].(map[string]interface{}) {
+		documented = append(documented, path)
+		for method, operation := range item.(map[string]interface{}) {
+			responses := operation.(map[string]interface{})["responses"].(map[string]interface{})
+			if responses["405"] == nil {
+				t.Errorf("%v %v expected to document 405", method, path)
+			}
+			req, _ := http.NewRequest(strings.ToUpper(method), path, nil)
+			if !public(req) && responses["401"] == nil {
+				t.Errorf("%v %v expected to document 401", method, path)
+			}
+			if adminOnly := adminAction(req) != ""; adminOnly != (responses["403"] != nil) {
+				t.Errorf("%v %v expected to document 403 %v", method, path, adminOnly)
+			}
+			if responses["429"] == nil {
+				t.Errorf("%v %v expected to document 429", method, path)
+			}
+			if body := operation.(map[string]interface{})["requestBody"]; (body != nil) != (responses["413"] != nil) {
+				t.Errorf("%v %v expected to document 413 %v", method, path, body != nil)
+			}
+		}
+	}
+	registered := append([]string{}, ws.paths...)
+	sort.Strings(documented)
+	sort.Strings(registered)
+
+	if strings.Join(documented, " ") != strings.Join(registered, " ") {
+		t.Errorf("documented paths expected %v, got %v", registered, documented)
+	}
+}
+
+func TestOpenAPIExamples(t *testing.T) {
+	doc := openAPI(t)
+
+	found := make(map[string]map[string]interface{})
+	examples(doc, "#", found)
+	if len(found) == 0 {
+		t.Fatalf("expected examples, got none")
+	}
+	for at, node := range found {
+		if err := validate(doc, node["schema"], node["example"], at); err != nil {
+			t.Errorf("example invalid: %v", err)
+		}
+	}
+}
+
+func TestOpenAPIValidate(t *testing.T) {
+	doc := openAPI(t)
+	route := map[string]interface{}{"$ref": "#/components/schemas/Route"}
+
+	var tests = []struct {
+		name  string
+		value string
+		valid bool
+	}{
+		{"valid", `{"Origin":"GRU","Destination":"BRC","Cost":10}`, true},
+		{"missing", `{"Origin":"GRU","Cost":10}`, false},
+		{"wrong type", `{"Origin":"GRU","Destination":"BRC","Cost":"10"}`, false},
+		{"unexpected", `{"Origin":"GRU","Destination":"BRC","Cost":10,"Time":"2020-06-02T15:04:05Z"}`, false},
+		{"null", `null`, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var value interface{}
+			json.Unmarshal([]byte(tt.value), &value)
+			if err := validate(doc, route, value, "#"); (err == nil) != tt.valid {
+				t.Errorf("validate expected valid %v, got %v", tt.valid, err)
+			}
+		})
+	}
+}
+
+func TestOpenAPIResponses(t *testing.T) {
+	doc := openAPI(t)
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, *dal.NewRoute("BRC", "SCL", 5))
+	addRoute(t, dal.Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF", FareID: "AF457"})
+
+	get := func(path string) (int, string) { return getBody(t, apiPrefix+path) }
+	post := func(path string, req interface{}) (int, string) { return postJSON(t, apiPrefix+path, req) }
+
+	var tests = []struct {
+		path    string
+		method  string
+		request func() (int, string)
+		status  int
+	}{
+		{"/route", http.MethodGet, func() (int, string) { return get("/route") }, http.StatusOK},
+		{"/route", http.MethodGet, func() (int, string) { return get("/route?AsOf=yesterday") }, http.StatusBadRequest},
+		{"/route", http.MethodPost, func() (int, string) { return post("/route", dal.NewRoute("SCL", "ORL", 20)) }, http.StatusCreated},
+		{"/route/best", http.MethodGet, func() (int, string) { return get("/route/best?Origin=GRU&Destination=SCL") }, http.StatusOK},
+		{"/route/best", http.MethodGet, func() (int, string) { return get("/route/best?Origin=SCL&Destination=GRU") }, http.StatusOK},
+		{"/route/best", http.MethodGet, func() (int, string) { return get("/route/best?Origin=GRU") }, http.StatusBadRequest},
+		{"/route/batch", http.MethodPost, func() (int, string) {
+			return post("/route/batch", batchRequest{[]batchOperation{{"insert", *dal.NewRoute("ORL", "CDG", 5)}}})
+		}, http.StatusOK},
+		{"/route/batch", http.MethodPost, func() (int, string) {
+			return post("/route/batch", batchRequest{[]batchOperation{{"delete", *dal.NewRoute("CDG", "GRU", 0)}}})
+		}, http.StatusUnprocessableEntity},
+		{"/route/matrix", http.MethodGet, func() (int, string) { return get("/route/matrix?airports=GRU,SCL,CDG") }, http.StatusOK},
+		{"/route/from", http.MethodGet, func() (int, string) { return get("/route/from?Origin=GRU&MaxCost=20") }, http.StatusOK},
+		{"/itinerary", http.MethodPost, func() (int, string) {
+			return post("/itinerary", itineraryRequest{[]string{"GRU", "SCL", "CDG"}})
+		}, http.StatusOK},
+		{"/itinerary", http.MethodPost, func() (int, string) {
+			return post("/itinerary", itineraryRequest{[]string{"CDG", "GRU"}})
+		}, http.StatusUnprocessableEntity},
+		{"/itinerary/tour", http.MethodPost, func() (int, string) {
+			return post("/itinerary/tour", tourRequest{[]string{"GRU", "SCL", "CDG"}, false})
+		}, http.StatusOK},
+		{"/airport", http.MethodGet, func() (int, string) { return get("/airport") }, http.StatusOK},
+		{"/airport/{code}/outbound", http.MethodGet, func() (int, string) { return get("/airport/GRU/outbound") }, http.StatusOK},
+		{"/airport/{code}/inbound", http.MethodGet, func() (int, string) { return get("/airport/FCO/inbound") }, http.StatusNotFound},
+		{"/watch", http.MethodGet, func() (int, string) { return get("/watch") }, http.StatusOK},
+		{"/watch/{id}", http.MethodDelete, func() (int, string) { return deleteBody(t, apiPrefix+"/watch/99") }, http.StatusNotFound},
+		{"/admin/compact", http.MethodPost, func() (int, string) { return post("/admin/compact", nil) }, http.StatusConflict},
+		{"/route", http.MethodDelete, func() (int, string) {
+			req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080"+apiPrefix+"/route", nil)
+			resp, err := http.DefaultClient.Do(req)
+			if err != nil {
+				t.Fatalf("http.Do error: %v\n", err.Error())
+			}
+			defer resp.Body.Close()
+			var buf bytes.Buffer
+			buf.ReadFrom(resp.Body)
+			return resp.StatusCode, buf.String()
+		}, http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%v %v %v", tt.method, tt.path, tt.status), func(t *testing.T) {
+			status, body := tt.request()
+			if status != tt.status {
+				t.Fatalf("status expected %v, got %v: %v", tt.status, status, body)
+			}
+
+			// Methods not documented validate against the GET responses
+			method := tt.method
+			if doc["paths"].(map[string]interface{})[tt.path].(map[string]interface{})[strings.ToLower(method)] == nil {
+				method = http.MethodGet
+			}
+			var value interface{}
+			if err := json.Unmarshal([]byte(body), &value); err != nil {
+				t.Fatalf("json.Unmarshal error: %v: %v", err, body)
+			}
+			if err := validate(doc, responseSchema(t, doc, tt.path, method, status), value, "#"); err != nil {
+				t.Errorf("response invalid: %v: %v", err, body)
+			}
+		})
+	}
+
+	status, body := getBody(t, apiPrefix+"/openapi.json")
+	if status != http.StatusOK || body != openAPIDocument {
+		t.Errorf("/openapi.json expected the document, got %v", status)
+	}
+
+	StopWebServer(srv)
+}
@@ -0,0 +1,43 @@
+package controller
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/domain"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+)
+
+// reachableHandler handles requests directed to "/route/from"
+func (ws *webServer) reachableHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		origin := r.FormValue("Origin")
+		if origin == "" {
+			missingParam(w, "Origin")
+			return
+		}
+
+		maxCost := float32(math.Inf(1))
+		if value := r.FormValue("MaxCost"); value != "" {
+			cost, err := strconv.ParseFloat(value, 32)
+			if err != nil {
+				invalidParam(w, "MaxCost", value)
+				return
+			}
+			maxCost = float32(cost)
+		}
+
+		reachable, err := domain.FindReachable(ws.routeDB.GetRoutes(), origin, maxCost)
+		var cycleErr *algorithm.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			writeError(w, http.StatusConflict, codeNegativeCycle, err.Error(),
+				map[string][]string{"cycle": cycleErr.Cycle})
+			return
+		}
+		writeJSON(w, http.StatusOK, reachable)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
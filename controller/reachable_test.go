@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestReachable(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, *dal.NewRoute("BRC", "SCL", 5))
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+
+	var tests = []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{"Budget", "/route/from?Origin=GRU&MaxCost=50", http.StatusOK,
+			`[{"Destination":"BRC","Route":["GRU","BRC"],"Cost":10},{"Destination":"SCL","Route":["GRU","BRC","SCL"],"Cost":15}]`},
+		{"Unlimited", "/route/from?Origin=BRC", http.StatusOK,
+			`[{"Destination":"SCL","Route":["BRC","SCL"],"Cost":5}]`},
+		{"Unknown", "/route/from?Origin=asdf", http.StatusOK, `[]`},
+		{"MissingOrigin", "/route/from?MaxCost=50", http.StatusBadRequest,
+			`{"code":"missing_param","message":"Missing 'Origin' param","details":{"param":"Origin"}}`},
+		{"InvalidMaxCost", "/route/from?Origin=GRU&MaxCost=abc", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'MaxCost' param: abc","details":{"param":"MaxCost","value":"abc"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := getBody(t, tt.path)
+			if status != tt.expectStatus {
+				t.Errorf("%v expected status %v, got %v", tt.path, tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("%v expected %v, got %v", tt.path, tt.expectBody, body)
+			}
+		})
+	}
+
+	addRoute(t, *dal.NewRoute("SCL", "BRC", -10))
+	status, body := getBody(t, "/route/from?Origin=GRU")
+	if status != http.StatusConflict || !strings.HasPrefix(body, `{"code":"negative_cycle","message":"negative cost cycle: `) {
+		t.Errorf("/route/from expected %v negative_cycle, got %v %v", http.StatusConflict, status, body)
+	}
+	// Not reachable from the cycle
+	if status, _ := getBody(t, "/route/from?Origin=CDG"); status != http.StatusOK {
+		t.Errorf("/route/from expected status %v, got %v", http.StatusOK, status)
+	}
+
+	StopWebServer(srv)
+}
@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// apiPrefix is the path prefix of the current API version
+// Paths without it are kept as aliases
+const apiPrefix = "/api/v1"
+
+// Codes of the errors replied
+const (
+	codeBadRequest       = "bad_request"
+	codeMissingParam     = "missing_param"
+	codeInvalidParam     = "invalid_param"
+	codeNotFound         = "not_found"
+	codeMethodNotAllowed = "method_not_allowed"
+	codeUnauthorized     = "unauthorized"
+	codeForbidden        = "forbidden"
+	codeRateLimited      = "rate_limited"
+	codeBodyTooLarge     = "body_too_large"
+	codeNegativeCycle    = "negative_cycle"
+	codeUnreachable      = "unreachable"
+	codeNoTour           = "no_tour"
+	codeRouteNotFound    = "route_not_found"
+	codeAirportNotFound  = "airport_not_found"
+	codeWatchNotFound    = "watch_not_found"
+	codeTooManyWatches   = "too_many_watches"
+	codeNotFileBacked    = "not_file_backed"
+	codeInternal         = "internal_error"
+)
+
+// errorResponse defines the envelope of every error replied
+type errorResponse struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// marshal encodes v as JSON, without escaping HTML characters such as '>'
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte{'\n'}), nil
+}
+
+// writeJSON replies with v encoded as JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	js, err := marshal(v)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// writeError replies with the error envelope
+func writeError(w http.ResponseWriter, status int, code string, message string, details interface{}) {
+	js, err := marshal(errorResponse{code, message, details})
+	if err != nil {
+		http.Error(w, message, status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(js)
+}
+
+// decodeBody decodes the JSON request body into v
+// Returns false in case it could not be decoded, after replying with the error
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	err := json.NewDecoder(r.Body).Decode(v)
+	if bodyTooLarge(err) {
+		writeError(w, http.StatusRequestEntityTooLarge, codeBodyTooLarge, "Request body too large", nil)
+		return false
+	} else if err != nil {
+		writeError(w, http.StatusBadRequest, codeBadRequest, err.Error(), nil)
+		return false
+	}
+	return true
+}
+
+// methodNotAllowed replies the request method is not handled
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusMethodNotAllowed, codeMethodNotAllowed,
+		fmt.Sprintf("%v: Method not allowed", r.Method), nil)
+}
+
+// missingParam replies a required param is missing
+func missingParam(w http.ResponseWriter, name string) {
+	writeError(w, http.StatusBadRequest, codeMissingParam,
+		fmt.Sprintf("Missing '%v' param", name), map[string]string{"param": name})
+}
+
+// invalidParam replies a param could not be decoded
+func invalidParam(w http.ResponseWriter, name string, value string) {
+	writeError(w, http.StatusBadRequest, codeInvalidParam,
+		fmt.Sprintf("Invalid '%v' param: %v", name, value), map[string]string{"param": name, "value": value})
+}
+
+// notFoundHandler replies the path is not handled
+func notFoundHandler(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, codeNotFound, fmt.Sprintf("%v: Not found", r.URL.Path), nil)
+}
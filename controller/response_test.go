@@ -0,0 +1,63 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"net/http"
+	"testing"
+)
+
+func TestAPIVersion(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+
+	var tests = []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{"Versioned", "/api/v1/route/best?Origin=GRU&Destination=BRC", http.StatusOK,
+			`{"Route":["GRU","BRC"],"Legs":[{"Origin":"GRU","Destination":"BRC","Cost":10}],"Cost":10}`},
+		{"Alias", "/route/best?Origin=GRU&Destination=BRC", http.StatusOK,
+			`{"Route":["GRU","BRC"],"Legs":[{"Origin":"GRU","Destination":"BRC","Cost":10}],"Cost":10}`},
+		{"MissingOrigin", "/api/v1/route/best?Destination=BRC", http.StatusBadRequest,
+			`{"code":"missing_param","message":"Missing 'Origin' param","details":{"param":"Origin"}}`},
+		{"NotFound", "/api/v1/airline", http.StatusNotFound,
+			`{"code":"not_found","message":"/api/v1/airline: Not found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := getBody(t, tt.path)
+			if status != tt.expectStatus {
+				t.Errorf("%v expected status %v, got %v", tt.path, tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("%v expected %v, got %v", tt.path, tt.expectBody, body)
+			}
+		})
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, "http://localhost:8080/api/v1/route", nil)
+	if err != nil {
+		t.Fatalf("http.NewRequest error: %v\n", err.Error())
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("http.DefaultClient.Do error: %v\n", err.Error())
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("DELETE /api/v1/route expected JSON status %v, got %v %v",
+			http.StatusMethodNotAllowed, resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+
+	StopWebServer(srv)
+}
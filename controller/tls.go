@@ -0,0 +1,128 @@
+package controller
+
+import (
+	"TravelRoute/poller"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// TLSOptions defines how the servers serve HTTPS
+type TLSOptions struct {
+	// CertFile and KeyFile hold the PEM encoded certificate chain and private key of the server
+	CertFile string
+	KeyFile  string
+	// ClientCAFile, when set, holds the PEM encoded CAs the client certificates are verified against
+	// Clients without a certificate signed by one of them are refused
+	ClientCAFile string
+	// ReloadInterval is how often the files are checked for changes, every second when 0
+	ReloadInterval time.Duration
+}
+
+// CertReloader keeps the TLS configuration of a server up to date with the files
+// Files are polled, and loaded again whenever one of them changes on disk
+// In case they can not be loaded, the previous configuration is kept
+type CertReloader struct {
+	options TLSOptions
+	mutex   sync.RWMutex
+	config  *tls.Config
+	poller  *poller.Poller
+}
+
+// NewCertReloader loads the files and starts polling them
+// Returns an error in case the files can not be loaded
+func NewCertReloader(options TLSOptions) (*CertReloader, error) {
+	if options.ReloadInterval == 0 {
+		options.ReloadInterval = time.Second
+	}
+
+	// Polls before loading, so changes made in between are loaded on the next poll
+	cr := &CertReloader{options: options}
+	cr.poller = poller.Start(cr.files(), options.ReloadInterval, func([]os.FileInfo, []os.FileInfo) {
+		cr.reload()
+	})
+	if err := cr.load(); err != nil {
+		cr.poller.Stop()
+		return nil, err
+	}
+	return cr, nil
+}
+
+// Stop stops polling the files
+func (cr *CertReloader) Stop() {
+	cr.poller.Stop()
+}
+
+// TLSConfig builds the configuration handed to the server, which asks the reloader for the current one on each handshake
+func (cr *CertReloader) TLSConfig() *tls.Config {
+	return &tls.Config{
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			return cr.current(), nil
+		},
+	}
+}
+
+// current returns the configuration last loaded
+func (cr *CertReloader) current() *tls.Config {
+	cr.mutex.RLock()
+	defer cr.mutex.RUnlock()
+	return cr.config
+}
+
+// files lists the files the configuration is loaded from
+func (cr *CertReloader) files() []string {
+	files := []string{cr.options.CertFile, cr.options.KeyFile}
+	if cr.options.ClientCAFile != "" {
+		files = append(files, cr.options.ClientCAFile)
+	}
+	return files
+}
+
+// reload loads the files again, one of them changed on disk
+func (cr *CertReloader) reload() {
+	if err := cr.load(); err != nil {
+		log.Printf("could not reload certificates, keeping the previous ones: %v", err)
+		return
+	}
+	log.Printf("%v reloaded", cr.options.CertFile)
+}
+
+// load loads the files into a new configuration
+func (cr *CertReloader) load() error {
+	cert, err := tls.LoadX509KeyPair(cr.options.CertFile, cr.options.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		NextProtos:   []string{"h2", "http/1.1"},
+	}
+	if cr.options.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(cr.options.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%v: %w", cr.options.ClientCAFile, errNoCertificates)
+		}
+		config.ClientCAs = pool
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	cr.mutex.Lock()
+	defer cr.mutex.Unlock()
+	cr.config = config
+	return nil
+}
+
+// errNoCertificates is returned when the CA bundle holds no certificate
+var errNoCertificates = errors.New("no certificates found")
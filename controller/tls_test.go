@@ -0,0 +1,300 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// testCA issues the certificates used by the tests
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T, name string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: name},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate error: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("x509.ParseCertificate error: %v", err)
+	}
+	return &testCA{cert, key, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue issues a certificate for localhost, either for a server or a client
+// Returns the certificate and key, PEM encoded
+func (ca *testCA) issue(t *testing.T, serial int64, server bool) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}
+	if server {
+		template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+		template.DNSNames = []string{"localhost"}
+		template.IPAddresses = []net.IP{net.ParseIP("127.0.0.1")}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalECPrivateKey error: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// keyPair parses the certificate and key issued
+func keyPair(t *testing.T, certPEM []byte, keyPEM []byte) tls.Certificate {
+	cert, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		t.Fatalf("tls.X509KeyPair error: %v", err)
+	}
+	return cert
+}
+
+// replaceFile replaces the file at path at once, as certificate managers do
+func replaceFile(t *testing.T, path string, content []byte) {
+	if err := ioutil.WriteFile(path+".new", content, 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+	if err := os.Rename(path+".new", path); err != nil {
+		t.Fatalf("os.Rename error: %v", err)
+	}
+}
+
+// tlsGet requests the routes over HTTPS, presenting the client certificates
+func tlsGet(roots *x509.CertPool, certs ...tls.Certificate) (int, error) {
+	client := &http.Client{Transport: &http.Transport{
+		TLSClientConfig:   &tls.Config{RootCAs: roots, Certificates: certs},
+		DisableKeepAlives: true,
+	}}
+	resp, err := client.Get("https://localhost:8080/api/v1/route")
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// serverSerial connects to the server, returning the serial number of its certificate
+func serverSerial(t *testing.T, roots *x509.CertPool) int64 {
+	conn, err := tls.Dial("tcp", "localhost:8080", &tls.Config{RootCAs: roots})
+	if err != nil {
+		t.Fatalf("tls.Dial error: %v", err)
+	}
+	defer conn.Close()
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64()
+}
+
+// waitSerial waits for the server to present the certificate with the serial number
+func waitSerial(t *testing.T, roots *x509.CertPool, serial int64) {
+	for i := 0; i < 200 && serverSerial(t, roots) != serial; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if got := serverSerial(t, roots); got != serial {
+		t.Errorf("server certificate expected serial %v, got %v", serial, got)
+	}
+}
+
+func TestTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "TravelRoute CA")
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	options := &TLSOptions{
+		CertFile:       filepath.Join(dir, "server.crt"),
+		KeyFile:        filepath.Join(dir, "server.key"),
+		ReloadInterval: 10 * time.Millisecond,
+	}
+	certPEM, keyPEM := ca.issue(t, 1, true)
+	replaceFile(t, options.CertFile, certPEM)
+	replaceFile(t, options.KeyFile, keyPEM)
+
+	srv := StartWebServerWithOptions(dal.NewDB(&bytes.Buffer{}), 8080, Options{TLS: options})
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	if status, err := tlsGet(roots); err != nil || status != http.StatusOK {
+		t.Errorf("GET /route expected %v, got %v %v", http.StatusOK, status, err)
+	}
+	if status, err := tlsGet(x509.NewCertPool()); err == nil {
+		t.Errorf("GET /route expected the certificate not to be trusted, got %v", status)
+	}
+	waitSerial(t, roots, 1)
+
+	// The certificate renewed is served on the next connections
+	certPEM, keyPEM = ca.issue(t, 2, true)
+	replaceFile(t, options.KeyFile, keyPEM)
+	replaceFile(t, options.CertFile, certPEM)
+	waitSerial(t, roots, 2)
+
+	// An invalid certificate is not loaded, the previous one is kept
+	replaceFile(t, options.CertFile, []byte("not a certificate"))
+	time.Sleep(50 * time.Millisecond)
+	if serial := serverSerial(t, roots); serial != 2 {
+		t.Errorf("server certificate expected serial 2, got %v", serial)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestMutualTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "TravelRoute CA")
+	partnerCA := newTestCA(t, "Partner CA")
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	options := &TLSOptions{
+		CertFile:       filepath.Join(dir, "server.crt"),
+		KeyFile:        filepath.Join(dir, "server.key"),
+		ClientCAFile:   filepath.Join(dir, "clients.pem"),
+		ReloadInterval: 10 * time.Millisecond,
+	}
+	certPEM, keyPEM := ca.issue(t, 1, true)
+	replaceFile(t, options.CertFile, certPEM)
+	replaceFile(t, options.KeyFile, keyPEM)
+	replaceFile(t, options.ClientCAFile, ca.pem)
+
+	clientPEM, clientKeyPEM := ca.issue(t, 10, false)
+	client := keyPair(t, clientPEM, clientKeyPEM)
+	partnerPEM, partnerKeyPEM := partnerCA.issue(t, 20, false)
+	partner := keyPair(t, partnerPEM, partnerKeyPEM)
+
+	srv := StartWebServerWithOptions(dal.NewDB(&bytes.Buffer{}), 8080, Options{TLS: options})
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	var tests = []struct {
+		name     string
+		certs    []tls.Certificate
+		accepted bool
+	}{
+		{"no certificate", nil, false},
+		{"client", []tls.Certificate{client}, true},
+		{"partner", []tls.Certificate{partner}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, err := tlsGet(roots, tt.certs...)
+			if tt.accepted && (err != nil || status != http.StatusOK) {
+				t.Errorf("GET /route expected %v, got %v %v", http.StatusOK, status, err)
+			}
+			if !tt.accepted && err == nil {
+				t.Errorf("GET /route expected to be refused, got %v", status)
+			}
+		})
+	}
+
+	// Clients of the CAs added to the bundle are accepted once it is reloaded
+	replaceFile(t, options.ClientCAFile, append(append([]byte{}, ca.pem...), partnerCA.pem...))
+	accepted := false
+	for i := 0; i < 200 && !accepted; i++ {
+		time.Sleep(10 * time.Millisecond)
+		status, err := tlsGet(roots, partner)
+		accepted = err == nil && status == http.StatusOK
+	}
+	if !accepted {
+		t.Errorf("GET /route expected the partner to be accepted after the reload")
+	}
+	if status, err := tlsGet(roots, client); err != nil || status != http.StatusOK {
+		t.Errorf("GET /route expected %v, got %v %v", http.StatusOK, status, err)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestCertReloaderErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tls")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ca := newTestCA(t, "TravelRoute CA")
+	certPEM, keyPEM := ca.issue(t, 1, true)
+	_, otherKeyPEM := ca.issue(t, 2, true)
+	files := map[string][]byte{"server.crt": certPEM, "server.key": keyPEM, "other.key": otherKeyPEM, "empty.pem": {}}
+	for name, content := range files {
+		replaceFile(t, filepath.Join(dir, name), content)
+	}
+	path := func(name string) string { return filepath.Join(dir, name) }
+
+	var tests = []struct {
+		name    string
+		options TLSOptions
+		check   func(error) bool
+	}{
+		{"missing certificate", TLSOptions{CertFile: path("missing.crt"), KeyFile: path("server.key")}, os.IsNotExist},
+		{"key mismatch", TLSOptions{CertFile: path("server.crt"), KeyFile: path("other.key")}, func(err error) bool { return err != nil }},
+		{"missing CA", TLSOptions{CertFile: path("server.crt"), KeyFile: path("server.key"), ClientCAFile: path("missing.pem")}, os.IsNotExist},
+		{"empty CA", TLSOptions{CertFile: path("server.crt"), KeyFile: path("server.key"), ClientCAFile: path("empty.pem")},
+			func(err error) bool { return errors.Is(err, errNoCertificates) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cr, err := NewCertReloader(tt.options)
+			if !tt.check(err) {
+				t.Errorf("NewCertReloader unexpected error %v", err)
+			}
+			if cr != nil {
+				cr.Stop()
+			}
+		})
+	}
+}
@@ -0,0 +1,394 @@
+package controller
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/dal"
+	"TravelRoute/domain"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// watchAttempts is the amount of times a notification is sent before giving up
+const watchAttempts = 4
+
+// watchQueueSize is the amount of notifications queued for a watch while its callback is being notified
+// Further changes are folded into the last notification queued
+const watchQueueSize = 16
+
+// DefaultMaxWatches is the amount of watches registered at most when none is set
+const DefaultMaxWatches = 100
+
+// watchRetryDelay is the delay before the first retry, doubled on each retry
+var watchRetryDelay = 500 * time.Millisecond
+
+type watchRequest struct {
+	Origin      string
+	Destination string
+	Callback    string
+}
+
+// watch defines a pair of airports whose cheapest route is watched
+type watch struct {
+	ID          int
+	Origin      string
+	Destination string
+	Callback    string
+	// Route and Cost hold the cheapest route last notified
+	Route []string
+	Cost  float32
+	// queue holds the notifications not sent yet, delivered in order by a single goroutine while sending
+	queue   []watchNotification
+	sending bool
+	// stale is set while the cheapest route can not be found, so every change evaluates the watch again
+	stale bool
+	// removed stops the delivery of the notifications queued
+	removed bool
+}
+
+type watchedRoute struct {
+	Route []string
+	Cost  float32
+}
+
+// watchNotification is posted to the callback when the cheapest route changes
+type watchNotification struct {
+	ID          int
+	Origin      string
+	Destination string
+	Old         watchedRoute
+	New         watchedRoute
+}
+
+// watchRegistry holds the watches registered
+// The routes are only followed once the first watch is registered
+type watchRegistry struct {
+	mutex    sync.Mutex
+	watches  []*watch
+	nextID   int
+	watching bool
+	// max caps the amount of watches registered, no cap when negative
+	max int
+}
+
+// watchHandler handles requests directed to "/watch"
+func (ws *webServer) watchHandler(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		// Copies the watches, so the response is written unlocked
+		ws.watches.mutex.Lock()
+		watches := make([]watch, len(ws.watches.watches))
+		for i, wt := range ws.watches.watches {
+			watches[i] = *wt
+		}
+		ws.watches.mutex.Unlock()
+		writeJSON(w, http.StatusOK, watches)
+	case http.MethodPost:
+		var req watchRequest
+		if !decodeBody(w, r, &req) {
+			return
+		}
+		if req.Origin == "" {
+			missingParam(w, "Origin")
+			return
+		}
+		if req.Destination == "" {
+			missingParam(w, "Destination")
+			return
+		}
+		if callback, err := url.Parse(req.Callback); err != nil || (callback.Scheme != "http" && callback.Scheme != "https") || callback.Host == "" {
+			invalidParam(w, "Callback", req.Callback)
+			return
+		}
+
+		// The current route is taken and the watch registered while locked,
+		// so changes made in between are evaluated against the watch once it is unlocked
+		ws.watchRoutes()
+		ws.watches.mutex.Lock()
+		if ws.watches.max >= 0 && len(ws.watches.watches) >= ws.watches.max {
+			ws.watches.mutex.Unlock()
+			writeError(w, http.StatusConflict, codeTooManyWatches,
+				fmt.Sprintf("At most %v watches may be registered", ws.watches.max), nil)
+			return
+		}
+		route, cost, err := domain.FindCheapestRoute(ws.routeDB.GetRoutes(), req.Origin, req.Destination)
+		if err != nil {
+			ws.watches.mutex.Unlock()
+			var cycleErr *algorithm.NegativeCycleError
+			if errors.As(err, &cycleErr) {
+				writeError(w, http.StatusConflict, codeNegativeCycle, err.Error(),
+					map[string][]string{"cycle": cycleErr.Cycle})
+			} else {
+				writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+			}
+			return
+		}
+		ws.watches.nextID++
+		wt := &watch{ID: ws.watches.nextID, Origin: req.Origin, Destination: req.Destination,
+			Callback: req.Callback, Route: route, Cost: cost}
+		ws.watches.watches = append(ws.watches.watches, wt)
+		created := *wt
+		ws.watches.mutex.Unlock()
+		writeJSON(w, http.StatusCreated, created)
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// watchItemHandler handles requests directed to "/watch/{id}"
+func (ws *webServer) watchItemHandler(w http.ResponseWriter, r *http.Request) {
+	param := strings.TrimPrefix(strings.TrimPrefix(r.URL.Path, apiPrefix), "/watch/")
+	id, err := strconv.Atoi(param)
+	if err != nil {
+		notFoundHandler(w, r)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodDelete:
+		ws.watches.mutex.Lock()
+		for i, wt := range ws.watches.watches {
+			if wt.ID == id {
+				// Notifications still queued are dropped
+				wt.removed = true
+				ws.watches.watches = append(ws.watches.watches[:i], ws.watches.watches[i+1:]...)
+				ws.watches.mutex.Unlock()
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+		ws.watches.mutex.Unlock()
+		writeError(w, http.StatusNotFound, codeWatchNotFound, fmt.Sprintf("No watch %v", id),
+			map[string]int{"id": id})
+	default:
+		methodNotAllowed(w, r)
+	}
+}
+
+// watchRoutes starts following the route changes, once
+// The watches are evaluated again after each change until the server shuts down
+func (ws *webServer) watchRoutes() {
+	ws.watches.mutex.Lock()
+	defer ws.watches.mutex.Unlock()
+	if ws.watches.watching {
+		return
+	}
+	ws.watches.watching = true
+
+	s, _, _ := ws.routeDB.Subscribe(0)
+	go func() {
+		for {
+			select {
+			case e, ok := <-s.Events():
+				if !ok {
+					// Fell behind, every watch is evaluated against the latest routes
+					s, _, _ = ws.routeDB.Subscribe(0)
+					ws.evaluateWatches(nil)
+					continue
+				}
+				// Events already delivered are evaluated at once
+				changed := []dal.Route{e.Route}
+				for pending := len(s.Events()); pending > 0; pending-- {
+					if e, ok := <-s.Events(); ok {
+						changed = append(changed, e.Route)
+					}
+				}
+				ws.evaluateWatches(changed)
+			case <-ws.shutdown:
+				s.Close()
+				return
+			}
+		}
+	}()
+}
+
+// evaluateWatches finds the cheapest route of the watches the changed routes may affect, notifying the ones that changed
+// Every watch is evaluated when changed is nil
+func (ws *webServer) evaluateWatches(changed []dal.Route) {
+	routes := ws.routeDB.GetRoutes()
+	affected := newRouteReach(routes, changed)
+
+	ws.watches.mutex.Lock()
+	defer ws.watches.mutex.Unlock()
+	for _, wt := range ws.watches.watches {
+		if changed != nil && !wt.stale && !affected.affects(wt) {
+			continue
+		}
+
+		route, cost, err := domain.FindCheapestRoute(routes, wt.Origin, wt.Destination)
+		wt.stale = err != nil
+		if err != nil || (cost == wt.Cost && equalRoutes(route, wt.Route)) {
+			continue
+		}
+
+		notification := watchNotification{wt.ID, wt.Origin, wt.Destination,
+			watchedRoute{wt.Route, wt.Cost}, watchedRoute{route, cost}}
+		wt.Route, wt.Cost = route, cost
+		ws.enqueue(wt, notification)
+	}
+}
+
+// routeReach tells which watches the changed routes may affect
+type routeReach struct {
+	changed []dal.Route
+	// reaches and reachedFrom hold the airports each changed airport reaches and is reached from
+	reaches     map[string]map[string]bool
+	reachedFrom map[string]map[string]bool
+}
+
+// newRouteReach follows the routes from and to every airport of the changed routes
+func newRouteReach(routes []dal.Route, changed []dal.Route) *routeReach {
+	outbound := make(map[string][]string)
+	inbound := make(map[string][]string)
+	for _, route := range routes {
+		outbound[route.Origin] = append(outbound[route.Origin], route.Destination)
+		inbound[route.Destination] = append(inbound[route.Destination], route.Origin)
+		if route.Bidirectional {
+			outbound[route.Destination] = append(outbound[route.Destination], route.Origin)
+			inbound[route.Origin] = append(inbound[route.Origin], route.Destination)
+		}
+	}
+
+	rr := &routeReach{changed, make(map[string]map[string]bool), make(map[string]map[string]bool)}
+	for _, route := range changed {
+		for _, airport := range []string{route.Origin, route.Destination} {
+			if rr.reaches[airport] == nil {
+				rr.reaches[airport] = traverse(outbound, airport)
+				rr.reachedFrom[airport] = traverse(inbound, airport)
+			}
+		}
+	}
+	return rr
+}
+
+// affects tells whether a changed route is a leg of the cheapest route of the watch,
+// or whether it may join a cheaper one, linking an airport reached from the origin to one reaching the destination
+// Changed routes are legs either way, as they may have been bidirectional before the change
+func (rr *routeReach) affects(wt *watch) bool {
+	for _, route := range rr.changed {
+		for i := 1; i < len(wt.Route); i++ {
+			if (wt.Route[i-1] == route.Origin && wt.Route[i] == route.Destination) ||
+				(wt.Route[i-1] == route.Destination && wt.Route[i] == route.Origin) {
+				return true
+			}
+		}
+
+		if route.Deleted {
+			continue
+		}
+		if rr.reachedFrom[route.Origin][wt.Origin] && rr.reaches[route.Destination][wt.Destination] {
+			return true
+		}
+		if route.Bidirectional && rr.reachedFrom[route.Destination][wt.Origin] && rr.reaches[route.Origin][wt.Destination] {
+			return true
+		}
+	}
+	return false
+}
+
+// traverse finds every airport reached from the airport through the links, the airport itself included
+func traverse(links map[string][]string, airport string) map[string]bool {
+	reached := map[string]bool{airport: true}
+	toVisit := []string{airport}
+	for len(toVisit) > 0 {
+		current := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		for _, next := range links[current] {
+			if !reached[next] {
+				reached[next] = true
+				toVisit = append(toVisit, next)
+			}
+		}
+	}
+	return reached
+}
+
+// enqueue queues the notification of the watch, starting its delivery unless it is already being delivered
+// A full queue folds the notification into the last one queued, so the callback still gets the latest route
+// The watches must be locked
+func (ws *webServer) enqueue(wt *watch, notification watchNotification) {
+	if len(wt.queue) == watchQueueSize {
+		wt.queue[len(wt.queue)-1].New = notification.New
+	} else {
+		wt.queue = append(wt.queue, notification)
+	}
+
+	if !wt.sending {
+		wt.sending = true
+		go ws.deliver(wt)
+	}
+}
+
+// deliver sends the notifications queued for the watch one at a time, in order
+// It returns once the queue is empty, the watch is removed or the server shuts down
+func (ws *webServer) deliver(wt *watch) {
+	for {
+		ws.watches.mutex.Lock()
+		if len(wt.queue) == 0 || wt.removed {
+			wt.queue, wt.sending = nil, false
+			ws.watches.mutex.Unlock()
+			return
+		}
+		notification := wt.queue[0]
+		wt.queue = wt.queue[1:]
+		ws.watches.mutex.Unlock()
+
+		if !notify(wt.Callback, notification, ws.shutdown) {
+			return
+		}
+	}
+}
+
+// notify posts the notification to the callback, retrying in case it fails
+// Returns false in case the server shuts down while waiting to retry
+func notify(callback string, notification watchNotification, shutdown <-chan struct{}) bool {
+	js, err := json.Marshal(notification)
+	if err != nil {
+		log.Printf("could not notify %v: %v", callback, err)
+		return true
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	delay := watchRetryDelay
+	for attempt := 1; ; attempt++ {
+		resp, err := client.Post(callback, "application/json", bytes.NewBuffer(js))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return true
+			}
+			err = fmt.Errorf("status %v", resp.Status)
+		}
+
+		if attempt == watchAttempts {
+			log.Printf("could not notify %v: %v", callback, err)
+			return true
+		}
+		select {
+		case <-time.After(delay):
+		case <-shutdown:
+			return false
+		}
+		delay *= 2
+	}
+}
+
+func equalRoutes(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,251 @@
+package controller
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatch(t *testing.T) {
+	watchRetryDelay = time.Millisecond
+	defer func() { watchRetryDelay = 500 * time.Millisecond }()
+
+	// The callback fails the first attempt of each notification
+	notifications := make(chan string, 10)
+	attempts := 0
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		if attempts%2 == 1 {
+			http.Error(w, "try again", http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		notifications <- string(body)
+	}))
+	defer callback.Close()
+
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+
+	status, body := postJSON(t, "/watch", watchRequest{"GRU", "CDG", callback.URL})
+	if status != http.StatusCreated {
+		t.Errorf("/watch expected status %v, got %v", http.StatusCreated, status)
+	}
+	expect := `{"ID":1,"Origin":"GRU","Destination":"CDG","Callback":"` + callback.URL + `","Route":["GRU","CDG"],"Cost":75}`
+	if body != expect {
+		t.Errorf("/watch expected %v, got %v", expect, body)
+	}
+
+	// Routes not changing the cheapest route are not notified
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, *dal.NewRoute("BRC", "CDG", 20))
+
+	select {
+	case notification := <-notifications:
+		var n watchNotification
+		if err := json.Unmarshal([]byte(notification), &n); err != nil {
+			t.Fatalf("json.Unmarshal error: %v", err)
+		}
+		if n.ID != 1 || n.Old.Cost != 75 || !equalRoutes(n.Old.Route, []string{"GRU", "CDG"}) ||
+			n.New.Cost != 30 || !equalRoutes(n.New.Route, []string{"GRU", "BRC", "CDG"}) {
+			t.Errorf("notification unexpected: %v", notification)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("notification expected, got none")
+	}
+
+	select {
+	case notification := <-notifications:
+		t.Errorf("notification unexpected: %v", notification)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	var tests = []struct {
+		name       string
+		req        watchRequest
+		expectBody string
+	}{
+		{"MissingOrigin", watchRequest{"", "CDG", callback.URL}, `{"code":"missing_param","message":"Missing 'Origin' param","details":{"param":"Origin"}}`},
+		{"MissingDestination", watchRequest{"GRU", "", callback.URL}, `{"code":"missing_param","message":"Missing 'Destination' param","details":{"param":"Destination"}}`},
+		{"InvalidCallback", watchRequest{"GRU", "CDG", "ftp://example.com"}, `{"code":"invalid_param","message":"Invalid 'Callback' param: ftp://example.com",` +
+			`"details":{"param":"Callback","value":"ftp://example.com"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postJSON(t, "/watch", tt.req)
+			if status != http.StatusBadRequest {
+				t.Errorf("/watch expected status %v, got %v", http.StatusBadRequest, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("/watch expected %v, got %v", tt.expectBody, body)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+}
+
+func deleteBody(t *testing.T, path string) (int, string) {
+	req, _ := http.NewRequest(http.MethodDelete, "http://localhost:8080"+path, nil)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("http.Do error: %v\n", err.Error())
+	}
+
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("ioutil.ReadAll error: %v\n", err.Error())
+	}
+
+	return resp.StatusCode, string(body)
+}
+
+func TestWatchOrder(t *testing.T) {
+	// The callback holds the first notification until every change is evaluated
+	notifications := make(chan watchNotification, 10)
+	release := make(chan struct{})
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var n watchNotification
+		json.NewDecoder(r.Body).Decode(&n)
+		if n.Old.Cost == 75 {
+			<-release
+		}
+		notifications <- n
+	}))
+	defer callback.Close()
+
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+	if status, body := postJSON(t, "/watch", watchRequest{"GRU", "CDG", callback.URL}); status != http.StatusCreated {
+		t.Fatalf("/watch expected status %v, got %v %v", http.StatusCreated, status, body)
+	}
+
+	costs := []float32{75, 70, 65, 60}
+	for _, cost := range costs[1:] {
+		addRoute(t, *dal.NewRoute("GRU", "CDG", cost))
+	}
+	for deadline := time.Now().Add(2 * time.Second); ; time.Sleep(10 * time.Millisecond) {
+		if _, body := getBody(t, "/watch"); strings.Contains(body, `"Cost":60`) {
+			break
+		} else if time.Now().After(deadline) {
+			t.Fatalf("/watch expected cost 60, got %v", body)
+		}
+	}
+	close(release)
+
+	for i := 1; i < len(costs); i++ {
+		select {
+		case n := <-notifications:
+			if n.Old.Cost != costs[i-1] || n.New.Cost != costs[i] {
+				t.Errorf("notification expected %v to %v, got %v to %v", costs[i-1], costs[i], n.Old.Cost, n.New.Cost)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("notification expected, got none")
+		}
+	}
+
+	StopWebServer(srv)
+}
+
+func TestWatchLimit(t *testing.T) {
+	callback := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer callback.Close()
+
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServerWithOptions(routeDB, 8080, Options{MaxWatches: 1})
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	req := watchRequest{"GRU", "CDG", callback.URL}
+	if status, body := postJSON(t, "/watch", req); status != http.StatusCreated {
+		t.Fatalf("/watch expected status %v, got %v %v", http.StatusCreated, status, body)
+	}
+	status, body := postJSON(t, "/watch", req)
+	if expected := `{"code":"too_many_watches","message":"At most 1 watches may be registered"}`; status != http.StatusConflict || body != expected {
+		t.Errorf("/watch expected %v %v, got %v %v", http.StatusConflict, expected, status, body)
+	}
+
+	var tests = []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{"Delete", "/watch/1", http.StatusNoContent, ``},
+		{"Deleted", "/api/v1/watch/1", http.StatusNotFound, `{"code":"watch_not_found","message":"No watch 1","details":{"id":1}}`},
+		{"InvalidID", "/watch/abc", http.StatusNotFound, `{"code":"not_found","message":"/watch/abc: Not found"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := deleteBody(t, tt.path)
+			if status != tt.expectStatus {
+				t.Errorf("%v expected status %v, got %v", tt.path, tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("%v expected %v, got %v", tt.path, tt.expectBody, body)
+			}
+		})
+	}
+
+	// The watch removed makes room for another
+	status, body = postJSON(t, "/watch", req)
+	if status != http.StatusCreated || !strings.HasPrefix(body, `{"ID":2,`) {
+		t.Errorf("/watch expected status %v and ID 2, got %v %v", http.StatusCreated, status, body)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestRouteReachAffects(t *testing.T) {
+	routes := []dal.Route{
+		*dal.NewRoute("GRU", "BRC", 10),
+		*dal.NewRoute("BRC", "CDG", 20),
+		*dal.NewRoute("SCL", "ORL", 5),
+		*dal.NewRoute("GRU", "SCL", 15),
+		*dal.NewRoute("GRU", "CDG", 40),
+	}
+	wt := &watch{Origin: "GRU", Destination: "CDG", Route: []string{"GRU", "BRC", "CDG"}, Cost: 30}
+
+	var tests = []struct {
+		name     string
+		changed  dal.Route
+		expected bool
+	}{
+		{"Elsewhere", *dal.NewRoute("SCL", "ORL", 5), false},
+		{"NotReachingDestination", *dal.NewRoute("GRU", "SCL", 15), false},
+		{"Leg", *dal.NewRoute("BRC", "CDG", 20), true},
+		{"ReversedLeg", dal.Route{Origin: "CDG", Destination: "BRC", Bidirectional: true, Deleted: true}, true},
+		{"Alternative", *dal.NewRoute("GRU", "CDG", 40), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if affects := newRouteReach(routes, []dal.Route{tt.changed}).affects(wt); affects != tt.expected {
+				t.Errorf("routeReach.affects expected %v, got %v", tt.expected, affects)
+			}
+		})
+	}
+}
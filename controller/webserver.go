@@ -1,27 +1,101 @@
 package controller
 
 import (
+	"TravelRoute/algorithm"
 	"TravelRoute/dal"
 	"TravelRoute/domain"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // TravelServer defines the HTTP server for the TravelRoute application
 type TravelServer struct {
 	srv *http.Server
 	wg  *sync.WaitGroup
+	// reloader keeps the certificates up to date, when serving HTTPS
+	reloader *CertReloader
 }
 
 // StartWebServer starts the webserver at the provided port
 // Receives a pointer to the DataBase to fetch and persist Route information
 // Returns a pointer to the WebServer that can be Stopped latter
 func StartWebServer(routeDB *dal.DB, port int) *TravelServer {
-	srv := &http.Server{Addr: fmt.Sprintf(":%v", port), Handler: newWebServer(routeDB)}
+	return StartWebServerWithOptions(routeDB, port, Options{})
+}
+
+// Options defines the optional behaviour of the webserver
+type Options struct {
+	// Keys, when set, are required by every request, see LoadKeys
+	// Every request is served when not set
+	Keys *KeyStore
+	// TLS, when set, serves HTTPS instead of plain HTTP
+	TLS *TLSOptions
+	// Timeouts of the http.Server, DefaultTimeouts when not set
+	Timeouts *Timeouts
+	// MaxBodyBytes caps the size of the request bodies, DefaultMaxBodyBytes when 0 and no cap when negative
+	MaxBodyBytes int64
+	// RateLimit, when set, limits the requests of each client
+	RateLimit *RateLimit
+	// MaxWatches caps the amount of watches registered, DefaultMaxWatches when 0 and no cap when negative
+	MaxWatches int
+}
+
+// StartWebServerWithOptions starts the webserver at the provided port, as StartWebServer, configured by the options
+func StartWebServerWithOptions(routeDB *dal.DB, port int, options Options) *TravelServer {
+	ws := newWebServer(routeDB)
+	ws.keys = options.Keys
+
+	timeouts := DefaultTimeouts
+	if options.Timeouts != nil {
+		timeouts = *options.Timeouts
+	}
+	ws.writeTimeout = timeouts.WriteTimeout
+	ws.maxBodyBytes = options.MaxBodyBytes
+	if ws.maxBodyBytes == 0 {
+		ws.maxBodyBytes = DefaultMaxBodyBytes
+	}
+	if options.MaxWatches != 0 {
+		ws.watches.max = options.MaxWatches
+	}
+	if options.RateLimit != nil {
+		if options.RateLimit.Rate <= 0 {
+			log.Fatal("RateLimit: the rate must be positive")
+		}
+		ws.limiter = NewRateLimiter(*options.RateLimit)
+	}
+
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%v", port),
+		Handler:      ws,
+		ReadTimeout:  timeouts.ReadTimeout,
+		WriteTimeout: timeouts.WriteTimeout,
+		IdleTimeout:  timeouts.IdleTimeout,
+	}
+	// Streams never go idle, so they are ended for Shutdown to complete
+	srv.RegisterOnShutdown(func() { close(ws.shutdown) })
+
+	var reloader *CertReloader
+	if options.TLS != nil {
+		var err error
+		if reloader, err = NewCertReloader(*options.TLS); err != nil {
+			log.Fatal("TLS: " + err.Error())
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+	}
+
+	// Listens before returning so the server is ready to accept connections
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		log.Fatal("Listen: " + err.Error())
+	}
 
 	// Used to syncronize Stop call
 	wg := &sync.WaitGroup{}
@@ -31,12 +105,17 @@ func StartWebServer(routeDB *dal.DB, port int) *TravelServer {
 		defer wg.Done()
 
 		fmt.Printf("Listening on port %v...\n", port)
-		if err := srv.ListenAndServe(); err != http.ErrServerClosed {
-			log.Fatal("ListenAndServe: " + err.Error())
+		serve := srv.Serve
+		if reloader != nil {
+			// The certificates are taken from the TLS config, instead of files
+			serve = func(l net.Listener) error { return srv.ServeTLS(l, "", "") }
+		}
+		if err := serve(listener); err != http.ErrServerClosed {
+			log.Fatal("Serve: " + err.Error())
 		}
 	}()
 
-	return &TravelServer{srv, wg}
+	return &TravelServer{srv, wg, reloader}
 }
 
 // StopWebServer stops the webserver
@@ -47,16 +126,41 @@ func StopWebServer(ts *TravelServer) {
 	}
 	// wait for goroutine started in Start() to finish
 	ts.wg.Wait()
+	if ts.reloader != nil {
+		ts.reloader.Stop()
+	}
 }
 
 // webServer defines a route's webserver
 type webServer struct {
 	mux     *http.ServeMux
 	routeDB *dal.DB
+	// shutdown is closed when the server is shutting down
+	shutdown chan struct{}
+	watches  *watchRegistry
+	// paths holds the paths registered, without the API prefix
+	paths []string
+	// keys authorizes the requests, when set
+	keys *KeyStore
+	// limiter limits the requests of each client, when set
+	limiter *RateLimiter
+	// maxBodyBytes caps the size of the request bodies, no cap when negative
+	maxBodyBytes int64
+	// writeTimeout is the time the server has to write a response, no limit when 0
+	writeTimeout time.Duration
 }
 
-// ServeHTTP uses the default ServerHTTP from http
+// ServeHTTP uses the default ServerHTTP from http, once the request is within the limits and authorized
 func (ws *webServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if ws.limiter != nil && !ws.limiter.throttle(w, r) {
+		return
+	}
+	if ws.maxBodyBytes > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(w, r.Body, ws.maxBodyBytes)
+	}
+	if ws.keys != nil && !ws.keys.authorize(w, r) {
+		return
+	}
 	ws.mux.ServeHTTP(w, r)
 }
 
@@ -64,29 +168,54 @@ func (ws *webServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 func (ws *webServer) routeHandler(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		routes := ws.routeDB.GetRoutes()
-		js, err := json.Marshal(routes)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		q, ok := routeQuery(w, r)
+		if !ok {
+			return
+		}
+
+		page, err := ws.routeDB.QueryRoutes(q)
+		if errors.Is(err, dal.ErrInvalidSort) {
+			invalidParam(w, "sort", q.Sort)
+			return
+		} else if errors.Is(err, dal.ErrInvalidCursor) {
+			invalidParam(w, "cursor", q.Cursor)
+			return
+		} else if err == dal.ErrCursorExpired {
+			writeError(w, http.StatusBadRequest, codeInvalidParam, fmt.Sprintf("Invalid 'cursor' param: %v", err),
+				map[string]string{"param": "cursor", "value": q.Cursor})
+			return
+		} else if errors.Is(err, dal.ErrHistoryCompacted) {
+			historyCompacted(w, r, err)
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
+			return
+		}
+
+		if page.Next != "" {
+			next := *r.URL
+			params := next.Query()
+			params.Set("cursor", page.Next)
+			next.RawQuery = params.Encode()
+			w.Header().Set("Link", fmt.Sprintf("<%v>; rel=\"next\"", next.RequestURI()))
+		}
+		if q.AsOf != nil {
+			writeJSON(w, http.StatusOK, routeVersions(page.Routes))
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(js)
+		writeJSON(w, http.StatusOK, page.Routes)
 	case http.MethodPost, http.MethodPut:
 		var route dal.Route
-		err := json.NewDecoder(r.Body).Decode(&route)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		if !decodeBody(w, r, &route) || !validRoute(w, &route) {
 			return
 		}
 
 		ws.routeDB.InsertRoute(route)
 		fmt.Printf("Route added: %v\n", route)
 
-		w.Header().Set("Content-Type", "text/plain")
-		w.Write([]byte("OK"))
+		writeJSON(w, http.StatusCreated, route)
 	default:
-		http.Error(w, fmt.Sprintf("%v: Method not allowed", r.Method), http.StatusMethodNotAllowed)
+		methodNotAllowed(w, r)
 	}
 }
 
@@ -96,40 +225,208 @@ func (ws *webServer) bestRouteHandler(w http.ResponseWriter, r *http.Request) {
 	case http.MethodGet:
 		origin := r.FormValue("Origin")
 		if origin == "" {
-			http.Error(w, "Missing 'origin' param", http.StatusBadRequest)
+			missingParam(w, "Origin")
 			return
 		}
 
 		destination := r.FormValue("Destination")
 		if destination == "" {
-			http.Error(w, "Missing 'Destination' param", http.StatusBadRequest)
+			missingParam(w, "Destination")
 			return
 		}
 
-		expectedBestRoute, expectedCost := domain.FindCheapestRoute(ws.routeDB.GetRoutes(), origin, destination)
-		resp := bestRouteResponse{Route: expectedBestRoute, Cost: expectedCost}
-		js, err := json.Marshal(resp)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		routes, ok := ws.routesAsOf(w, r)
+		if !ok {
+			return
+		}
+
+		options := domain.SearchOptions{
+			IncludeCarriers: listParam(r, "IncludeCarriers"),
+			ExcludeCarriers: listParam(r, "ExcludeCarriers"),
+			Avoid:           listParam(r, "Avoid"),
+			Via:             listParam(r, "Via"),
+		}
+		best, err := domain.FindBestRoute(routes, origin, destination, options)
+		var cycleErr *algorithm.NegativeCycleError
+		if errors.As(err, &cycleErr) {
+			writeError(w, http.StatusConflict, codeNegativeCycle, err.Error(),
+				map[string][]string{"cycle": cycleErr.Cycle})
+			return
+		} else if err != nil {
+			writeError(w, http.StatusInternalServerError, codeInternal, err.Error(), nil)
 			return
 		}
-		w.Header().Set("Content-Type", "application/json")
-		w.Write(js)
+
+		resp := bestRouteResponse{Route: best.Route, Legs: best.Legs, Cost: best.Cost}
+		writeJSON(w, http.StatusOK, resp)
 	default:
-		http.Error(w, fmt.Sprintf("%v: Method not allowed", r.Method), http.StatusMethodNotAllowed)
+		methodNotAllowed(w, r)
+	}
+}
+
+// validRoute checks the route can be stored
+// Returns false in case it can not, after replying with the error
+func validRoute(w http.ResponseWriter, route *dal.Route) bool {
+	err := route.Validate()
+	var invalid *dal.InvalidRouteError
+	if errors.As(err, &invalid) {
+		writeError(w, http.StatusBadRequest, codeInvalidParam, err.Error(),
+			map[string]string{"param": invalid.Field, "value": invalid.Value})
+		return false
 	}
+	return true
+}
+
+// routesAsOf retrieves the routes as they were at the instant in the 'AsOf' param, RFC 3339 formatted
+// All routes are retrieved when the param is missing
+// Returns false in case the param is invalid, after replying with the error
+func (ws *webServer) routesAsOf(w http.ResponseWriter, r *http.Request) ([]dal.Route, bool) {
+	param := r.FormValue("AsOf")
+	if param == "" {
+		return ws.routeDB.GetRoutes(), true
+	}
+
+	asOf, err := time.Parse(time.RFC3339Nano, param)
+	if err != nil {
+		invalidParam(w, "AsOf", param)
+		return nil, false
+	}
+	routes, err := ws.routeDB.GetRoutesAsOf(asOf)
+	if err != nil {
+		historyCompacted(w, r, err)
+		return nil, false
+	}
+	return routes, true
+}
+
+// historyCompacted replies the routes can not be retrieved as of the instant in the 'AsOf' param,
+// its versions were dropped by a compaction
+func historyCompacted(w http.ResponseWriter, r *http.Request, err error) {
+	param := r.FormValue("AsOf")
+	writeError(w, http.StatusBadRequest, codeInvalidParam, fmt.Sprintf("Invalid 'AsOf' param: %v", err),
+		map[string]string{"param": "AsOf", "value": param})
+}
+
+// routeVersion defines a version of a route along with when it was recorded
+type routeVersion struct {
+	dal.Route
+	// Time is left out for routes written by hand, which have no timestamp
+	Time *time.Time `json:",omitempty"`
+}
+
+// routeVersions adds to the routes when they were recorded
+func routeVersions(routes []dal.Route) []routeVersion {
+	versions := make([]routeVersion, len(routes))
+	for i := range routes {
+		versions[i].Route = routes[i]
+		if !routes[i].Time.IsZero() {
+			versions[i].Time = &routes[i].Time
+		}
+	}
+	return versions
+}
+
+// routeQuery reads the params filtering and paging the routes listed
+// Returns false in case a param is invalid, after replying with the error
+func routeQuery(w http.ResponseWriter, r *http.Request) (dal.RouteQuery, bool) {
+	q := dal.RouteQuery{
+		Origin:      r.FormValue("Origin"),
+		Destination: r.FormValue("Destination"),
+		Sort:        r.FormValue("sort"),
+		Cursor:      r.FormValue("cursor"),
+	}
+
+	for _, bound := range []struct {
+		name string
+		cost **float32
+	}{{"MinCost", &q.MinCost}, {"MaxCost", &q.MaxCost}} {
+		param := r.FormValue(bound.name)
+		if param == "" {
+			continue
+		}
+		cost, err := strconv.ParseFloat(param, 32)
+		if err != nil {
+			invalidParam(w, bound.name, param)
+			return q, false
+		}
+		c := float32(cost)
+		*bound.cost = &c
+	}
+
+	if param := r.FormValue("limit"); param != "" {
+		limit, err := strconv.Atoi(param)
+		if err != nil || limit < 1 {
+			invalidParam(w, "limit", param)
+			return q, false
+		}
+		q.Limit = limit
+	}
+
+	if param := r.FormValue("AsOf"); param != "" {
+		asOf, err := time.Parse(time.RFC3339Nano, param)
+		if err != nil {
+			invalidParam(w, "AsOf", param)
+			return q, false
+		}
+		q.AsOf = &asOf
+	}
+	return q, true
+}
+
+// listParam reads a list param, either comma separated or repeated
+func listParam(r *http.Request, name string) []string {
+	r.ParseForm()
+	values := make([]string, 0)
+	for _, param := range r.Form[name] {
+		for _, value := range strings.Split(param, ",") {
+			if value = strings.TrimSpace(value); value != "" {
+				values = append(values, value)
+			}
+		}
+	}
+	return values
 }
 
 type bestRouteResponse struct {
 	Route []string
+	Legs  []dal.Route
 	Cost  float32
 }
 
 // newWebServer constructs a new Webserver
 func newWebServer(routeDB *dal.DB) *webServer {
 	mux := http.NewServeMux()
-	ws := &webServer{mux, routeDB}
-	mux.HandleFunc("/route", ws.routeHandler)
-	mux.HandleFunc("/route/best", ws.bestRouteHandler)
+	ws := &webServer{mux: mux, routeDB: routeDB, shutdown: make(chan struct{}), watches: &watchRegistry{max: DefaultMaxWatches}}
+	ws.handle("/route", ws.routeHandler)
+	ws.handle("/route/best", ws.bestRouteHandler)
+	ws.handle("/route/batch", ws.batchHandler)
+	ws.handle("/route/events", ws.eventsHandler)
+	ws.handle("/route/matrix", ws.matrixHandler)
+	ws.handle("/route/from", ws.reachableHandler)
+	ws.handle("/itinerary", ws.itineraryHandler)
+	ws.handle("/itinerary/tour", ws.tourHandler)
+	ws.handle("/airport", ws.airportsHandler)
+	ws.handleTree("/airport/", ws.airportHandler, "/airport/{code}/outbound", "/airport/{code}/inbound")
+	ws.handle("/watch", ws.watchHandler)
+	ws.handleTree("/watch/", ws.watchItemHandler, "/watch/{id}")
+	ws.handle("/graphql", ws.graphQLHandler)
+	ws.handle("/admin/compact", ws.compactHandler)
+	ws.handle("/openapi.json", ws.openAPIHandler)
+	mux.HandleFunc("/", notFoundHandler)
 	return ws
 }
+
+// handle registers the handler for the path under the API prefix, keeping the path itself as an alias
+func (ws *webServer) handle(path string, handler http.HandlerFunc) {
+	ws.mux.HandleFunc(apiPrefix+path, handler)
+	ws.mux.HandleFunc(path, handler)
+	ws.paths = append(ws.paths, path)
+}
+
+// handleTree registers the handler for every path under the prefix, under the API prefix as well
+// The paths actually served, as documented, are given in paths
+func (ws *webServer) handleTree(prefix string, handler http.HandlerFunc, paths ...string) {
+	ws.mux.HandleFunc(apiPrefix+prefix, handler)
+	ws.mux.HandleFunc(prefix, handler)
+	ws.paths = append(ws.paths, paths...)
+}
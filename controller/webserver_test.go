@@ -9,9 +9,19 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
+func init() {
+	// Every test starts a new server on the same port, so connections must not be reused
+	http.DefaultTransport.(*http.Transport).DisableKeepAlives = true
+}
+
 func TestStartStopServer(t *testing.T) {
 	routeDB := dal.NewDB(&bytes.Buffer{})
 	srv := StartWebServer(routeDB, 8080)
@@ -76,13 +86,89 @@ func TestGetEmptyRoutes(t *testing.T) {
 	StopWebServer(srv)
 }
 
+func TestQueryRoutes(t *testing.T) {
+	routeDB := dal.NewDB(bytes.NewBufferString("GRU,BRC,10\nBRC,SCL,5\nGRU,CDG,75\nGRU,SCL,20\nSCL,CDG,20\n"))
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	var tests = []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{"Origin", "/route?Origin=GRU", http.StatusOK,
+			`[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"GRU","Destination":"CDG","Cost":75},{"Origin":"GRU","Destination":"SCL","Cost":20}]`},
+		{"Destination", "/route?Destination=CDG&sort=cost", http.StatusOK,
+			`[{"Origin":"SCL","Destination":"CDG","Cost":20},{"Origin":"GRU","Destination":"CDG","Cost":75}]`},
+		{"Cost", "/route?MinCost=10&MaxCost=20&sort=-origin", http.StatusOK,
+			`[{"Origin":"SCL","Destination":"CDG","Cost":20},{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"GRU","Destination":"SCL","Cost":20}]`},
+		{"None", "/route?Origin=FCO", http.StatusOK, `[]`},
+		{"InvalidCost", "/route?MinCost=cheap", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'MinCost' param: cheap","details":{"param":"MinCost","value":"cheap"}}`},
+		{"InvalidSort", "/route?sort=carrier", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'sort' param: carrier","details":{"param":"sort","value":"carrier"}}`},
+		{"InvalidLimit", "/route?limit=0", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'limit' param: 0","details":{"param":"limit","value":"0"}}`},
+		{"InvalidCursor", "/route?cursor=GRU", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'cursor' param: GRU","details":{"param":"cursor","value":"GRU"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := getBody(t, tt.path)
+			if status != tt.expectStatus {
+				t.Errorf("%v expected status %v, got %v", tt.path, tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("%v expected %v, got %v", tt.path, tt.expectBody, body)
+			}
+		})
+	}
+
+	// Pages are followed through the Link header until the last one
+	pages := make([]string, 0)
+	next := "/api/v1/route?sort=cost&limit=2"
+	for next != "" && len(pages) < 5 {
+		resp, err := http.Get("http://localhost:8080" + next)
+		if err != nil {
+			t.Fatalf("http.Get error: %v\n", err.Error())
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("ioutil.ReadAll error: %v\n", err.Error())
+		}
+		pages = append(pages, string(body))
+
+		next = ""
+		if link := resp.Header.Get("Link"); link != "" {
+			fmt.Sscanf(link, "<%s", &next)
+			next = strings.TrimSuffix(next, ">;")
+		}
+	}
+	expect := []string{
+		`[{"Origin":"BRC","Destination":"SCL","Cost":5},{"Origin":"GRU","Destination":"BRC","Cost":10}]`,
+		`[{"Origin":"GRU","Destination":"SCL","Cost":20},{"Origin":"SCL","Destination":"CDG","Cost":20}]`,
+		`[{"Origin":"GRU","Destination":"CDG","Cost":75}]`,
+	}
+	if fmt.Sprint(pages) != fmt.Sprint(expect) {
+		t.Errorf("Pages expected %v, got %v", expect, pages)
+	}
+
+	StopWebServer(srv)
+}
+
 func addRoute(t *testing.T, r dal.Route) {
 	js, err := json.Marshal(r)
 	if err != nil {
 		t.Fatalf("json.Marshal error: %v\n", err.Error())
 	}
 
-	resp, err := http.Post("http://localhost:8080/route", "application/json", bytes.NewBuffer(js))
+	resp, err := http.Post("http://localhost:8080/api/v1/route", "application/json", bytes.NewBuffer(js))
 	if err != nil {
 		t.Fatalf("http.Post error: %v\n", err.Error())
 	}
@@ -93,9 +179,12 @@ func addRoute(t *testing.T, r dal.Route) {
 		t.Fatalf("ioutil.ReadAll error: %v\n", err.Error())
 	}
 
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("/route status expect %v, got %v\n", http.StatusCreated, resp.StatusCode)
+	}
 	ret := string(body)
-	if ret != "OK" {
-		t.Fatalf("/route response expect %v, got %v\n", "OK", ret)
+	if ret != string(js) {
+		t.Fatalf("/route response expect %v, got %v\n", string(js), ret)
 	}
 }
 
@@ -136,6 +225,64 @@ func getBestRoute(t *testing.T, origin string, destination string) string {
 	return string(body)
 }
 
+func TestAddInvalidRoutes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "routes")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "routes.csv")
+
+	routeDB, err := dal.OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("dal.OpenFileDB error: %v", err)
+	}
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	var tests = []struct {
+		name         string
+		path         string
+		req          interface{}
+		expectStatus int
+		expectBody   string
+	}{
+		{"MissingOrigin", "/route", dal.Route{Destination: "BRC", Cost: 10}, http.StatusBadRequest,
+			`{"code":"invalid_param","message":"invalid route: Origin is required","details":{"param":"Origin","value":""}}`},
+		{"InjectedRecord", "/route", dal.Route{Origin: "GRU", Destination: "BRC", Cost: 10, Carrier: "LA\nSCL,ORL,1"}, http.StatusBadRequest,
+			`{"code":"invalid_param","message":"invalid route: Carrier \"LA\\nSCL,ORL,1\" can not hold ',', '=' or line breaks","details":{"param":"Carrier","value":"LA\nSCL,ORL,1"}}`},
+		{"BatchComma", "/route/batch", batchRequest{[]batchOperation{{"insert", *dal.NewRoute("GRU", "BRC", 10)}, {"insert", *dal.NewRoute("BRC,SCL", "ORL", 5)}}},
+			http.StatusBadRequest,
+			`{"code":"invalid_param","message":"invalid route: Origin \"BRC,SCL\" can not hold ',', '=' or line breaks","details":{"param":"Origin","value":"BRC,SCL"}}`},
+		{"Valid", "/route", dal.Route{Origin: "GRU", Destination: "BRC", Cost: 10, Carrier: "LA", FareID: "LA-1"}, http.StatusCreated,
+			`{"Origin":"GRU","Destination":"BRC","Cost":10,"Carrier":"LA","FareID":"LA-1"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := postJSON(t, tt.path, tt.req)
+			if status != tt.expectStatus || body != tt.expectBody {
+				t.Errorf("%v expected %v %v, got %v %v", tt.path, tt.expectStatus, tt.expectBody, status, body)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+
+	// Only the valid route was stored, and the file reloads to the same routes
+	reloaded, err := dal.OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("dal.OpenFileDB error: %v", err)
+	}
+	routes, reloadedRoutes := routeDB.GetRoutes(), reloaded.GetRoutes()
+	if len(routes) != 1 || !reflect.DeepEqual(routes, reloadedRoutes) {
+		t.Errorf("reloaded routes expected %v, got %v", routes, reloadedRoutes)
+	}
+}
+
 func TestBestRoute(t *testing.T) {
 	routeDB := dal.NewDB(&bytes.Buffer{})
 
@@ -160,8 +307,11 @@ func TestBestRoute(t *testing.T) {
 	addRoute(t, *dal.NewRoute("SCL", "ORL", 20))
 
 	for _, test := range tests {
-		expectedBestRoute, expectedCost := domain.FindCheapestRoute(routeDB.GetRoutes(), test.origin, test.destination)
-		resp := bestRouteResponse{Route: expectedBestRoute, Cost: expectedCost}
+		expectedBestRoute, err := domain.FindBestRoute(routeDB.GetRoutes(), test.origin, test.destination, domain.SearchOptions{})
+		if err != nil {
+			t.Fatalf("FindBestRoute unexpected error: %v\n", err.Error())
+		}
+		resp := bestRouteResponse{Route: expectedBestRoute.Route, Legs: expectedBestRoute.Legs, Cost: expectedBestRoute.Cost}
 		expectJS, err := json.Marshal(resp)
 		if err != nil {
 			t.Fatalf("json.Marshal error: %v\n", err.Error())
@@ -175,3 +325,177 @@ func TestBestRoute(t *testing.T) {
 
 	StopWebServer(srv)
 }
+
+func TestBestRouteLegs(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, dal.Route{Origin: "GRU", Destination: "CDG", Cost: 60, FareID: "LA8084"})
+	addRoute(t, dal.Route{Origin: "GRU", Destination: "CDG", Cost: 75, FareID: "AF457"})
+	addRoute(t, *dal.NewRoute("CDG", "FCO", 20))
+
+	expect := `{"Route":["GRU","CDG","FCO"],"Legs":[{"Origin":"GRU","Destination":"CDG","Cost":60,"FareID":"LA8084"},` +
+		`{"Origin":"CDG","Destination":"FCO","Cost":20}],"Cost":80}`
+	bestRoute := getBestRoute(t, "GRU", "FCO")
+	if expect != bestRoute {
+		t.Errorf("BestRoute expected %v, got %v", expect, bestRoute)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestBestRouteCarriers(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, dal.Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF"})
+	addRoute(t, dal.Route{Origin: "GRU", Destination: "CDG", Cost: 60, Carrier: "LA"})
+	addRoute(t, dal.Route{Origin: "GRU", Destination: "CDG", Cost: 40, Carrier: "JJ"})
+
+	var tests = []struct {
+		name   string
+		query  string
+		expect string
+	}{
+		{"NoFilter", "",
+			`{"Route":["GRU","CDG"],"Legs":[{"Origin":"GRU","Destination":"CDG","Cost":40,"Carrier":"JJ"}],"Cost":40}`},
+		{"Exclude", "&ExcludeCarriers=JJ,LA",
+			`{"Route":["GRU","CDG"],"Legs":[{"Origin":"GRU","Destination":"CDG","Cost":75,"Carrier":"AF"}],"Cost":75}`},
+		{"Include", "&IncludeCarriers=AF&IncludeCarriers=LA",
+			`{"Route":["GRU","CDG"],"Legs":[{"Origin":"GRU","Destination":"CDG","Cost":60,"Carrier":"LA"}],"Cost":60}`},
+		{"NoRoute", "&IncludeCarriers=G3",
+			`{"Route":[],"Legs":[],"Cost":0}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, body := getBody(t, "/route/best?Origin=GRU&Destination=CDG"+tt.query)
+			if body != tt.expect {
+				t.Errorf("BestRoute expected %v, got %v", tt.expect, body)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+}
+
+func TestBestRouteAvoidVia(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "ORL", 10))
+	addRoute(t, *dal.NewRoute("ORL", "CDG", 10))
+	addRoute(t, *dal.NewRoute("GRU", "SCL", 20))
+	addRoute(t, *dal.NewRoute("SCL", "CDG", 20))
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 50))
+
+	var tests = []struct {
+		name   string
+		query  string
+		expect string
+	}{
+		{"AvoidORL", "&Avoid=ORL",
+			`{"Route":["GRU","SCL","CDG"],"Legs":[{"Origin":"GRU","Destination":"SCL","Cost":20},{"Origin":"SCL","Destination":"CDG","Cost":20}],"Cost":40}`},
+		{"AvoidORLAndSCL", "&Avoid=ORL,SCL",
+			`{"Route":["GRU","CDG"],"Legs":[{"Origin":"GRU","Destination":"CDG","Cost":50}],"Cost":50}`},
+		{"ViaSCL", "&Via=SCL",
+			`{"Route":["GRU","SCL","CDG"],"Legs":[{"Origin":"GRU","Destination":"SCL","Cost":20},{"Origin":"SCL","Destination":"CDG","Cost":20}],"Cost":40}`},
+		{"ViaSCLAvoidSCL", "&Via=SCL&Avoid=SCL",
+			`{"Route":[],"Legs":[],"Cost":0}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, body := getBody(t, "/route/best?Origin=GRU&Destination=CDG"+tt.query)
+			if body != tt.expect {
+				t.Errorf("BestRoute expected %v, got %v", tt.expect, body)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+}
+
+func TestBestRouteNegativeCycle(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "BRC", 10))
+	addRoute(t, *dal.NewRoute("BRC", "SCL", -5))
+	addRoute(t, *dal.NewRoute("SCL", "BRC", 2))
+
+	status, body := getBody(t, "/route/best?Origin=GRU&Destination=SCL")
+	if status != http.StatusConflict {
+		t.Errorf("BestRoute expected status %v, got %v", http.StatusConflict, status)
+	}
+	if body != `{"code":"negative_cycle","message":"negative cost cycle: BRC > SCL > BRC","details":{"cycle":["BRC","SCL","BRC"]}}` &&
+		body != `{"code":"negative_cycle","message":"negative cost cycle: SCL > BRC > SCL","details":{"cycle":["SCL","BRC","SCL"]}}` {
+		t.Errorf("BestRoute expected the negative cycle, got %v", body)
+	}
+
+	StopWebServer(srv)
+}
+
+func TestBestRouteAsOf(t *testing.T) {
+	routeDB := dal.NewDB(bytes.NewBufferString("GRU,BRC,10\n"))
+
+	srv := StartWebServer(routeDB, 8080)
+	if srv == nil {
+		t.Errorf("TravelServer expected not nil, got nil")
+	}
+
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 75))
+	recorded, _ := json.Marshal(routeDB.GetRoutes()[1].Time)
+	time.Sleep(10 * time.Millisecond)
+	asOf := url.QueryEscape(time.Now().Format(time.RFC3339Nano))
+	time.Sleep(10 * time.Millisecond)
+	addRoute(t, *dal.NewRoute("GRU", "CDG", 60))
+
+	var tests = []struct {
+		name         string
+		path         string
+		expectStatus int
+		expectBody   string
+	}{
+		{"Current", "/route/best?Origin=GRU&Destination=CDG", http.StatusOK,
+			`{"Route":["GRU","CDG"],"Legs":[{"Origin":"GRU","Destination":"CDG","Cost":60}],"Cost":60}`},
+		{"Past", "/route/best?Origin=GRU&Destination=CDG&AsOf=" + asOf, http.StatusOK,
+			`{"Route":["GRU","CDG"],"Legs":[{"Origin":"GRU","Destination":"CDG","Cost":75}],"Cost":75}`},
+		{"BeforeAll", "/route/best?Origin=GRU&Destination=CDG&AsOf=2000-01-01T00:00:00Z", http.StatusOK,
+			`{"Route":[],"Legs":[],"Cost":0}`},
+		{"Routes", "/route?AsOf=" + asOf, http.StatusOK,
+			`[{"Origin":"GRU","Destination":"BRC","Cost":10},{"Origin":"GRU","Destination":"CDG","Cost":75,"Time":` + string(recorded) + `}]`},
+		{"Invalid", "/route?AsOf=yesterday", http.StatusBadRequest,
+			`{"code":"invalid_param","message":"Invalid 'AsOf' param: yesterday","details":{"param":"AsOf","value":"yesterday"}}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := getBody(t, tt.path)
+			if status != tt.expectStatus {
+				t.Errorf("%v expected status %v, got %v", tt.path, tt.expectStatus, status)
+			}
+			if body != tt.expectBody {
+				t.Errorf("%v expected %v, got %v", tt.path, tt.expectBody, body)
+			}
+		})
+	}
+
+	StopWebServer(srv)
+}
@@ -0,0 +1,140 @@
+package dal
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// ErrNotFileBacked is returned when compacting a Database not backed by a routes file
+var ErrNotFileBacked = errors.New("database is not backed by a routes file")
+
+// DefaultRetention is how long the versions of the routes are kept by a compaction when none is set
+const DefaultRetention = 30 * 24 * time.Hour
+
+// Compact rewrites the routes file dropping the versions of the routes older than retention
+// The routes as they were from then on are kept, so they can still be retrieved as of any later instant
+// The instant is recorded in the file, and the routes can no longer be retrieved as of earlier instants
+// The file is replaced atomically, so it is never left half written
+// Returns the number of lines dropped from the file
+func (rDB *DB) Compact(retention time.Duration) (int, error) {
+	rDB.mutex.Lock()
+	defer rDB.mutex.Unlock()
+
+	if rDB.path == "" {
+		return 0, ErrNotFileBacked
+	}
+
+	data, err := ioutil.ReadFile(rDB.path)
+	if err != nil {
+		return 0, err
+	}
+	lines, _ := splitLines(string(data))
+
+	// History dropped by a previous compaction can not be brought back
+	horizon := now().UTC().Round(0).Add(-retention)
+	if horizon.Before(rDB.horizon) {
+		horizon = rDB.horizon
+	}
+
+	// Records are renumbered up to the current sequence, so it keeps growing
+	routes := compactRoutes(rDB.routes, horizon)
+	records := uint64(len(routes)) + 1
+	seq := rDB.seq
+	if seq < records {
+		seq = records
+	}
+	if err := writeFileAtomic(rDB.path, horizon, routes, seq-records+1); err != nil {
+		return 0, err
+	}
+
+	file, err := os.OpenFile(rDB.path, os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return 0, err
+	}
+	if closer, ok := (*rDB.stream).(io.Closer); ok {
+		closer.Close()
+	}
+	var stream io.ReadWriter = file
+	rDB.stream = &stream
+	rDB.written = statStream(stream)
+	rDB.routes = routes
+	rDB.index = newRouteIndex(rDB.routes)
+	rDB.generation++
+	rDB.seq = seq
+
+	// The horizon recorded by a previous compaction is replaced, not dropped
+	dropped := len(lines) - len(routes)
+	if !rDB.horizon.IsZero() {
+		dropped--
+	}
+	rDB.horizon = horizon
+	return dropped, nil
+}
+
+// compactRoutes drops the versions of the routes no longer effective at the instant horizon,
+// as later routes overwrite earlier ones
+// The versions recorded after horizon are kept, tombstones included,
+// along with the last version of each pair recorded up to it, unless deleted
+// Routes keep their order, so bidirectional routes overwrite the same way
+func compactRoutes(routes []Route, horizon time.Time) []Route {
+	last := make(map[routeKey]int)
+	for i := range routes {
+		if !routes[i].Time.After(horizon) {
+			last[keyOf(&routes[i])] = i
+		}
+	}
+
+	compacted := make([]Route, 0, len(routes))
+	for i, route := range routes {
+		if route.Time.After(horizon) || (last[keyOf(&route)] == i && !route.Deleted) {
+			compacted = append(compacted, route)
+		}
+	}
+	return compacted
+}
+
+// writeFileAtomic writes the horizon and the routes as journal records, starting at sequence first,
+// to a temporary file renamed over path
+func writeFileAtomic(path string, horizon time.Time, routes []Route, first uint64) error {
+	dir := filepath.Dir(path)
+	tmp, err := ioutil.TempFile(dir, filepath.Base(path)+".compact-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.WriteString(tmp, toHorizonRecord(horizon, first)); err != nil {
+		tmp.Close()
+		return err
+	}
+	for i := range routes {
+		if _, err := io.WriteString(tmp, toRecord(&routes[i], first+1+uint64(i))); err != nil {
+			tmp.Close()
+			return err
+		}
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		return err
+	}
+
+	// Syncs the directory as well, so the rename survives a crash
+	if d, err := os.Open(dir); err == nil {
+		d.Sync()
+		d.Close()
+	}
+	return nil
+}
@@ -0,0 +1,188 @@
+package dal
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompactRoutes(t *testing.T) {
+	later := recordedAt.Add(time.Hour)
+	input := []Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 10},
+		{Origin: "GRU", Destination: "SCL", Cost: 20, Carrier: "LA"},
+		{Origin: "GRU", Destination: "BRC", Cost: 12},
+		{Origin: "GRU", Destination: "SCL", Cost: 18, Carrier: "JJ"},
+		{Origin: "BRC", Destination: "GRU", Cost: 9},
+		{Origin: "GRU", Destination: "BRC", Cost: 11, Time: recordedAt},
+		{Origin: "BRC", Destination: "GRU", Cost: 9, Deleted: true, Time: recordedAt},
+		{Origin: "GRU", Destination: "BRC", Cost: 13, Time: later},
+		{Origin: "GRU", Destination: "SCL", Cost: 18, Carrier: "JJ", Deleted: true, Time: later},
+	}
+
+	var tests = []struct {
+		name     string
+		horizon  time.Time
+		expected []Route
+	}{
+		{"Before every version", recordedAt.Add(-time.Second), []Route{
+			{Origin: "GRU", Destination: "SCL", Cost: 20, Carrier: "LA"},
+			{Origin: "GRU", Destination: "BRC", Cost: 12},
+			{Origin: "GRU", Destination: "SCL", Cost: 18, Carrier: "JJ"},
+			{Origin: "BRC", Destination: "GRU", Cost: 9},
+			{Origin: "GRU", Destination: "BRC", Cost: 11, Time: recordedAt},
+			{Origin: "BRC", Destination: "GRU", Cost: 9, Deleted: true, Time: recordedAt},
+			{Origin: "GRU", Destination: "BRC", Cost: 13, Time: later},
+			{Origin: "GRU", Destination: "SCL", Cost: 18, Carrier: "JJ", Deleted: true, Time: later},
+		}},
+		{"Between versions", recordedAt, []Route{
+			{Origin: "GRU", Destination: "SCL", Cost: 20, Carrier: "LA"},
+			{Origin: "GRU", Destination: "SCL", Cost: 18, Carrier: "JJ"},
+			{Origin: "GRU", Destination: "BRC", Cost: 11, Time: recordedAt},
+			{Origin: "GRU", Destination: "BRC", Cost: 13, Time: later},
+			{Origin: "GRU", Destination: "SCL", Cost: 18, Carrier: "JJ", Deleted: true, Time: later},
+		}},
+		{"After every version", later, []Route{
+			{Origin: "GRU", Destination: "SCL", Cost: 20, Carrier: "LA"},
+			{Origin: "GRU", Destination: "BRC", Cost: 13, Time: later},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes := compactRoutes(input, tt.horizon)
+			if !reflect.DeepEqual(routes, tt.expected) {
+				t.Errorf("compactRoutes expected %v, got %v", tt.expected, routes)
+			}
+		})
+	}
+}
+
+func TestCompact(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\nBRC,SCL,5\nSCL,ORL,20,asdjfh\nGRU,BRC,12\n")
+	file.Close()
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB, err := OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("OpenFileDB error: %v", err)
+	}
+
+	setClock(t, recordedAt)
+	dropped, err := routeDB.Compact(0)
+	if err != nil {
+		t.Fatalf("routeDB.Compact error: %v", err)
+	}
+	if dropped != 2 {
+		t.Errorf("routeDB.Compact expected 2 lines dropped, got %v", dropped)
+	}
+
+	expected := []Route{
+		{Origin: "BRC", Destination: "SCL", Cost: 5},
+		{Origin: "GRU", Destination: "BRC", Cost: 12},
+	}
+	if routes := routeDB.GetRoutes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("routeDB.GetRoutes expected %v, got %v", expected, routes)
+	}
+
+	// Writes go to the compacted file
+	routeDB.InsertRoute(Route{Origin: "SCL", Destination: "ORL", Cost: 20})
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile error: %v", err)
+	}
+	content := toHorizonRecord(recordedAt, 1) + toRecord(&expected[0], 2) + toRecord(&expected[1], 3) +
+		toRecord(&Route{Origin: "SCL", Destination: "ORL", Cost: 20, Time: recordedAt}, 4)
+	if string(data) != content {
+		t.Errorf("file expected %q, got %q", content, string(data))
+	}
+
+	// No temporary file is left behind
+	files, _ := ioutil.ReadDir(filepath.Dir(path))
+	for _, f := range files {
+		if strings.Contains(f.Name(), ".compact-") {
+			t.Errorf("temporary file %v left behind", f.Name())
+		}
+	}
+}
+
+func TestCompactKeepsHistory(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\n")
+	file.Close()
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB, err := OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("OpenFileDB error: %v", err)
+	}
+	for i, cost := range []float32{12, 14, 16} {
+		setClock(t, recordedAt.Add(time.Duration(i)*time.Hour))
+		routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: cost})
+	}
+
+	// Keeps the versions of the last 90 minutes, along with the one in effect before them
+	setClock(t, recordedAt.Add(2*time.Hour))
+	dropped, err := routeDB.Compact(90 * time.Minute)
+	if err != nil {
+		t.Fatalf("routeDB.Compact error: %v", err)
+	}
+	if dropped != 1 {
+		t.Errorf("routeDB.Compact expected 1 line dropped, got %v", dropped)
+	}
+	horizon := recordedAt.Add(30 * time.Minute)
+
+	// The history survives reopening the file
+	reopened, err := OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("OpenFileDB error: %v", err)
+	}
+	for _, db := range []*DB{routeDB, reopened} {
+		var tests = []struct {
+			asOf     time.Time
+			expected float32
+		}{
+			{horizon, 12},
+			{recordedAt.Add(time.Hour), 14},
+			{recordedAt.Add(2 * time.Hour), 16},
+		}
+		for _, tt := range tests {
+			routes, err := db.GetRoutesAsOf(tt.asOf)
+			if err != nil {
+				t.Fatalf("GetRoutesAsOf error: %v", err)
+			}
+			if last := routes[len(routes)-1]; last.Cost != tt.expected {
+				t.Errorf("GetRoutesAsOf %v expected cost %v, got %v", tt.asOf, tt.expected, last.Cost)
+			}
+		}
+
+		if _, err := db.GetRoutesAsOf(horizon.Add(-time.Second)); !errors.Is(err, ErrHistoryCompacted) {
+			t.Errorf("GetRoutesAsOf expected %v, got %v", ErrHistoryCompacted, err)
+		}
+		asOf := horizon.Add(-time.Second)
+		if _, err := db.QueryRoutes(RouteQuery{AsOf: &asOf}); !errors.Is(err, ErrHistoryCompacted) {
+			t.Errorf("QueryRoutes expected %v, got %v", ErrHistoryCompacted, err)
+		}
+	}
+
+	// A longer retention does not move the horizon back
+	if _, err := reopened.Compact(24 * time.Hour); err != nil {
+		t.Fatalf("Compact error: %v", err)
+	}
+	if _, err := reopened.GetRoutesAsOf(horizon.Add(-time.Second)); !errors.Is(err, ErrHistoryCompacted) {
+		t.Errorf("GetRoutesAsOf expected %v, got %v", ErrHistoryCompacted, err)
+	}
+}
+
+func TestCompactNotFileBacked(t *testing.T) {
+	routeDB := NewDB(bytes.NewBufferString("GRU,BRC,10\n"))
+	if _, err := routeDB.Compact(0); err != ErrNotFileBacked {
+		t.Errorf("routeDB.Compact expected %v, got %v", ErrNotFileBacked, err)
+	}
+}
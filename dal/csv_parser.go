@@ -6,6 +6,7 @@ import (
 	"log"
 	"strconv"
 	"strings"
+	"time"
 )
 
 // csvParser defines a Routes CSV Parser
@@ -19,53 +20,72 @@ func newCSVParser(routeDB *DB) *csvParser {
 }
 
 // parseStream parses CSV stream and fills the Route Database
+// A last line left by a write interrupted midway is quarantined and cut from the stream,
+// as well as the records of a transaction left incomplete
 func (csv *csvParser) parseStream(reader *io.ReadWriter) {
-	internalBuffer := make([]byte, 0)
+	j, err := readJournal(*reader)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, line := range j.corrupt {
+		log.Printf("skipping corrupt record: %q", line)
+	}
 
-	for {
-		temporaryBuffer := make([]byte, 1024)
-		bytesRead, err := (*reader).Read(temporaryBuffer)
-		if err != io.EOF && err != nil {
-			log.Fatal(err)
-		}
+	if j.tail != "" {
+		j.settleTail(*reader)
+	}
 
-		newInternalBuffer := make([]byte, len(internalBuffer)+bytesRead)
-		copy(newInternalBuffer, internalBuffer)
-		copy(newInternalBuffer[len(internalBuffer):], temporaryBuffer[:bytesRead])
-
-		// Adds a end of line caracter to diferenciate from an incomplete line
-		// Adds it to the stream as well, so the next write starts at the right position
-		if err == io.EOF {
-			if len(newInternalBuffer) > 0 && newInternalBuffer[len(newInternalBuffer)-1] != '\n' {
-				newInternalBuffer = append(newInternalBuffer, '\n')
-				(*reader).Write([]byte{'\n'})
-			}
+	if j.tail != "" || len(j.batchLines) > 0 {
+		for _, line := range j.batchLines {
+			csv.routeDB.quarantineRecord(line)
 		}
-
-		routes, bytesConsumed := processLines(string(newInternalBuffer))
-		internalBuffer = newInternalBuffer[bytesConsumed:]
-
-		for _, route := range routes {
-			csv.routeDB.routes = append(csv.routeDB.routes, route)
+		if j.tail != "" {
+			csv.routeDB.quarantineRecord(j.tail)
 		}
-
-		if err == io.EOF {
-			break
+		if !truncate(*reader, int64(j.size-len(j.tail)-j.batchSize())) && j.tail != "" {
+			// Keeps at least the next write in its own line
+			(*reader).Write([]byte{'\n'})
 		}
 	}
+
+	csv.routeDB.routes = append(csv.routeDB.routes, j.routes...)
+	csv.routeDB.seq = j.lastSeq
+	csv.routeDB.horizon = j.horizon
 }
 
-// writeLastRouteToStream writes in CSV format the last added route to the stream
+// writeLastRouteToStream writes as a journal record the last added route to the stream
+// The stream is synced to disk before returning, when possible
 func (csv *csvParser) writeLastRouteToStream(writer *io.ReadWriter) {
 	if len(csv.routeDB.routes) == 0 {
 		return
 	}
 
 	route := csv.routeDB.routes[len(csv.routeDB.routes)-1]
-	_, err := io.WriteString(*writer, toLine(&route))
+	csv.routeDB.seq++
+	_, err := io.WriteString(*writer, toRecord(&route, csv.routeDB.seq))
 	if err != nil {
 		log.Fatal(err)
 	}
+
+	if syncer, ok := (*writer).(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
+
+// truncate cuts the stream to size
+// Returns false in case the stream can not be truncated
+func truncate(stream io.ReadWriter, size int64) bool {
+	truncater, ok := stream.(interface{ Truncate(int64) error })
+	if !ok {
+		return false
+	}
+	if err := truncater.Truncate(size); err != nil {
+		log.Printf("could not truncate: %v", err)
+		return false
+	}
+	return true
 }
 
 // splitLines splits the data input into lines.
@@ -92,21 +112,25 @@ func splitLines(data string) ([]string, int) {
 // processLines splits the input in lines and decode them into Route structs
 // Returns an array of Routes and the amount of data consumed
 func processLines(data string) ([]Route, int) {
-	routes := make([]Route, 0)
-	lines, bytesConsumed := splitLines(data)
-
-	for _, line := range lines {
-		route, err := processLine(line)
-		if err {
-			continue
-		}
-		routes = append(routes, *route)
-	}
-
-	return routes, bytesConsumed
+	j := newJournal()
+	bytesConsumed := j.processLines(data)
+	return j.routes, bytesConsumed
 }
 
+// Optional columns holding the Route attributes
+const (
+	carrierAttribute = "carrier"
+	fareAttribute    = "fare"
+	timeAttribute    = "ts"
+	// bidirectionalMarker is a bare column marking bidirectional routes
+	bidirectionalMarker = "<>"
+	// deletedMarker is a bare column marking tombstones, which delete the route
+	deletedMarker = "deleted"
+)
+
 // toLine transforms the Route Object into a comma separated line
+// Optional fields are written after the cost, only when set:
+// the deleted and bidirectional markers followed by key=value columns
 func toLine(route *Route) string {
 	if route == nil {
 		return ""
@@ -116,16 +140,33 @@ func toLine(route *Route) string {
 	values[0] = route.Origin
 	values[1] = route.Destination
 	values[2] = fmt.Sprintf("%.2f", route.Cost)
+	if route.Deleted {
+		values = append(values, deletedMarker)
+	}
+	if route.Bidirectional {
+		values = append(values, bidirectionalMarker)
+	}
+	if route.Carrier != "" {
+		values = append(values, carrierAttribute+"="+route.Carrier)
+	}
+	if route.FareID != "" {
+		values = append(values, fareAttribute+"="+route.FareID)
+	}
+	if !route.Time.IsZero() {
+		values = append(values, timeAttribute+"="+route.Time.Format(time.RFC3339Nano))
+	}
 
 	return strings.Join(values, ",") + "\n"
 }
 
 // processLine splits comma separated input and decode it into a Route struct
+// The origin, destination and cost columns may be followed by optional columns,
+// either key=value or the deleted and bidirectional markers
 // Returns a Route pointer and an error flag.
 // It will either return nil, true or *Route, false
 func processLine(line string) (*Route, bool) {
 	values := strings.Split(line, ",")
-	if len(values) != 3 {
+	if len(values) < 3 {
 		return nil, true
 	}
 
@@ -136,5 +177,46 @@ func processLine(line string) (*Route, bool) {
 		return nil, true
 	}
 
-	return NewRoute(origin, destination, float32(cost)), false
+	route := NewRoute(origin, destination, float32(cost))
+	for _, value := range values[3:] {
+		if !processAttribute(route, value) {
+			return nil, true
+		}
+	}
+
+	return route, false
+}
+
+// processAttribute decodes an optional column into the route
+// Returns false in case the column is unknown or malformed
+func processAttribute(route *Route, value string) bool {
+	if value == bidirectionalMarker {
+		route.Bidirectional = true
+		return true
+	}
+	if value == deletedMarker {
+		route.Deleted = true
+		return true
+	}
+
+	attribute := strings.SplitN(value, "=", 2)
+	if len(attribute) != 2 {
+		return false
+	}
+
+	switch attribute[0] {
+	case carrierAttribute:
+		route.Carrier = attribute[1]
+	case fareAttribute:
+		route.FareID = attribute[1]
+	case timeAttribute:
+		t, err := time.Parse(time.RFC3339Nano, attribute[1])
+		if err != nil {
+			return false
+		}
+		route.Time = t.UTC()
+	default:
+		return false
+	}
+	return true
 }
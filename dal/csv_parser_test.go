@@ -11,14 +11,25 @@ func TestProcessLine(t *testing.T) {
 		err      bool
 		expected Route
 	}{
-		{"GRU,BRC,10", false, Route{"GRU", "BRC", 10}},
-		{"BRC,SCL,5", false, Route{"BRC", "SCL", 5}},
-		{"GRU,CDG,75", false, Route{"GRU", "CDG", 75}},
-		{"GRU,SCL,20", false, Route{"GRU", "SCL", 20}},
-		{"GRU,ORL,56", false, Route{"GRU", "ORL", 56}},
-		{"ORL,CDG,5", false, Route{"ORL", "CDG", 5}},
-		{"SCL,ORL,20", false, Route{"SCL", "ORL", 20}},
+		{"GRU,BRC,10", false, Route{Origin: "GRU", Destination: "BRC", Cost: 10}},
+		{"BRC,SCL,5", false, Route{Origin: "BRC", Destination: "SCL", Cost: 5}},
+		{"GRU,CDG,75", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75}},
+		{"GRU,SCL,20", false, Route{Origin: "GRU", Destination: "SCL", Cost: 20}},
+		{"GRU,ORL,56", false, Route{Origin: "GRU", Destination: "ORL", Cost: 56}},
+		{"ORL,CDG,5", false, Route{Origin: "ORL", Destination: "CDG", Cost: 5}},
+		{"SCL,ORL,20", false, Route{Origin: "SCL", Destination: "ORL", Cost: 20}},
+		{"GRU,CDG,75,fare=AF457", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75, FareID: "AF457"}},
+		{"GRU,CDG,75,fare=", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75}},
+		{"GRU,CDG,75,carrier=AF", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF"}},
+		{"GRU,CDG,75,fare=AF457,carrier=AF", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF", FareID: "AF457"}},
+		{"GRU,CDG,75,<>", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75, Bidirectional: true}},
+		{"GRU,CDG,75,carrier=AF,<>", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF", Bidirectional: true}},
+		{"GRU,CDG,75,ts=2020-06-02T15:04:05Z", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75, Time: recordedAt}},
+		{"GRU,CDG,75,ts=2020-06-02T12:04:05-03:00", false, Route{Origin: "GRU", Destination: "CDG", Cost: 75, Time: recordedAt}},
+		{"SCL,ORL,20,ts=yesterday", true, Route{}},
 		{"SCL,ORL,20,asdjfh", true, Route{}},
+		{"SCL,ORL,20,<", true, Route{}},
+		{"SCL,ORL,20,seat=12", true, Route{}},
 		{"SCL,ORL,", true, Route{}},
 		{"sdkfjasdfsdfj", true, Route{}},
 	}
@@ -48,13 +59,17 @@ func TestToLine(t *testing.T) {
 		expected string
 		input    *Route
 	}{
-		{"GRU,BRC,10.00\n", &Route{"GRU", "BRC", 10}},
-		{"BRC,SCL,5.00\n", &Route{"BRC", "SCL", 5}},
-		{"GRU,CDG,75.00\n", &Route{"GRU", "CDG", 75}},
-		{"GRU,SCL,20.00\n", &Route{"GRU", "SCL", 20}},
-		{"GRU,ORL,56.00\n", &Route{"GRU", "ORL", 56}},
-		{"ORL,CDG,5.00\n", &Route{"ORL", "CDG", 5}},
-		{"SCL,ORL,20.00\n", &Route{"SCL", "ORL", 20}},
+		{"GRU,BRC,10.00\n", &Route{Origin: "GRU", Destination: "BRC", Cost: 10}},
+		{"BRC,SCL,5.00\n", &Route{Origin: "BRC", Destination: "SCL", Cost: 5}},
+		{"GRU,CDG,75.00\n", &Route{Origin: "GRU", Destination: "CDG", Cost: 75}},
+		{"GRU,SCL,20.00\n", &Route{Origin: "GRU", Destination: "SCL", Cost: 20}},
+		{"GRU,ORL,56.00\n", &Route{Origin: "GRU", Destination: "ORL", Cost: 56}},
+		{"ORL,CDG,5.00\n", &Route{Origin: "ORL", Destination: "CDG", Cost: 5}},
+		{"SCL,ORL,20.00\n", &Route{Origin: "SCL", Destination: "ORL", Cost: 20}},
+		{"GRU,CDG,75.00,fare=AF457\n", &Route{Origin: "GRU", Destination: "CDG", Cost: 75, FareID: "AF457"}},
+		{"GRU,CDG,75.00,carrier=AF,fare=AF457\n", &Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF", FareID: "AF457"}},
+		{"GRU,CDG,75.00,<>\n", &Route{Origin: "GRU", Destination: "CDG", Cost: 75, Bidirectional: true}},
+		{"GRU,CDG,75.00,<>,carrier=AF\n", &Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF", Bidirectional: true}},
 		{"", nil},
 	}
 
@@ -152,13 +167,13 @@ SCL,ORL,20
 `,
 			75,
 			[]Route{
-				{"GRU", "BRC", 10},
-				{"BRC", "SCL", 5},
-				{"GRU", "CDG", 75},
-				{"GRU", "SCL", 20},
-				{"GRU", "ORL", 56},
-				{"ORL", "CDG", 5},
-				{"SCL", "ORL", 20}}},
+				{Origin: "GRU", Destination: "BRC", Cost: 10},
+				{Origin: "BRC", Destination: "SCL", Cost: 5},
+				{Origin: "GRU", Destination: "CDG", Cost: 75},
+				{Origin: "GRU", Destination: "SCL", Cost: 20},
+				{Origin: "GRU", Destination: "ORL", Cost: 56},
+				{Origin: "ORL", Destination: "CDG", Cost: 5},
+				{Origin: "SCL", Destination: "ORL", Cost: 20}}},
 		{"InvalidRoute",
 			`GRU,BRC,10
 BRC,SCL,5,asjdfa
@@ -166,8 +181,8 @@ GRU,CDG,75
 `,
 			39,
 			[]Route{
-				{"GRU", "BRC", 10},
-				{"GRU", "CDG", 75}}},
+				{Origin: "GRU", Destination: "BRC", Cost: 10},
+				{Origin: "GRU", Destination: "CDG", Cost: 75}}},
 		{"Empty", "",
 			0,
 			[]Route{}},
@@ -210,20 +225,20 @@ GRU,ORL,56
 ORL,CDG,5
 SCL,ORL,20`,
 			[]Route{
-				{"GRU", "BRC", 10},
-				{"BRC", "SCL", 5},
-				{"GRU", "CDG", 75},
-				{"GRU", "SCL", 20},
-				{"GRU", "ORL", 56},
-				{"ORL", "CDG", 5},
-				{"SCL", "ORL", 20}}},
+				{Origin: "GRU", Destination: "BRC", Cost: 10},
+				{Origin: "BRC", Destination: "SCL", Cost: 5},
+				{Origin: "GRU", Destination: "CDG", Cost: 75},
+				{Origin: "GRU", Destination: "SCL", Cost: 20},
+				{Origin: "GRU", Destination: "ORL", Cost: 56},
+				{Origin: "ORL", Destination: "CDG", Cost: 5},
+				{Origin: "SCL", Destination: "ORL", Cost: 20}}},
 		{"InvalidRoute",
 			`GRU,BRC,10
 BRC,SCL,5,asjdfa
 GRU,CDG,75`,
 			[]Route{
-				{"GRU", "BRC", 10},
-				{"GRU", "CDG", 75}}},
+				{Origin: "GRU", Destination: "BRC", Cost: 10},
+				{Origin: "GRU", Destination: "CDG", Cost: 75}}},
 		{"Empty", "",
 			[]Route{}},
 	}
@@ -248,13 +263,16 @@ GRU,CDG,75`,
 }
 
 func TestWriteStream(t *testing.T) {
+	setClock(t, recordedAt)
 	var buf bytes.Buffer
 	routeDB := NewDB(&buf)
 	routeDB.InsertRoute(*NewRoute("GRU", "BRC", 10))
 	routeDB.InsertRoute(*NewRoute("BRC", "SCL", 5))
 	routeDB.InsertRoute(*NewRoute("GRU", "CDG", 75))
 
-	expected := "GRU,BRC,10.00\nBRC,SCL,5.00\nGRU,CDG,75.00\n"
+	expected := "GRU,BRC,10.00,ts=2020-06-02T15:04:05Z,seq=1,crc=11d8ad71\n" +
+		"BRC,SCL,5.00,ts=2020-06-02T15:04:05Z,seq=2,crc=71003f74\n" +
+		"GRU,CDG,75.00,ts=2020-06-02T15:04:05Z,seq=3,crc=8b320d75\n"
 	result := buf.String()
 	if expected != result {
 		t.Errorf("value expected %v, got %v", expected, result)
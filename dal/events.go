@@ -0,0 +1,151 @@
+package dal
+
+import "errors"
+
+// Types of route events
+const (
+	EventInserted = "inserted"
+	EventUpdated  = "updated"
+	EventDeleted  = "deleted"
+)
+
+// eventHistory is the amount of past events kept to resume subscriptions
+const eventHistory = 1024
+
+// subscriptionBuffer is the amount of events a subscriber may fall behind before being dropped
+const subscriptionBuffer = 64
+
+// ErrEventsLost is returned when resuming from an event no longer kept
+var ErrEventsLost = errors.New("events after the resume token are no longer kept")
+
+// Event defines a change of the stored routes
+// Its ID is the sequence of the record written, so it keeps growing across restarts
+// Changes made to the routes file by other programs take the next sequences when reloaded
+type Event struct {
+	ID    uint64
+	Type  string
+	Route Route
+}
+
+// Subscription delivers the route events as they happen
+type Subscription struct {
+	routeDB *DB
+	events  chan Event
+	last    uint64
+}
+
+// Events returns the channel the events are delivered to
+// It is closed when the subscription is closed or falls too far behind,
+// in which case it may be resumed from the last event received
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Last returns the ID of the last event delivered before the subscription started
+func (s *Subscription) Last() uint64 {
+	return s.last
+}
+
+// Close stops delivering events
+func (s *Subscription) Close() {
+	s.routeDB.mutex.Lock()
+	defer s.routeDB.mutex.Unlock()
+
+	s.routeDB.unsubscribe(s)
+}
+
+// Subscribe starts delivering the route events
+// Events after the one with ID after are returned as well, so subscriptions may be resumed
+// after 0 subscribes to the events from now on
+// Returns ErrEventsLost in case some of those events are no longer kept
+func (rDB *DB) Subscribe(after uint64) (*Subscription, []Event, error) {
+	rDB.mutex.Lock()
+	defer rDB.mutex.Unlock()
+
+	missed := make([]Event, 0)
+	if after != 0 {
+		if after > rDB.seq {
+			return nil, nil, ErrEventsLost
+		}
+		if after < rDB.seq && (len(rDB.events) == 0 || rDB.events[0].ID > after+1) {
+			return nil, nil, ErrEventsLost
+		}
+		for _, event := range rDB.events {
+			if event.ID > after {
+				missed = append(missed, event)
+			}
+		}
+	}
+
+	s := &Subscription{routeDB: rDB, events: make(chan Event, subscriptionBuffer), last: rDB.seq}
+	if rDB.subscriptions == nil {
+		rDB.subscriptions = make(map[*Subscription]bool)
+	}
+	rDB.subscriptions[s] = true
+	return s, missed, nil
+}
+
+// unsubscribe drops the subscription
+// The Database must be locked
+func (rDB *DB) unsubscribe(s *Subscription) {
+	if rDB.subscriptions[s] {
+		delete(rDB.subscriptions, s)
+		close(s.events)
+	}
+}
+
+// publish delivers the event of a route written with sequence seq
+// Subscribers not keeping up are dropped, so writes never wait for them
+// The Database must be locked
+func (rDB *DB) publish(route Route, seq uint64, existed bool) {
+	event := Event{ID: seq, Type: EventInserted, Route: route}
+	if route.Deleted {
+		event.Type = EventDeleted
+	} else if existed {
+		event.Type = EventUpdated
+	}
+
+	rDB.events = append(rDB.events, event)
+	if len(rDB.events) > eventHistory {
+		rDB.events = rDB.events[len(rDB.events)-eventHistory:]
+	}
+
+	for s := range rDB.subscriptions {
+		select {
+		case s.events <- event:
+		default:
+			rDB.unsubscribe(s)
+		}
+	}
+}
+
+// publishReload publishes an event for each route changed by a reload, previous indexing the routes before it
+// The event holds the last version of the route after the reload, or a tombstone in case none is left
+// The Database must be locked
+func (rDB *DB) publishReload(previous *routeIndex, added []Route, removed []Route) {
+	published := make(map[routeKey]bool)
+	for _, route := range append(append([]Route{}, added...), removed...) {
+		key := keyOf(&route)
+		if published[key] {
+			continue
+		}
+		published[key] = true
+
+		existed := previous.has(key)
+		event := Route{Origin: route.Origin, Destination: route.Destination,
+			Carrier: route.Carrier, FareID: route.FareID, Deleted: true}
+		if positions := rDB.index.byKey[key]; len(positions) != 0 {
+			event = rDB.routes[positions[len(positions)-1]]
+		} else if !existed {
+			continue
+		}
+		rDB.seq++
+		rDB.publish(event, rDB.seq, existed)
+	}
+}
+
+// stored tells whether there is a route not deleted with the key
+// The Database must be locked
+func (rDB *DB) stored(key routeKey) bool {
+	return rDB.index.has(key)
+}
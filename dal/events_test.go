@@ -0,0 +1,97 @@
+package dal
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSubscribe(t *testing.T) {
+	setClock(t, recordedAt)
+	routeDB := NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 10})
+
+	s, missed, err := routeDB.Subscribe(0)
+	if err != nil {
+		t.Fatalf("routeDB.Subscribe error: %v", err)
+	}
+	defer s.Close()
+	if len(missed) != 0 || s.Last() != 1 {
+		t.Errorf("routeDB.Subscribe expected no events after 1, got %v after %v", missed, s.Last())
+	}
+
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 12})
+	tx := routeDB.Begin()
+	tx.InsertRoute(Route{Origin: "BRC", Destination: "SCL", Cost: 5})
+	tx.DeleteRoute(Route{Origin: "GRU", Destination: "BRC"})
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit error: %v", err)
+	}
+
+	expected := []Event{
+		{2, EventUpdated, Route{Origin: "GRU", Destination: "BRC", Cost: 12, Time: recordedAt}},
+		{3, EventInserted, Route{Origin: "BRC", Destination: "SCL", Cost: 5, Time: recordedAt}},
+		{4, EventDeleted, Route{Origin: "GRU", Destination: "BRC", Time: recordedAt, Deleted: true}},
+	}
+	for _, e := range expected {
+		if event := <-s.Events(); event != e {
+			t.Errorf("event expected %v, got %v", e, event)
+		}
+	}
+
+	// Resuming returns the events missed
+	resumed, missed, err := routeDB.Subscribe(2)
+	if err != nil {
+		t.Fatalf("routeDB.Subscribe error: %v", err)
+	}
+	resumed.Close()
+	if len(missed) != 2 || missed[0] != expected[1] || missed[1] != expected[2] {
+		t.Errorf("routeDB.Subscribe expected %v, got %v", expected[1:], missed)
+	}
+
+	if _, _, err := routeDB.Subscribe(5); err != ErrEventsLost {
+		t.Errorf("routeDB.Subscribe expected %v, got %v", ErrEventsLost, err)
+	}
+}
+
+func TestSubscribeEventsLost(t *testing.T) {
+	var buf bytes.Buffer
+	routeDB := NewDB(&buf)
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 10})
+	routeDB.InsertRoute(Route{Origin: "BRC", Destination: "SCL", Cost: 5})
+
+	// Events are not kept across restarts, but their IDs keep growing
+	restarted := NewDB(bytes.NewBufferString(buf.String()))
+	if _, _, err := restarted.Subscribe(1); err != ErrEventsLost {
+		t.Errorf("restarted.Subscribe expected %v, got %v", ErrEventsLost, err)
+	}
+	if _, missed, err := restarted.Subscribe(2); err != nil || len(missed) != 0 {
+		t.Errorf("restarted.Subscribe expected no events, got %v %v", missed, err)
+	}
+
+	restarted.InsertRoute(Route{Origin: "SCL", Destination: "ORL", Cost: 20})
+	if _, missed, err := restarted.Subscribe(2); err != nil || len(missed) != 1 || missed[0].ID != 3 {
+		t.Errorf("restarted.Subscribe expected event 3, got %v %v", missed, err)
+	}
+}
+
+func TestSubscriptionDropped(t *testing.T) {
+	routeDB := NewDB(&bytes.Buffer{})
+	s, _, err := routeDB.Subscribe(0)
+	if err != nil {
+		t.Fatalf("routeDB.Subscribe error: %v", err)
+	}
+
+	// Writes never wait for subscribers falling behind
+	for i := 0; i <= subscriptionBuffer; i++ {
+		routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: float32(i)})
+	}
+
+	received := 0
+	for range s.Events() {
+		received++
+	}
+	if received != subscriptionBuffer {
+		t.Errorf("subscription expected %v events, got %v", subscriptionBuffer, received)
+	}
+	s.Close()
+}
@@ -0,0 +1,84 @@
+package dal
+
+// routeIndex indexes the stored routes by their position in the Database
+// Positions never change until the routes are rewritten by a reload or a compaction,
+// which rebuild the index
+// A reload of routes only appended to the file keeps them
+type routeIndex struct {
+	// live tells whether the route at each position is neither deleted nor a tombstone
+	live []bool
+	// byKey holds the live positions of the versions of each route
+	byKey map[routeKey][]int
+	// byOrigin and byDestination hold the positions of the routes leaving and arriving at each airport
+	// Positions of deleted routes are kept, they are skipped by checking live
+	byOrigin      map[string][]int
+	byDestination map[string][]int
+}
+
+func newRouteIndex(routes []Route) *routeIndex {
+	index := &routeIndex{
+		live:          make([]bool, 0, len(routes)),
+		byKey:         make(map[routeKey][]int),
+		byOrigin:      make(map[string][]int),
+		byDestination: make(map[string][]int),
+	}
+	for i := range routes {
+		index.add(&routes[i])
+	}
+	return index
+}
+
+// add indexes the route stored at the next position
+// Tombstones are not listed, they mark the previous versions of the route as deleted
+func (index *routeIndex) add(route *Route) {
+	position := len(index.live)
+	key := keyOf(route)
+	if route.Deleted {
+		for _, p := range index.byKey[key] {
+			index.live[p] = false
+		}
+		delete(index.byKey, key)
+		index.live = append(index.live, false)
+		return
+	}
+
+	index.live = append(index.live, true)
+	index.byKey[key] = append(index.byKey[key], position)
+	index.byOrigin[route.Origin] = append(index.byOrigin[route.Origin], position)
+	index.byDestination[route.Destination] = append(index.byDestination[route.Destination], position)
+}
+
+// has tells whether there is a live route with the key
+func (index *routeIndex) has(key routeKey) bool {
+	return len(index.byKey[key]) != 0
+}
+
+// candidates lists the live positions that may match the airports, in order
+// The shortest list indexed is used, every live position when neither airport is set
+func (index *routeIndex) candidates(origin string, destination string) []int {
+	var positions []int
+	switch {
+	case origin != "" && destination != "":
+		positions = index.byOrigin[origin]
+		if other := index.byDestination[destination]; len(other) < len(positions) {
+			positions = other
+		}
+	case origin != "":
+		positions = index.byOrigin[origin]
+	case destination != "":
+		positions = index.byDestination[destination]
+	default:
+		positions = make([]int, len(index.live))
+		for i := range positions {
+			positions[i] = i
+		}
+	}
+
+	live := make([]int, 0, len(positions))
+	for _, p := range positions {
+		if index.live[p] {
+			live = append(live, p)
+		}
+	}
+	return live
+}
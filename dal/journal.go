@@ -0,0 +1,265 @@
+package dal
+
+import (
+	"fmt"
+	"hash/crc32"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Columns closing every record written by the DataBase
+// Lines written by hand may leave them out
+// Records written by a transaction are also marked with the sequence of its last record
+const (
+	txAttribute  = "tx"
+	seqAttribute = "seq"
+	crcAttribute = "crc"
+)
+
+// horizonAttribute is the only column of the record written by a compaction,
+// holding the instant the versions of the routes were dropped up to
+const horizonAttribute = "compacted"
+
+// toRecord transforms the Route Object into a journal record:
+// its CSV line followed by the sequence and the CRC-32 checksum of everything before it
+func toRecord(route *Route, seq uint64) string {
+	return toTxRecord(route, seq, 0)
+}
+
+// toTxRecord transforms the Route Object into a journal record of the transaction ending at record tx
+// Records written outside transactions have tx 0
+func toTxRecord(route *Route, seq uint64, tx uint64) string {
+	line := strings.TrimSuffix(toLine(route), "\n")
+	if tx != 0 {
+		line += fmt.Sprintf(",%v=%d", txAttribute, tx)
+	}
+	return sealRecord(line, seq)
+}
+
+// toHorizonRecord transforms the horizon of a compaction into a journal record
+func toHorizonRecord(horizon time.Time, seq uint64) string {
+	return sealRecord(horizonAttribute+"="+horizon.Format(time.RFC3339Nano), seq)
+}
+
+// sealRecord closes the line with the sequence and the CRC-32 checksum of everything before it
+func sealRecord(line string, seq uint64) string {
+	line += fmt.Sprintf(",%v=%d", seqAttribute, seq)
+	return fmt.Sprintf("%v,%v=%08x\n", line, crcAttribute, crc32.ChecksumIEEE([]byte(line)))
+}
+
+// parseHorizon decodes the horizon of a compaction from the route columns of a record
+// Returns false in case the record holds a route instead
+func parseHorizon(line string) (time.Time, bool) {
+	if !strings.HasPrefix(line, horizonAttribute+"=") {
+		return time.Time{}, false
+	}
+	horizon, err := time.Parse(time.RFC3339Nano, line[len(horizonAttribute)+1:])
+	if err != nil {
+		return time.Time{}, false
+	}
+	return horizon.UTC(), true
+}
+
+// record defines a line read from the journal
+type record struct {
+	// line holds the route columns, without transaction, sequence and checksum
+	line string
+	seq  uint64
+	// journaled tells whether the line carried a sequence and checksum
+	journaled bool
+	// tx holds the sequence of the last record of the transaction, if any
+	tx uint64
+}
+
+// checkRecord verifies the checksum of a journal record and strips its sequence and checksum
+// Lines without them are returned untouched
+// Returns the record and false in case the checksum is malformed or does not match
+func checkRecord(line string) (record, bool) {
+	crcIndex := strings.LastIndex(line, ","+crcAttribute+"=")
+	if crcIndex == -1 {
+		// A sequence without checksum is a record cut short
+		if strings.Contains(line, ","+seqAttribute+"=") {
+			return record{}, false
+		}
+		return record{line: line}, true
+	}
+
+	body := line[:crcIndex]
+	checksum, err := strconv.ParseUint(line[crcIndex+len(crcAttribute)+2:], 16, 32)
+	if err != nil || uint32(checksum) != crc32.ChecksumIEEE([]byte(body)) {
+		return record{}, false
+	}
+
+	seqIndex := strings.LastIndex(body, ","+seqAttribute+"=")
+	if seqIndex == -1 {
+		return record{}, false
+	}
+	seq, err := strconv.ParseUint(body[seqIndex+len(seqAttribute)+2:], 10, 64)
+	if err != nil {
+		return record{}, false
+	}
+
+	body = body[:seqIndex]
+	txIndex := strings.LastIndex(body, ","+txAttribute+"=")
+	if txIndex == -1 {
+		return record{line: body, seq: seq, journaled: true}, true
+	}
+	tx, err := strconv.ParseUint(body[txIndex+len(txAttribute)+2:], 10, 64)
+	if err != nil || tx < seq {
+		return record{}, false
+	}
+	return record{line: body[:txIndex], seq: seq, journaled: true, tx: tx}, true
+}
+
+// journal holds the records read from a routes stream
+type journal struct {
+	routes  []Route
+	lastSeq uint64
+	// journaled tells whether any record carried a sequence and checksum
+	journaled bool
+	// corrupt holds the lines whose checksum does not match
+	// and the lines of transactions cut short
+	corrupt []string
+	// tail holds the last line in case it has no end of line
+	tail string
+	size int
+	// batch holds the routes of the transaction being read, applied once its last record is read
+	batch      []Route
+	batchLines []string
+	tx         uint64
+	// droppedTx holds the transaction cut short, whose remaining records are dropped as well
+	droppedTx uint64
+	// horizon holds the instant the last compaction dropped the versions of the routes up to
+	horizon time.Time
+}
+
+func newJournal() *journal {
+	return &journal{routes: make([]Route, 0), corrupt: make([]string, 0)}
+}
+
+// readJournal reads every record of a routes stream
+// The last line is left in tail, unprocessed, in case it has no end of line
+func readJournal(reader io.Reader) (*journal, error) {
+	j := newJournal()
+	internalBuffer := make([]byte, 0)
+
+	for {
+		temporaryBuffer := make([]byte, 1024)
+		bytesRead, err := reader.Read(temporaryBuffer)
+		if err != io.EOF && err != nil {
+			return nil, err
+		}
+		j.size += bytesRead
+
+		newInternalBuffer := make([]byte, len(internalBuffer)+bytesRead)
+		copy(newInternalBuffer, internalBuffer)
+		copy(newInternalBuffer[len(internalBuffer):], temporaryBuffer[:bytesRead])
+
+		bytesConsumed := j.processLines(string(newInternalBuffer))
+		internalBuffer = newInternalBuffer[bytesConsumed:]
+
+		if err == io.EOF {
+			j.tail = string(internalBuffer)
+			return j, nil
+		}
+	}
+}
+
+// processLines splits the input in lines and processes them as records
+// Returns the amount of data consumed
+func (j *journal) processLines(data string) int {
+	lines, bytesConsumed := splitLines(data)
+	for _, line := range lines {
+		j.processRecord(line)
+	}
+	return bytesConsumed
+}
+
+// processRecord verifies the record and decodes its route
+// Lines whose checksum does not match are kept in corrupt
+// Routes written by a transaction are only kept once its last record is read
+func (j *journal) processRecord(line string) {
+	rec, valid := checkRecord(line)
+	if len(j.batchLines) > 0 && (!valid || rec.tx != j.tx) {
+		// The transaction was cut short
+		j.dropBatch()
+	}
+	if !valid || (rec.tx != 0 && rec.tx == j.droppedTx) {
+		j.corrupt = append(j.corrupt, line)
+		return
+	}
+
+	if rec.journaled {
+		j.journaled = true
+		if rec.seq > j.lastSeq {
+			j.lastSeq = rec.seq
+		}
+	}
+
+	if horizon, ok := parseHorizon(rec.line); ok && rec.journaled && rec.tx == 0 {
+		if horizon.After(j.horizon) {
+			j.horizon = horizon
+		}
+		return
+	}
+
+	route, err := processLine(rec.line)
+	if err {
+		return
+	}
+	if rec.tx == 0 {
+		j.routes = append(j.routes, *route)
+		return
+	}
+
+	j.tx = rec.tx
+	j.batch = append(j.batch, *route)
+	j.batchLines = append(j.batchLines, line)
+	if rec.seq == rec.tx {
+		j.routes = append(j.routes, j.batch...)
+		j.batch, j.batchLines = nil, nil
+	}
+}
+
+// dropBatch drops the transaction being read
+func (j *journal) dropBatch() {
+	j.corrupt = append(j.corrupt, j.batchLines...)
+	j.droppedTx = j.tx
+	j.batch, j.batchLines = nil, nil
+}
+
+// batchSize returns the amount of data taken by the records of the transaction being read
+func (j *journal) batchSize() int {
+	size := 0
+	for _, line := range j.batchLines {
+		size += len(line) + 1
+	}
+	return size
+}
+
+// tailTorn tells whether the unterminated last line was left by a write interrupted midway
+// Only a line carrying the columns of a record, whose checksum does not match, is torn
+// Any other line is taken as written by hand, whether the stream is loaded or reloaded
+func (j *journal) tailTorn() bool {
+	if !strings.Contains(j.tail, ","+seqAttribute+"=") && !strings.Contains(j.tail, ","+crcAttribute+"=") {
+		return false
+	}
+	_, valid := checkRecord(j.tail)
+	return !valid
+}
+
+// settleTail processes the unterminated last line, unless torn
+// Adds a end of line caracter to the stream as well, so the next write starts at the right position
+// Returns false in case the line is torn, leaving it in tail
+func (j *journal) settleTail(stream io.Writer) bool {
+	if j.tailTorn() {
+		return false
+	}
+	j.processRecord(j.tail)
+	stream.Write([]byte{'\n'})
+	j.size++
+	j.tail = ""
+	return true
+}
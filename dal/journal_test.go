@@ -0,0 +1,131 @@
+package dal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestToRecord(t *testing.T) {
+	var tests = []struct {
+		expected string
+		input    *Route
+		seq      uint64
+	}{
+		{"GRU,BRC,10.00,seq=1,crc=b16f54fd\n", &Route{Origin: "GRU", Destination: "BRC", Cost: 10}, 1},
+		{"BRC,SCL,5.00,seq=2,crc=b2823c88\n", &Route{Origin: "BRC", Destination: "SCL", Cost: 5}, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected, func(t *testing.T) {
+			value := toRecord(tt.input, tt.seq)
+			if tt.expected != value {
+				t.Errorf("value expected %v, got %v", tt.expected, value)
+			}
+		})
+	}
+}
+
+func TestCheckRecord(t *testing.T) {
+	var tests = []struct {
+		input    string
+		valid    bool
+		expected record
+	}{
+		{"GRU,BRC,10.00,seq=1,crc=b16f54fd", true, record{"GRU,BRC,10.00", 1, true, 0}},
+		{"GRU,CDG,75,carrier=AF", true, record{"GRU,CDG,75,carrier=AF", 0, false, 0}},
+		{"GRU,BRC,10.00,tx=2,seq=1,crc=d852377c", true, record{"GRU,BRC,10.00", 1, true, 2}},
+		{"GRU,BRC,10.00,tx=1,seq=2,crc=70b37c5b", false, record{}},
+		{"GRU,BRC,10.00,seq=1,crc=b16f54fe", false, record{}},
+		{"GRU,BRC,11.00,seq=1,crc=b16f54fd", false, record{}},
+		{"GRU,BRC,10.00,seq=1,crc=b16f", false, record{}},
+		{"GRU,BRC,10.00,seq=1,crc=", false, record{}},
+		{"GRU,BRC,10.00,seq=1", false, record{}},
+		{"GRU,BRC,10.00,crc=00000000", false, record{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rec, valid := checkRecord(tt.input)
+			if valid != tt.valid {
+				t.Fatalf("checkRecord expected %v, got %v", tt.valid, valid)
+			}
+			if valid && rec != tt.expected {
+				t.Errorf("record expected %v, got %v", tt.expected, rec)
+			}
+		})
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	record1 := toRecord(NewRoute("GRU", "BRC", 10), 1)
+	record2 := toRecord(NewRoute("BRC", "SCL", 5), 2)
+	txRecord2 := toTxRecord(NewRoute("BRC", "SCL", 5), 2, 3)
+	txRecord3 := toTxRecord(NewRoute("SCL", "ORL", 20), 3, 3)
+	record3 := toRecord(NewRoute("SCL", "ORL", 20), 3)
+
+	var tests = []struct {
+		name               string
+		input              string
+		expectedRoutes     int
+		expectedQuarantine string
+		expectedSeq        uint64
+	}{
+		{"Clean", record1 + record2, 2, "", 2},
+		{"MissingEndOfLine", record1 + record2[:len(record2)-1], 2, "", 2},
+		{"TornChecksum", record1 + record2[:len(record2)-4], 1, record2[:len(record2)-4] + "\n", 1},
+		{"TornSequence", record1 + record2[:len(record2)-14], 1, record2[:len(record2)-14] + "\n", 1},
+		{"HandWrittenLast", record1 + "BRC,SCL,5", 2, "", 1},
+		{"CorruptRecord", record1 + "BRC,SCL,6.00,seq=2,crc=b2823c88\n" + record2, 2, "", 2},
+		{"HandWritten", "GRU,BRC,10\nBRC,SCL,5", 2, "", 0},
+		{"HandWrittenAfterRecords", record1 + "BRC,SCL,5\nSCL,ORL,20\n", 3, "", 1},
+		{"Transaction", record1 + txRecord2 + txRecord3, 3, "", 3},
+		{"IncompleteTransaction", record1 + txRecord2, 1, txRecord2, 2},
+		{"TornTransaction", record1 + txRecord2 + txRecord3[:len(txRecord3)-4], 1, txRecord2 + txRecord3[:len(txRecord3)-4] + "\n", 2},
+		{"TransactionCutShort", record1 + txRecord2 + record3, 2, "", 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var quarantine bytes.Buffer
+			routeDB := NewDBWithQuarantine(bytes.NewBufferString(tt.input), &quarantine)
+			if len(routeDB.GetRoutes()) != tt.expectedRoutes {
+				t.Errorf("routeDB.GetRoutes expected size %v, got %v", tt.expectedRoutes, len(routeDB.GetRoutes()))
+			}
+			if quarantine.String() != tt.expectedQuarantine {
+				t.Errorf("quarantine expected %q, got %q", tt.expectedQuarantine, quarantine.String())
+			}
+			if routeDB.seq != tt.expectedSeq {
+				t.Errorf("routeDB.seq expected %v, got %v", tt.expectedSeq, routeDB.seq)
+			}
+		})
+	}
+}
+
+func TestRecoveryTruncatesFile(t *testing.T) {
+	record1 := toRecord(NewRoute("GRU", "BRC", 10), 1)
+	record2 := toRecord(NewRoute("BRC", "SCL", 5), 2)
+	torn := record2[:len(record2)-4]
+	path, file := openRoutesFile(t, record1+torn)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	setClock(t, recordedAt)
+	var quarantine bytes.Buffer
+	routeDB := NewDBWithQuarantine(file, &quarantine)
+	routeDB.InsertRoute(*NewRoute("GRU", "CDG", 75))
+	file.Close()
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile error: %v", err)
+	}
+	expected := record1 + toRecord(&Route{Origin: "GRU", Destination: "CDG", Cost: 75, Time: recordedAt}, 2)
+	if string(content) != expected {
+		t.Errorf("file content expected %q, got %q", expected, string(content))
+	}
+	if quarantine.String() != torn+"\n" {
+		t.Errorf("quarantine expected %q, got %q", torn+"\n", quarantine.String())
+	}
+}
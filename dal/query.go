@@ -0,0 +1,189 @@
+package dal
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Fields the routes may be sorted by, prefixed by '-' for descending order
+// Routes are listed in the order they were stored when no field is set
+const (
+	SortOrigin      = "origin"
+	SortDestination = "destination"
+	SortCost        = "cost"
+)
+
+// ErrInvalidSort is returned when sorting by an unknown field
+var ErrInvalidSort = errors.New("invalid sort")
+
+// ErrInvalidCursor is returned when the cursor was not returned by QueryRoutes
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// ErrCursorExpired is returned when the routes were rewritten after the cursor was returned,
+// by a compaction or a reload moving them, the listing must start over
+var ErrCursorExpired = errors.New("cursor expired, the routes were rewritten")
+
+// RouteQuery defines the routes listed by QueryRoutes
+type RouteQuery struct {
+	// Origin and Destination, when set, restrict the routes to the ones leaving or arriving at the airport
+	Origin      string
+	Destination string
+	// MinCost and MaxCost, when set, bound the cost of the routes, inclusive
+	MinCost *float32
+	MaxCost *float32
+	// AsOf, when set, lists the routes as they were at the instant
+	// It can not be before the horizon of the last compaction
+	AsOf *time.Time
+	// Sort is one of the Sort fields, optionally prefixed by '-'
+	Sort string
+	// Limit caps the routes listed, every route is listed when 0
+	Limit int
+	// Cursor resumes the listing after the last route of a previous page
+	Cursor string
+}
+
+// RoutePage defines a page of the routes listed
+type RoutePage struct {
+	Routes []Route
+	// Next is the cursor of the next page, empty in case this is the last one
+	Next string
+}
+
+// QueryRoutes lists the routes matching the query, one page at a time
+// Routes are ordered by the sort field, ties kept in the order they were stored,
+// so pages are stable while routes are inserted and deleted
+// Routes leaving or arriving at an airport are found through the index, without scanning every route
+func (rDB *DB) QueryRoutes(q RouteQuery) (RoutePage, error) {
+	field, descending, err := parseSort(q.Sort)
+	if err != nil {
+		return RoutePage{}, err
+	}
+
+	rDB.mutex.RLock()
+	defer rDB.mutex.RUnlock()
+
+	after := -1
+	if q.Cursor != "" {
+		if after, err = rDB.decodeCursor(q.Cursor); err != nil {
+			return RoutePage{}, err
+		}
+	}
+
+	index := rDB.index
+	if q.AsOf != nil {
+		if err := rDB.checkAsOf(*q.AsOf); err != nil {
+			return RoutePage{}, err
+		}
+		index = newRouteIndexAsOf(rDB.routes, *q.AsOf)
+	}
+	matching := make([]int, 0)
+	for _, p := range index.candidates(q.Origin, q.Destination) {
+		if q.matches(&rDB.routes[p]) {
+			matching = append(matching, p)
+		}
+	}
+
+	// before orders two positions by the sort field, then by position
+	before := func(a int, b int) bool {
+		if c := compareRoutes(&rDB.routes[a], &rDB.routes[b], field); c != 0 {
+			return (c < 0) != descending
+		}
+		return a < b
+	}
+	sort.Slice(matching, func(i, j int) bool { return before(matching[i], matching[j]) })
+	if after >= 0 {
+		matching = matching[sort.Search(len(matching), func(i int) bool { return before(after, matching[i]) }):]
+	}
+
+	page := RoutePage{Routes: make([]Route, 0, len(matching))}
+	if q.Limit > 0 && len(matching) > q.Limit {
+		matching = matching[:q.Limit]
+		page.Next = rDB.encodeCursor(matching[len(matching)-1])
+	}
+	for _, p := range matching {
+		page.Routes = append(page.Routes, rDB.routes[p])
+	}
+	return page, nil
+}
+
+// matches tells whether the route passes the filters of the query
+func (q *RouteQuery) matches(route *Route) bool {
+	return (q.Origin == "" || route.Origin == q.Origin) &&
+		(q.Destination == "" || route.Destination == q.Destination) &&
+		(q.MinCost == nil || route.Cost >= *q.MinCost) &&
+		(q.MaxCost == nil || route.Cost <= *q.MaxCost)
+}
+
+// parseSort splits the sort into its field and direction
+func parseSort(s string) (string, bool, error) {
+	field := strings.TrimPrefix(s, "-")
+	switch field {
+	case "", SortOrigin, SortDestination, SortCost:
+		return field, field != s, nil
+	default:
+		return "", false, fmt.Errorf("%w: %v", ErrInvalidSort, s)
+	}
+}
+
+// compareRoutes compares the field of both routes
+// Returns a negative number when a comes first, positive when b does and 0 when they tie
+func compareRoutes(a *Route, b *Route, field string) int {
+	switch field {
+	case SortOrigin:
+		return strings.Compare(a.Origin, b.Origin)
+	case SortDestination:
+		return strings.Compare(a.Destination, b.Destination)
+	case SortCost:
+		if a.Cost < b.Cost {
+			return -1
+		} else if a.Cost > b.Cost {
+			return 1
+		}
+	}
+	return 0
+}
+
+// newRouteIndexAsOf indexes the routes stored up to the instant asOf
+// Routes stored later are kept at their positions, but never live
+func newRouteIndexAsOf(routes []Route, asOf time.Time) *routeIndex {
+	index := newRouteIndex(nil)
+	for i := range routes {
+		if routes[i].Time.After(asOf) {
+			index.live = append(index.live, false)
+			continue
+		}
+		index.add(&routes[i])
+	}
+	return index
+}
+
+// encodeCursor encodes the position of the last route of a page
+// The generation of the routes is encoded along, positions change when they are rewritten
+func (rDB *DB) encodeCursor(position int) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("%x.%x", rDB.generation, position)))
+}
+
+// decodeCursor decodes the position of the last route of a page
+// The Database must be locked
+func (rDB *DB) decodeCursor(cursor string) (int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return 0, ErrInvalidCursor
+	}
+	var generation uint64
+	var position int
+	if n, err := fmt.Sscanf(string(data), "%x.%x", &generation, &position); err != nil || n != 2 {
+		return 0, ErrInvalidCursor
+	}
+	if generation != rDB.generation {
+		return 0, ErrCursorExpired
+	}
+	if position < 0 || position >= len(rDB.routes) {
+		return 0, ErrInvalidCursor
+	}
+	return position, nil
+}
@@ -0,0 +1,229 @@
+package dal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// summarize lists the routes as "ORIGIN>DESTINATION:COST"
+func summarize(routes []Route) []string {
+	summary := make([]string, len(routes))
+	for i, r := range routes {
+		summary[i] = fmt.Sprintf("%v>%v:%v", r.Origin, r.Destination, r.Cost)
+	}
+	return summary
+}
+
+func costPtr(cost float32) *float32 {
+	return &cost
+}
+
+func TestQueryRoutes(t *testing.T) {
+	routeDB := NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*NewRoute("BRC", "SCL", 5))
+	routeDB.InsertRoute(*NewRoute("GRU", "CDG", 75))
+	routeDB.InsertRoute(*NewRoute("GRU", "SCL", 20))
+	routeDB.InsertRoute(*NewRoute("SCL", "CDG", 20))
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "ORL", Cost: 56, Deleted: true})
+	routeDB.InsertRoute(*NewRoute("GRU", "ORL", 56))
+
+	var tests = []struct {
+		name     string
+		query    RouteQuery
+		expected []string
+	}{
+		{"all", RouteQuery{}, []string{"GRU>BRC:10", "BRC>SCL:5", "GRU>CDG:75", "GRU>SCL:20", "SCL>CDG:20", "GRU>ORL:56"}},
+		{"origin", RouteQuery{Origin: "GRU"}, []string{"GRU>BRC:10", "GRU>CDG:75", "GRU>SCL:20", "GRU>ORL:56"}},
+		{"destination", RouteQuery{Destination: "CDG"}, []string{"GRU>CDG:75", "SCL>CDG:20"}},
+		{"origin and destination", RouteQuery{Origin: "GRU", Destination: "SCL"}, []string{"GRU>SCL:20"}},
+		{"cost range", RouteQuery{MinCost: costPtr(10), MaxCost: costPtr(20)}, []string{"GRU>BRC:10", "GRU>SCL:20", "SCL>CDG:20"}},
+		{"unknown airport", RouteQuery{Origin: "FCO"}, []string{}},
+		{"sort by cost", RouteQuery{Sort: "cost"}, []string{"BRC>SCL:5", "GRU>BRC:10", "GRU>SCL:20", "SCL>CDG:20", "GRU>ORL:56", "GRU>CDG:75"}},
+		{"sort by cost descending", RouteQuery{Sort: "-cost"}, []string{"GRU>CDG:75", "GRU>ORL:56", "GRU>SCL:20", "SCL>CDG:20", "GRU>BRC:10", "BRC>SCL:5"}},
+		{"sort by destination", RouteQuery{Origin: "GRU", Sort: "destination"}, []string{"GRU>BRC:10", "GRU>CDG:75", "GRU>ORL:56", "GRU>SCL:20"}},
+		{"limit", RouteQuery{Sort: "-origin", Limit: 2}, []string{"SCL>CDG:20", "GRU>BRC:10"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := routeDB.QueryRoutes(tt.query)
+			if err != nil {
+				t.Fatalf("routeDB.QueryRoutes error: %v", err)
+			}
+			if summary := summarize(page.Routes); !equalStrings(summary, tt.expected) {
+				t.Errorf("routeDB.QueryRoutes expected %v, got %v", tt.expected, summary)
+			}
+			if (page.Next != "") != (tt.query.Limit != 0) {
+				t.Errorf("routeDB.QueryRoutes expected next cursor %v, got %q", tt.query.Limit != 0, page.Next)
+			}
+		})
+	}
+}
+
+func TestQueryRoutesPages(t *testing.T) {
+	routeDB := NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*NewRoute("GRU", "CDG", 75))
+	routeDB.InsertRoute(*NewRoute("GRU", "SCL", 20))
+	routeDB.InsertRoute(*NewRoute("GRU", "ORL", 20))
+
+	q := RouteQuery{Origin: "GRU", Sort: "cost", Limit: 2}
+	first, err := routeDB.QueryRoutes(q)
+	if err != nil {
+		t.Fatalf("routeDB.QueryRoutes error: %v", err)
+	}
+	if expected := []string{"GRU>BRC:10", "GRU>SCL:20"}; !equalStrings(summarize(first.Routes), expected) {
+		t.Errorf("first page expected %v, got %v", expected, summarize(first.Routes))
+	}
+
+	// Changes made between pages do not shift the routes not changed
+	routeDB.InsertRoute(*NewRoute("GRU", "FCO", 5))
+	routeDB.InsertRoute(*NewRoute("GRU", "MIA", 30))
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "SCL", Deleted: true})
+
+	q.Cursor = first.Next
+	second, err := routeDB.QueryRoutes(q)
+	if err != nil {
+		t.Fatalf("routeDB.QueryRoutes error: %v", err)
+	}
+	if expected := []string{"GRU>ORL:20", "GRU>MIA:30"}; !equalStrings(summarize(second.Routes), expected) {
+		t.Errorf("second page expected %v, got %v", expected, summarize(second.Routes))
+	}
+
+	q.Cursor = second.Next
+	last, err := routeDB.QueryRoutes(q)
+	if err != nil {
+		t.Fatalf("routeDB.QueryRoutes error: %v", err)
+	}
+	if expected := []string{"GRU>CDG:75"}; !equalStrings(summarize(last.Routes), expected) || last.Next != "" {
+		t.Errorf("last page expected %v, got %v %q", expected, summarize(last.Routes), last.Next)
+	}
+}
+
+func TestQueryRoutesAsOf(t *testing.T) {
+	setClock(t, recordedAt)
+	routeDB := NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*NewRoute("GRU", "SCL", 20))
+
+	setClock(t, recordedAt.Add(time.Hour))
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Deleted: true})
+	routeDB.InsertRoute(*NewRoute("GRU", "CDG", 75))
+
+	page, err := routeDB.QueryRoutes(RouteQuery{Origin: "GRU", AsOf: &recordedAt})
+	if err != nil {
+		t.Fatalf("routeDB.QueryRoutes error: %v", err)
+	}
+	if expected := []string{"GRU>BRC:10", "GRU>SCL:20"}; !equalStrings(summarize(page.Routes), expected) {
+		t.Errorf("routeDB.QueryRoutes expected %v, got %v", expected, summarize(page.Routes))
+	}
+}
+
+func TestQueryRoutesErrors(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\nGRU,BRC,12\nGRU,CDG,75\n")
+	file.Close()
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB, err := OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("OpenFileDB error: %v", err)
+	}
+
+	page, err := routeDB.QueryRoutes(RouteQuery{Limit: 1})
+	if err != nil {
+		t.Fatalf("routeDB.QueryRoutes error: %v", err)
+	}
+
+	var tests = []struct {
+		name     string
+		query    RouteQuery
+		expected error
+	}{
+		{"unknown sort", RouteQuery{Sort: "carrier"}, ErrInvalidSort},
+		{"not a cursor", RouteQuery{Cursor: "GRU"}, ErrInvalidCursor},
+		{"position out of range", RouteQuery{Cursor: routeDB.encodeCursor(3)}, ErrInvalidCursor},
+		{"valid cursor", RouteQuery{Cursor: page.Next}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := routeDB.QueryRoutes(tt.query); !errors.Is(err, tt.expected) {
+				t.Errorf("routeDB.QueryRoutes expected %v, got %v", tt.expected, err)
+			}
+		})
+	}
+
+	// Compacting moves the routes, so the listing must start over
+	if _, err := routeDB.Compact(0); err != nil {
+		t.Fatalf("routeDB.Compact error: %v", err)
+	}
+	if _, err := routeDB.QueryRoutes(RouteQuery{Cursor: page.Next}); err != ErrCursorExpired {
+		t.Errorf("routeDB.QueryRoutes expected %v, got %v", ErrCursorExpired, err)
+	}
+}
+
+func TestQueryRoutesWatched(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\nBRC,SCL,5\n")
+	file.Close()
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB, err := OpenFileDB(path)
+	if err != nil {
+		t.Fatalf("OpenFileDB error: %v", err)
+	}
+	watcher := WatchFile(routeDB, path, 10*time.Millisecond)
+	defer watcher.Stop()
+
+	page, err := routeDB.QueryRoutes(RouteQuery{Limit: 1})
+	if err != nil {
+		t.Fatalf("routeDB.QueryRoutes error: %v", err)
+	}
+
+	// Routes inserted or appended to the file by hand keep the positions
+	routeDB.InsertRoute(*NewRoute("SCL", "ORL", 20))
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("os.OpenFile error: %v", err)
+	}
+	f.WriteString("ORL,CDG,5\n")
+	f.Close()
+	waitRoutes(t, routeDB, 4)
+	time.Sleep(50 * time.Millisecond)
+
+	next, err := routeDB.QueryRoutes(RouteQuery{Cursor: page.Next})
+	if err != nil {
+		t.Fatalf("routeDB.QueryRoutes error: %v", err)
+	}
+	expected := []string{"BRC>SCL:5", "SCL>ORL:20", "ORL>CDG:5"}
+	if routes := summarize(next.Routes); !equalStrings(routes, expected) {
+		t.Errorf("routeDB.QueryRoutes expected %v, got %v", expected, routes)
+	}
+
+	// Routes removed by hand move the ones after them, so the listing must start over
+	if err := ioutil.WriteFile(path, []byte("BRC,SCL,5\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+	waitRoutes(t, routeDB, 1)
+	if _, err := routeDB.QueryRoutes(RouteQuery{Cursor: page.Next}); err != ErrCursorExpired {
+		t.Errorf("routeDB.QueryRoutes expected %v, got %v", ErrCursorExpired, err)
+	}
+}
+
+func equalStrings(a []string, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
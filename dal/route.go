@@ -1,40 +1,370 @@
 // Package dal implements simple functions to store and retrieve routes.
 package dal
 
-import "io"
+import (
+	"TravelRoute/poller"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
 
 // Route defines a weighted oriented connection between 2 airports
+// Routes between the same airports with distinct Carrier or FareID are alternative legs
 type Route struct {
 	Origin      string
 	Destination string
 	Cost        float32
+	Carrier     string `json:",omitempty"`
+	FareID      string `json:",omitempty"`
+	// Bidirectional routes connect the destination back to the origin with the same cost
+	Bidirectional bool `json:",omitempty"`
+	// Time holds when the route was recorded, it is zero for routes written by hand
+	Time time.Time `json:"-"`
+	// Deleted marks a tombstone, which deletes the previous versions of the route
+	Deleted bool `json:"-"`
+}
+
+// InvalidRouteError is returned when a field of the route can not be stored in the routes file
+type InvalidRouteError struct {
+	Field string
+	Value string
+}
+
+func (e *InvalidRouteError) Error() string {
+	if e.Value == "" {
+		return fmt.Sprintf("invalid route: %v is required", e.Field)
+	}
+	return fmt.Sprintf("invalid route: %v %q can not hold ',', '=' or line breaks", e.Field, e.Value)
+}
+
+// Validate checks the route can be stored and read back from the routes file as it is
+// Origin and Destination are required, and no field may hold the separators of the columns
+// Returns an InvalidRouteError naming the first invalid field
+func (route *Route) Validate() error {
+	fields := []struct {
+		name     string
+		value    string
+		required bool
+	}{
+		{"Origin", route.Origin, true},
+		{"Destination", route.Destination, true},
+		{"Carrier", route.Carrier, false},
+		{"FareID", route.FareID, false},
+	}
+	for _, f := range fields {
+		if (f.required && f.value == "") || strings.ContainsAny(f.value, ",=\r\n") {
+			return &InvalidRouteError{f.name, f.value}
+		}
+	}
+	return nil
+}
+
+// routeKey identifies the versions of a route, later versions overwrite earlier ones
+type routeKey struct {
+	origin      string
+	destination string
+	carrier     string
+	fareID      string
+}
+
+func keyOf(route *Route) routeKey {
+	return routeKey{route.Origin, route.Destination, route.Carrier, route.FareID}
 }
 
 // NewRoute Constructs a route given an origin destination and cost
 func NewRoute(origin string, destination string, cost float32) *Route {
-	return &Route{origin, destination, cost}
+	return &Route{Origin: origin, Destination: destination, Cost: cost}
 }
 
+// ErrHistoryCompacted is returned when retrieving the routes as of an instant
+// whose versions were dropped by a compaction
+var ErrHistoryCompacted = errors.New("history compacted")
+
+// now is the clock used to timestamp inserted routes
+var now = time.Now
+
 // DB Defines an memory DataBase to store our routes
+// It is safe for concurrent use
 type DB struct {
-	routes []Route
-	stream *io.ReadWriter
+	mutex      sync.RWMutex
+	routes     []Route
+	stream     *io.ReadWriter
+	seq        uint64
+	quarantine io.Writer
+	// path holds the routes file, when the Database is backed by one
+	path string
+	// events holds the latest events, delivered to the subscriptions as they happen
+	events        []Event
+	subscriptions map[*Subscription]bool
+	// index locates the stored routes, it is updated along with routes
+	index *routeIndex
+	// generation changes whenever the stored routes are rewritten, expiring the cursors returned
+	generation uint64
+	// written holds the routes file as the Database last left it,
+	// so the Watcher can tell the writes of the Database from changes made by other programs
+	written os.FileInfo
+	// horizon holds the instant the last compaction dropped the versions of the routes up to,
+	// the routes can not be retrieved as of earlier instants
+	horizon time.Time
 }
 
 // NewDB constructs a new Route Database
 func NewDB(stream io.ReadWriter) *DB {
-	db := DB{make([]Route, 0), &stream}
-	newCSVParser(&db).parseStream(&stream)
-	return &db
+	return NewDBWithQuarantine(stream, nil)
+}
+
+// NewDBWithQuarantine constructs a new Route Database
+// Records left torn in the stream by an interrupted write are moved to quarantine
+func NewDBWithQuarantine(stream io.ReadWriter, quarantine io.Writer) *DB {
+	db := &DB{routes: make([]Route, 0), stream: &stream, quarantine: quarantine}
+	newCSVParser(db).parseStream(&stream)
+	db.index = newRouteIndex(db.routes)
+	db.generation = uint64(time.Now().UnixNano())
+	db.written = statStream(stream)
+	return db
+}
+
+// OpenFileDB constructs a new Route Database backed by the routes file at path
+// Torn records are moved to the path.quarantine file
+func OpenFileDB(path string) (*DB, error) {
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	quarantine, err := os.OpenFile(path+".quarantine", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		file.Close()
+		return nil, err
+	}
+
+	db := NewDBWithQuarantine(file, quarantine)
+	db.path = path
+	return db, nil
 }
 
 // InsertRoute inserts a route in the database
+// Previous versions of the route are kept, the route is timestamped to tell them apart
 func (rDB *DB) InsertRoute(route Route) {
+	rDB.mutex.Lock()
+	defer rDB.mutex.Unlock()
+
+	route.Time = now().UTC().Round(0)
+	existed := rDB.stored(keyOf(&route))
 	rDB.routes = append(rDB.routes, route)
+	rDB.index.add(&route)
+	untouched := rDB.untouched()
 	newCSVParser(rDB).writeLastRouteToStream(rDB.stream)
+	rDB.wrote(untouched)
+	rDB.publish(route, rDB.seq, existed)
 }
 
 // GetRoutes retrieves all routes stored in the Databse
+// Deleted routes are left out
 func (rDB *DB) GetRoutes() []Route {
-	return rDB.routes
+	rDB.mutex.RLock()
+	defer rDB.mutex.RUnlock()
+
+	return liveRoutes(rDB.routes)
+}
+
+// GetRoutesAsOf retrieves the routes stored in the Database up to the instant asOf
+// Routes written by hand have no timestamp and are always retrieved
+// Returns ErrHistoryCompacted in case the versions stored up to asOf were dropped by a compaction
+func (rDB *DB) GetRoutesAsOf(asOf time.Time) ([]Route, error) {
+	rDB.mutex.RLock()
+	defer rDB.mutex.RUnlock()
+
+	if err := rDB.checkAsOf(asOf); err != nil {
+		return nil, err
+	}
+	routes := make([]Route, 0, len(rDB.routes))
+	for _, route := range rDB.routes {
+		if !route.Time.After(asOf) {
+			routes = append(routes, route)
+		}
+	}
+	return liveRoutes(routes), nil
+}
+
+// checkAsOf returns ErrHistoryCompacted in case asOf is before the horizon of the last compaction
+// The Database must be locked
+func (rDB *DB) checkAsOf(asOf time.Time) error {
+	if asOf.Before(rDB.horizon) {
+		return fmt.Errorf("%w: the versions of the routes before %v were dropped",
+			ErrHistoryCompacted, rDB.horizon.Format(time.RFC3339Nano))
+	}
+	return nil
+}
+
+// liveRoutes applies the tombstones to the stored routes
+// Returns the routes not deleted, tombstones left out
+func liveRoutes(stored []Route) []Route {
+	routes := make([]Route, 0, len(stored))
+	for _, route := range stored {
+		if !route.Deleted {
+			routes = append(routes, route)
+			continue
+		}
+
+		key := keyOf(&route)
+		kept := routes[:0]
+		for _, r := range routes {
+			if keyOf(&r) != key {
+				kept = append(kept, r)
+			}
+		}
+		routes = kept
+	}
+	return routes
+}
+
+// reload parses the whole stream and swaps its routes for the stored ones at once
+// Reading while locked keeps routes being inserted from getting lost in the swap
+// The routes changed are published to the subscriptions
+// Returns the routes added and removed by the swap
+func (rDB *DB) reload(stream io.ReadWriter) ([]Route, []Route, error) {
+	rDB.mutex.Lock()
+	defer rDB.mutex.Unlock()
+
+	j, err := readJournal(stream)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, line := range j.corrupt {
+		log.Printf("skipping corrupt record: %q", line)
+	}
+
+	// The unterminated line follows the rule applied on startup, so it is kept or dropped either way
+	if j.tail != "" && !j.settleTail(stream) {
+		rDB.quarantineRecord(j.tail)
+		truncate(stream, int64(j.size-len(j.tail)))
+	}
+	for _, line := range j.batchLines {
+		log.Printf("skipping record of incomplete transaction: %q", line)
+	}
+
+	added, removed := diffRoutes(liveRoutes(rDB.routes), liveRoutes(j.routes))
+	previous := rDB.index
+	// Cursors hold positions, which are kept when routes are only appended to the file
+	if !samePositions(rDB.routes, j.routes) {
+		rDB.generation++
+	}
+	rDB.routes = j.routes
+	rDB.index = newRouteIndex(rDB.routes)
+	if j.lastSeq > rDB.seq {
+		rDB.seq = j.lastSeq
+	}
+	rDB.horizon = j.horizon
+	rDB.written = statStream(stream)
+	rDB.publishReload(previous, added, removed)
+	return added, removed, nil
+}
+
+// quarantineRecord sets aside a torn record
+func (rDB *DB) quarantineRecord(line string) {
+	log.Printf("quarantining torn record: %q", line)
+	if rDB.quarantine == nil {
+		return
+	}
+
+	if _, err := io.WriteString(rDB.quarantine, line+"\n"); err != nil {
+		log.Printf("could not quarantine record: %v", err)
+		return
+	}
+	if syncer, ok := rDB.quarantine.(interface{ Sync() error }); ok {
+		syncer.Sync()
+	}
+}
+
+// statStream returns the state of the stream on disk, nil in case it is not a file
+func statStream(stream io.ReadWriter) os.FileInfo {
+	file, ok := stream.(*os.File)
+	if !ok {
+		return nil
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil
+	}
+	return info
+}
+
+// untouched tells whether the stream is as the Database last left it, before a write
+// The Database must be locked
+func (rDB *DB) untouched() bool {
+	return poller.Same(rDB.written, statStream(*rDB.stream))
+}
+
+// wrote records the stream as the Database left it, after a write
+// Changes made by other programs before the write are kept unrecorded, so the Watcher still reloads them
+// The Database must be locked
+func (rDB *DB) wrote(untouched bool) {
+	if untouched {
+		rDB.written = statStream(*rDB.stream)
+	}
+}
+
+// ownWrite tells whether the routes file, as it is on disk, was last written by the Database
+func (rDB *DB) ownWrite(info os.FileInfo) bool {
+	rDB.mutex.RLock()
+	defer rDB.mutex.RUnlock()
+	return poller.Same(rDB.written, info)
+}
+
+// replaceStream swaps the stream new routes are written to
+// The previous stream is closed if it can be
+func (rDB *DB) replaceStream(stream io.ReadWriter) {
+	rDB.mutex.Lock()
+	defer rDB.mutex.Unlock()
+
+	if closer, ok := (*rDB.stream).(io.Closer); ok {
+		closer.Close()
+	}
+	rDB.stream = &stream
+}
+
+// samePositions tells whether every stored route is at the same position in newRoutes
+func samePositions(stored []Route, newRoutes []Route) bool {
+	if len(newRoutes) < len(stored) {
+		return false
+	}
+	for i := range stored {
+		if keyOf(&stored[i]) != keyOf(&newRoutes[i]) || stored[i].Deleted != newRoutes[i].Deleted {
+			return false
+		}
+	}
+	return true
+}
+
+// diffRoutes compares two sets of routes
+// Returns the routes only in newRoutes and the routes only in oldRoutes
+func diffRoutes(oldRoutes []Route, newRoutes []Route) ([]Route, []Route) {
+	count := make(map[Route]int)
+	for _, route := range oldRoutes {
+		count[route]++
+	}
+
+	added := make([]Route, 0)
+	for _, route := range newRoutes {
+		if count[route] > 0 {
+			count[route]--
+		} else {
+			added = append(added, route)
+		}
+	}
+
+	removed := make([]Route, 0)
+	for _, route := range oldRoutes {
+		if count[route] > 0 {
+			count[route]--
+			removed = append(removed, route)
+		}
+	}
+	return added, removed
 }
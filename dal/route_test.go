@@ -2,14 +2,26 @@ package dal
 
 import (
 	"bytes"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
+// recordedAt is the instant routes are inserted at while the clock is set
+var recordedAt = time.Date(2020, 6, 2, 15, 4, 5, 0, time.UTC)
+
+// setClock fixes the clock used to timestamp inserted routes until the test ends
+func setClock(t *testing.T, at time.Time) {
+	now = func() time.Time { return at }
+	t.Cleanup(func() { now = time.Now })
+}
+
 func TestRouteInsert(t *testing.T) {
 	var buf bytes.Buffer
 	routeDB := NewDB(&buf)
 
-	routeDB.InsertRoute(Route{"GRU", "CON", 5.2})
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "CON", Cost: 5.2})
 	routes := routeDB.GetRoutes()
 
 	if len(routes) != 1 {
@@ -17,7 +29,7 @@ func TestRouteInsert(t *testing.T) {
 	}
 
 	if routes[0].Origin != "GRU" || routes[0].Destination != "CON" || routes[0].Cost != 5.2 {
-		t.Errorf("route expected %v, got %v", Route{"GRU", "CON", 5.2}, routes[0])
+		t.Errorf("route expected %v, got %v", Route{Origin: "GRU", Destination: "CON", Cost: 5.2}, routes[0])
 	}
 }
 
@@ -35,3 +47,91 @@ func TestEmptyRoute(t *testing.T) {
 		t.Errorf("routes expected %v, got %v", make([]Route, 0), routes)
 	}
 }
+
+func TestRouteValidate(t *testing.T) {
+	var tests = []struct {
+		name     string
+		route    Route
+		expected error
+	}{
+		{"Valid", Route{Origin: "GRU", Destination: "BRC", Cost: 10, Carrier: "LA", FareID: "LA-1"}, nil},
+		{"MissingOrigin", Route{Destination: "BRC"}, &InvalidRouteError{"Origin", ""}},
+		{"MissingDestination", Route{Origin: "GRU"}, &InvalidRouteError{"Destination", ""}},
+		{"Comma", Route{Origin: "GRU", Destination: "BRC,SCL"}, &InvalidRouteError{"Destination", "BRC,SCL"}},
+		{"LineBreak", Route{Origin: "GRU", Destination: "BRC", Carrier: "LA\nSCL,ORL,1"}, &InvalidRouteError{"Carrier", "LA\nSCL,ORL,1"}},
+		{"Equals", Route{Origin: "GRU", Destination: "BRC", FareID: "seq=9"}, &InvalidRouteError{"FareID", "seq=9"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.route.Validate()
+			if !reflect.DeepEqual(err, tt.expected) {
+				t.Errorf("route.Validate expected %v, got %v", tt.expected, err)
+			}
+			// Valid routes are read back as they were written
+			if err == nil {
+				if read, failed := processLine(strings.TrimSuffix(toLine(&tt.route), "\n")); failed || *read != tt.route {
+					t.Errorf("processLine expected %v, got %v", tt.route, read)
+				}
+			}
+		})
+	}
+}
+
+func TestGetRoutesAsOf(t *testing.T) {
+	routeDB := NewDB(bytes.NewBufferString("GRU,BRC,10\n"))
+
+	setClock(t, recordedAt)
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "CDG", Cost: 75})
+	setClock(t, recordedAt.Add(time.Hour))
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "CDG", Cost: 60})
+
+	var tests = []struct {
+		name     string
+		asOf     time.Time
+		expected []Route
+	}{
+		{"Before", recordedAt.Add(-time.Second), []Route{
+			{Origin: "GRU", Destination: "BRC", Cost: 10},
+		}},
+		{"First", recordedAt, []Route{
+			{Origin: "GRU", Destination: "BRC", Cost: 10},
+			{Origin: "GRU", Destination: "CDG", Cost: 75, Time: recordedAt},
+		}},
+		{"Both", recordedAt.Add(2 * time.Hour), []Route{
+			{Origin: "GRU", Destination: "BRC", Cost: 10},
+			{Origin: "GRU", Destination: "CDG", Cost: 75, Time: recordedAt},
+			{Origin: "GRU", Destination: "CDG", Cost: 60, Time: recordedAt.Add(time.Hour)},
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			routes, err := routeDB.GetRoutesAsOf(tt.asOf)
+			if err != nil {
+				t.Fatalf("routeDB.GetRoutesAsOf error: %v", err)
+			}
+			if len(routes) != len(tt.expected) {
+				t.Fatalf("routeDB.GetRoutesAsOf expected %v, got %v", tt.expected, routes)
+			}
+			for i := range tt.expected {
+				if routes[i] != tt.expected[i] {
+					t.Errorf("route expected %v, got %v", tt.expected[i], routes[i])
+				}
+			}
+		})
+	}
+}
+
+func TestInsertedRouteReloads(t *testing.T) {
+	var buf bytes.Buffer
+	routeDB := NewDB(&buf)
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "CDG", Cost: 75})
+
+	// The timestamp read back matches the one in memory, so reloads find no changes
+	reloaded := NewDB(bytes.NewBufferString(buf.String()))
+	added, removed := diffRoutes(routeDB.GetRoutes(), reloaded.GetRoutes())
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("diffRoutes expected no changes, got %v added, %v removed", added, removed)
+	}
+}
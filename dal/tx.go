@@ -0,0 +1,147 @@
+package dal
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+)
+
+// ErrTxDone is returned when using a transaction already committed or rolled back
+var ErrTxDone = errors.New("transaction has already been committed or rolled back")
+
+// RouteNotFoundError is returned when updating or deleting a route not stored in the Database
+type RouteNotFoundError struct {
+	Route Route
+}
+
+func (e *RouteNotFoundError) Error() string {
+	msg := fmt.Sprintf("route not found: %v > %v", e.Route.Origin, e.Route.Destination)
+	if e.Route.Carrier != "" {
+		msg += " carrier " + e.Route.Carrier
+	}
+	if e.Route.FareID != "" {
+		msg += " fare " + e.Route.FareID
+	}
+	return msg
+}
+
+// Tx defines a set of route changes applied to the Database all at once
+// It is not safe for concurrent use
+type Tx struct {
+	routeDB *DB
+	changes []change
+	done    bool
+}
+
+// change defines a route change of a transaction
+type change struct {
+	route Route
+	// existing tells the route must be stored already
+	existing bool
+}
+
+// Begin starts a transaction
+// Its changes are neither visible nor written until committed
+func (rDB *DB) Begin() *Tx {
+	return &Tx{routeDB: rDB}
+}
+
+// InsertRoute inserts a route when the transaction is committed
+func (tx *Tx) InsertRoute(route Route) {
+	tx.changes = append(tx.changes, change{route: route})
+}
+
+// UpdateRoute replaces a stored route with the same origin, destination, carrier and fare
+// The commit fails in case there is no such route
+func (tx *Tx) UpdateRoute(route Route) {
+	tx.changes = append(tx.changes, change{route: route, existing: true})
+}
+
+// DeleteRoute deletes the stored route with the same origin, destination, carrier and fare
+// The commit fails in case there is no such route
+func (tx *Tx) DeleteRoute(route Route) {
+	tombstone := Route{Origin: route.Origin, Destination: route.Destination,
+		Carrier: route.Carrier, FareID: route.FareID, Deleted: true}
+	tx.changes = append(tx.changes, change{route: tombstone, existing: true})
+}
+
+// Rollback discards the changes of the transaction
+func (tx *Tx) Rollback() {
+	tx.done = true
+	tx.changes = nil
+}
+
+// Commit applies the changes of the transaction
+// Either all changes are written to the stream and stored, or none is
+// The routes written share the same timestamp
+func (tx *Tx) Commit() error {
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if len(tx.changes) == 0 {
+		return nil
+	}
+
+	rDB := tx.routeDB
+	rDB.mutex.Lock()
+	defer rDB.mutex.Unlock()
+
+	// keys holds whether the routes changed by the transaction are live so far
+	keys := make(map[routeKey]bool)
+	live := func(key routeKey) bool {
+		if value, found := keys[key]; found {
+			return value
+		}
+		return rDB.index.has(key)
+	}
+
+	timestamp := now().UTC().Round(0)
+	routes := make([]Route, len(tx.changes))
+	existed := make([]bool, len(tx.changes))
+	for i, c := range tx.changes {
+		key := keyOf(&c.route)
+		if c.existing && !live(key) {
+			return &RouteNotFoundError{c.route}
+		}
+		existed[i] = live(key)
+		keys[key] = !c.route.Deleted
+
+		routes[i] = c.route
+		routes[i].Time = timestamp
+	}
+
+	// Records are written at once, the last one marks the transaction as complete
+	var buf bytes.Buffer
+	last := rDB.seq + uint64(len(routes))
+	for i := range routes {
+		buf.WriteString(toTxRecord(&routes[i], rDB.seq+uint64(i)+1, last))
+	}
+	untouched := rDB.untouched()
+	defer rDB.wrote(untouched)
+	if _, err := (*rDB.stream).Write(buf.Bytes()); err != nil {
+		// Keeps the next write in its own line, the incomplete transaction is skipped when read
+		io.WriteString(*rDB.stream, "\n")
+		// Some records may have been written, their sequences are not taken again
+		// so no later transaction ends at the same record as the incomplete one
+		rDB.seq = last
+		return err
+	}
+	if syncer, ok := (*rDB.stream).(interface{ Sync() error }); ok {
+		if err := syncer.Sync(); err != nil {
+			log.Printf("could not sync: %v", err)
+		}
+	}
+
+	for i := range routes {
+		rDB.publish(routes[i], rDB.seq+uint64(i)+1, existed[i])
+	}
+	rDB.seq = last
+	rDB.routes = append(rDB.routes, routes...)
+	for i := range routes {
+		rDB.index.add(&routes[i])
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package dal
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTxCommit(t *testing.T) {
+	setClock(t, recordedAt)
+	var buf bytes.Buffer
+	routeDB := NewDB(&buf)
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 10})
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF"})
+
+	tx := routeDB.Begin()
+	tx.InsertRoute(Route{Origin: "BRC", Destination: "SCL", Cost: 5})
+	tx.UpdateRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 12})
+	tx.DeleteRoute(Route{Origin: "GRU", Destination: "CDG", Carrier: "AF"})
+
+	// Changes are not visible before the commit
+	if routes := routeDB.GetRoutes(); len(routes) != 2 {
+		t.Errorf("routeDB.GetRoutes expected size 2, got %v", len(routes))
+	}
+
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit error: %v", err)
+	}
+
+	expected := []Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 10, Time: recordedAt},
+		{Origin: "BRC", Destination: "SCL", Cost: 5, Time: recordedAt},
+		{Origin: "GRU", Destination: "BRC", Cost: 12, Time: recordedAt},
+	}
+	if routes := routeDB.GetRoutes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("routeDB.GetRoutes expected %v, got %v", expected, routes)
+	}
+
+	// The stream holds the same routes
+	reloaded := NewDB(bytes.NewBufferString(buf.String()))
+	if routes := reloaded.GetRoutes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("reloaded routes expected %v, got %v", expected, routes)
+	}
+
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("tx.Commit expected %v, got %v", ErrTxDone, err)
+	}
+}
+
+func TestTxCommitNotFound(t *testing.T) {
+	var buf bytes.Buffer
+	routeDB := NewDB(&buf)
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 10})
+	content := buf.String()
+
+	var tests = []struct {
+		name    string
+		changes func(tx *Tx)
+	}{
+		{"UpdateMissing", func(tx *Tx) {
+			tx.InsertRoute(Route{Origin: "BRC", Destination: "SCL", Cost: 5})
+			tx.UpdateRoute(Route{Origin: "GRU", Destination: "CDG", Cost: 75})
+		}},
+		{"UpdateOtherCarrier", func(tx *Tx) {
+			tx.UpdateRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 12, Carrier: "LA"})
+		}},
+		{"DeleteTwice", func(tx *Tx) {
+			tx.DeleteRoute(Route{Origin: "GRU", Destination: "BRC"})
+			tx.DeleteRoute(Route{Origin: "GRU", Destination: "BRC"})
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := routeDB.Begin()
+			tt.changes(tx)
+
+			var notFound *RouteNotFoundError
+			if err := tx.Commit(); !errors.As(err, &notFound) {
+				t.Errorf("tx.Commit expected RouteNotFoundError, got %v", err)
+			}
+			if routes := routeDB.GetRoutes(); len(routes) != 1 {
+				t.Errorf("routeDB.GetRoutes expected size 1, got %v", len(routes))
+			}
+			if buf.String() != content {
+				t.Errorf("stream expected %q, got %q", content, buf.String())
+			}
+		})
+	}
+}
+
+func TestTxRollback(t *testing.T) {
+	var buf bytes.Buffer
+	routeDB := NewDB(&buf)
+
+	tx := routeDB.Begin()
+	tx.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 10})
+	tx.Rollback()
+
+	if err := tx.Commit(); err != ErrTxDone {
+		t.Errorf("tx.Commit expected %v, got %v", ErrTxDone, err)
+	}
+	if routes := routeDB.GetRoutes(); len(routes) != 0 {
+		t.Errorf("routeDB.GetRoutes expected size 0, got %v", len(routes))
+	}
+	if buf.Len() != 0 {
+		t.Errorf("stream expected empty, got %q", buf.String())
+	}
+}
+
+func TestLiveRoutes(t *testing.T) {
+	stored := []Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 10},
+		{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF"},
+		{Origin: "GRU", Destination: "CDG", Cost: 60, Carrier: "LA"},
+		{Origin: "GRU", Destination: "BRC", Cost: 12},
+		{Origin: "GRU", Destination: "BRC", Deleted: true},
+		{Origin: "GRU", Destination: "CDG", Carrier: "AF", Deleted: true},
+		{Origin: "GRU", Destination: "BRC", Cost: 14},
+	}
+	expected := []Route{
+		{Origin: "GRU", Destination: "CDG", Cost: 60, Carrier: "LA"},
+		{Origin: "GRU", Destination: "BRC", Cost: 14},
+	}
+
+	if routes := liveRoutes(stored); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("liveRoutes expected %v, got %v", expected, routes)
+	}
+	if routes := compactRoutes(stored, recordedAt); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("compactRoutes expected %v, got %v", expected, routes)
+	}
+}
+
+func TestRecoveryTruncatesIncompleteTransaction(t *testing.T) {
+	record1 := toRecord(NewRoute("GRU", "BRC", 10), 1)
+	txRecord2 := toTxRecord(NewRoute("BRC", "SCL", 5), 2, 3)
+	path, file := openRoutesFile(t, record1+txRecord2)
+	defer os.RemoveAll(filepath.Dir(path))
+
+	var quarantine bytes.Buffer
+	routeDB := NewDBWithQuarantine(file, &quarantine)
+	file.Close()
+
+	if routes := routeDB.GetRoutes(); len(routes) != 1 {
+		t.Errorf("routeDB.GetRoutes expected size 1, got %v", len(routes))
+	}
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile error: %v", err)
+	}
+	if string(content) != record1 {
+		t.Errorf("file content expected %q, got %q", record1, string(content))
+	}
+	if quarantine.String() != txRecord2 {
+		t.Errorf("quarantine expected %q, got %q", txRecord2, quarantine.String())
+	}
+}
+
+// errWriteFailed is returned by failingWriter
+var errWriteFailed = errors.New("write failed")
+
+// failingWriter fails the write that goes past limit, after writing up to it
+// The writes after the failure succeed
+type failingWriter struct {
+	bytes.Buffer
+	limit int
+}
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if w.limit < 0 || w.Len()+len(p) <= w.limit {
+		return w.Buffer.Write(p)
+	}
+	n, _ := w.Buffer.Write(p[:w.limit-w.Len()])
+	w.limit = -1
+	return n, errWriteFailed
+}
+
+func TestTxCommitWriteFails(t *testing.T) {
+	setClock(t, recordedAt)
+	stream := &failingWriter{limit: -1}
+	routeDB := NewDB(stream)
+	routeDB.InsertRoute(Route{Origin: "GRU", Destination: "BRC", Cost: 10})
+
+	// The first record of the transaction is written whole, the second one torn
+	first := toTxRecord(&Route{Origin: "BRC", Destination: "SCL", Cost: 5, Time: recordedAt}, 2, 3)
+	stream.limit = stream.Len() + len(first) + 5
+	tx := routeDB.Begin()
+	tx.InsertRoute(Route{Origin: "BRC", Destination: "SCL", Cost: 5})
+	tx.InsertRoute(Route{Origin: "SCL", Destination: "ORL", Cost: 20})
+	if err := tx.Commit(); err != errWriteFailed {
+		t.Fatalf("tx.Commit expected %v, got %v", errWriteFailed, err)
+	}
+
+	tx = routeDB.Begin()
+	tx.InsertRoute(Route{Origin: "GRU", Destination: "CDG", Cost: 75})
+	tx.InsertRoute(Route{Origin: "CDG", Destination: "FCO", Cost: 30})
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit error: %v", err)
+	}
+
+	// The committed transaction is not taken for the incomplete one when read
+	expected := []Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 10, Time: recordedAt},
+		{Origin: "GRU", Destination: "CDG", Cost: 75, Time: recordedAt},
+		{Origin: "CDG", Destination: "FCO", Cost: 30, Time: recordedAt},
+	}
+	if routes := routeDB.GetRoutes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("routeDB.GetRoutes expected %v, got %v", expected, routes)
+	}
+	reloaded := NewDB(bytes.NewBufferString(stream.String()))
+	if routes := reloaded.GetRoutes(); !reflect.DeepEqual(routes, expected) {
+		t.Errorf("reloaded routes expected %v, got %v", expected, routes)
+	}
+}
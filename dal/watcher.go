@@ -0,0 +1,76 @@
+package dal
+
+import (
+	"TravelRoute/poller"
+	"log"
+	"os"
+	"time"
+)
+
+// Watcher polls a routes file and reloads the DataBase whenever the file changes on disk
+type Watcher struct {
+	routeDB *DB
+	path    string
+	poller  *poller.Poller
+}
+
+// WatchFile starts polling the routes file at path every interval
+// Changes made by other programs are parsed and swapped into routeDB at once,
+// the writes of routeDB itself are skipped
+// Returns a pointer to the Watcher that can be Stopped latter
+func WatchFile(routeDB *DB, path string, interval time.Duration) *Watcher {
+	w := &Watcher{routeDB: routeDB, path: path}
+	w.poller = poller.Start([]string{path}, interval, func(previous []os.FileInfo, current []os.FileInfo) {
+		w.changed(previous[0], current[0])
+	})
+	return w
+}
+
+// Stop stops polling the file
+func (w *Watcher) Stop() {
+	w.poller.Stop()
+}
+
+// changed reloads the file, changed on disk since it was last seen as previous
+func (w *Watcher) changed(previous os.FileInfo, info os.FileInfo) {
+	// The routes written by the Database are stored already
+	if w.routeDB.ownWrite(info) {
+		return
+	}
+	w.reload(previous != nil && !os.SameFile(info, previous))
+}
+
+// reload parses the file and swaps its routes into the DataBase
+// When the file was replaced, instead of modified in place, new routes are written to the new file
+func (w *Watcher) reload(replaced bool) {
+	file, err := os.OpenFile(w.path, os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		log.Printf("could not open %v: %v", w.path, err)
+		return
+	}
+
+	added, removed, err := w.routeDB.reload(file)
+	if err != nil {
+		log.Printf("could not read %v: %v", w.path, err)
+		file.Close()
+		return
+	}
+
+	if replaced {
+		w.routeDB.replaceStream(file)
+	} else {
+		file.Close()
+	}
+
+	if len(added) == 0 && len(removed) == 0 {
+		return
+	}
+
+	log.Printf("%v reloaded: %v routes added, %v routes removed", w.path, len(added), len(removed))
+	for _, route := range added {
+		log.Printf("+ %v", route)
+	}
+	for _, route := range removed {
+		log.Printf("- %v", route)
+	}
+}
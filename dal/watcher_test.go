@@ -0,0 +1,264 @@
+package dal
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// waitRoutes polls the DataBase until it holds the expected amount of routes
+func waitRoutes(t *testing.T, routeDB *DB, expected int) []Route {
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		routes := routeDB.GetRoutes()
+		if len(routes) == expected {
+			return routes
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("routeDB.GetRoutes expected size %v, got %v", expected, len(routes))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func openRoutesFile(t *testing.T, content string) (string, *os.File) {
+	dir, err := ioutil.TempDir("", "routes")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	path := filepath.Join(dir, "routes.csv")
+	if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		t.Fatalf("os.OpenFile error: %v", err)
+	}
+	return path, file
+}
+
+func TestWatcherReloadsModifiedFile(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\nBRC,SCL,5\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB := NewDB(file)
+	watcher := WatchFile(routeDB, path, 10*time.Millisecond)
+	defer watcher.Stop()
+
+	// Edited by hand, without the last end of line
+	if err := ioutil.WriteFile(path, []byte("GRU,BRC,12\nBRC,SCL,5\nGRU,CDG,75"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+
+	routes := waitRoutes(t, routeDB, 3)
+	expected := []Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 12},
+		{Origin: "BRC", Destination: "SCL", Cost: 5},
+		{Origin: "GRU", Destination: "CDG", Cost: 75},
+	}
+	for i := range expected {
+		if routes[i] != expected[i] {
+			t.Errorf("route expected %v, got %v", expected[i], routes[i])
+		}
+	}
+
+	// New routes keep being written after the reloaded ones, after the sequences taken by their events
+	setClock(t, recordedAt)
+	routeDB.InsertRoute(*NewRoute("CDG", "FCO", 20))
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile error: %v", err)
+	}
+	if string(content) != "GRU,BRC,12\nBRC,SCL,5\nGRU,CDG,75\n"+toRecord(&Route{Origin: "CDG", Destination: "FCO", Cost: 20, Time: recordedAt}, 3) {
+		t.Errorf("file content unexpected: %q", string(content))
+	}
+}
+
+func TestWatcherUnterminatedLine(t *testing.T) {
+	record1 := toRecord(NewRoute("GRU", "BRC", 10), 1)
+	record2 := toRecord(NewRoute("BRC", "SCL", 5), 2)
+
+	var tests = []struct {
+		name               string
+		tail               string
+		expectedRoutes     int
+		expectedContent    string
+		expectedQuarantine string
+	}{
+		{"HandWritten", "GRU,CDG,75", 2, record1 + "GRU,CDG,75\n", ""},
+		{"Torn", record2[:len(record2)-4], 1, record1, record2[:len(record2)-4] + "\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path, file := openRoutesFile(t, record1)
+			defer os.RemoveAll(filepath.Dir(path))
+
+			var quarantine bytes.Buffer
+			routeDB := NewDBWithQuarantine(file, &quarantine)
+			watcher := WatchFile(routeDB, path, 10*time.Millisecond)
+
+			f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+			if err != nil {
+				t.Fatalf("os.OpenFile error: %v", err)
+			}
+			f.WriteString(tt.tail)
+			f.Close()
+
+			deadline := time.Now().Add(2 * time.Second)
+			for {
+				content, _ := ioutil.ReadFile(path)
+				if string(content) == tt.expectedContent {
+					break
+				}
+				if time.Now().After(deadline) {
+					t.Fatalf("file content expected %q, got %q", tt.expectedContent, string(content))
+				}
+				time.Sleep(5 * time.Millisecond)
+			}
+			watcher.Stop()
+
+			if routes := routeDB.GetRoutes(); len(routes) != tt.expectedRoutes {
+				t.Errorf("routeDB.GetRoutes expected size %v, got %v", tt.expectedRoutes, len(routes))
+			}
+			if quarantine.String() != tt.expectedQuarantine {
+				t.Errorf("quarantine expected %q, got %q", tt.expectedQuarantine, quarantine.String())
+			}
+
+			// Loading the same file on startup keeps or drops the line the same way
+			restarted := NewDB(bytes.NewBufferString(record1 + tt.tail))
+			if routes := restarted.GetRoutes(); len(routes) != tt.expectedRoutes {
+				t.Errorf("restarted.GetRoutes expected size %v, got %v", tt.expectedRoutes, len(routes))
+			}
+		})
+	}
+}
+
+func TestWatcherReloadsReplacedFile(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB := NewDB(file)
+	watcher := WatchFile(routeDB, path, 10*time.Millisecond)
+	defer watcher.Stop()
+
+	// Editors often write a new file and rename it over the old one
+	newPath := path + ".new"
+	if err := ioutil.WriteFile(newPath, []byte("GRU,BRC,10\nBRC,SCL,5\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+	if err := os.Rename(newPath, path); err != nil {
+		t.Fatalf("os.Rename error: %v", err)
+	}
+
+	waitRoutes(t, routeDB, 2)
+
+	setClock(t, recordedAt)
+	routeDB.InsertRoute(*NewRoute("SCL", "ORL", 20))
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ioutil.ReadFile error: %v", err)
+	}
+	if string(content) != "GRU,BRC,10\nBRC,SCL,5\n"+toRecord(&Route{Origin: "SCL", Destination: "ORL", Cost: 20, Time: recordedAt}, 2) {
+		t.Errorf("file content unexpected: %q", string(content))
+	}
+}
+
+func TestWatcherSkipsOwnWrites(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB := NewDB(file)
+	watcher := WatchFile(routeDB, path, 10*time.Millisecond)
+	defer watcher.Stop()
+
+	index := routeDB.index
+	routeDB.InsertRoute(*NewRoute("BRC", "SCL", 5))
+	tx := routeDB.Begin()
+	tx.InsertRoute(*NewRoute("SCL", "ORL", 20))
+	if err := tx.Commit(); err != nil {
+		t.Fatalf("tx.Commit unexpected error: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	// A reload would have rebuilt the index
+	routeDB.mutex.RLock()
+	reloaded := routeDB.index != index
+	routeDB.mutex.RUnlock()
+	if reloaded {
+		t.Errorf("routes written by the DataBase expected not to be reloaded")
+	}
+
+	// Changes made by other programs are still reloaded
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatalf("os.OpenFile error: %v", err)
+	}
+	f.WriteString("ORL,CDG,5\n")
+	f.Close()
+	waitRoutes(t, routeDB, 4)
+}
+
+func TestWatcherPublishesChanges(t *testing.T) {
+	path, file := openRoutesFile(t, "GRU,BRC,10\nBRC,SCL,5\n")
+	defer os.RemoveAll(filepath.Dir(path))
+
+	routeDB := NewDB(file)
+	s, _, err := routeDB.Subscribe(0)
+	if err != nil {
+		t.Fatalf("routeDB.Subscribe error: %v", err)
+	}
+	defer s.Close()
+	watcher := WatchFile(routeDB, path, 10*time.Millisecond)
+	defer watcher.Stop()
+
+	if err := ioutil.WriteFile(path, []byte("GRU,BRC,12\nGRU,CDG,75\n"), 0644); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+
+	expected := []Event{
+		{1, EventUpdated, Route{Origin: "GRU", Destination: "BRC", Cost: 12}},
+		{2, EventInserted, Route{Origin: "GRU", Destination: "CDG", Cost: 75}},
+		{3, EventDeleted, Route{Origin: "BRC", Destination: "SCL", Deleted: true}},
+	}
+	for _, e := range expected {
+		select {
+		case event := <-s.Events():
+			if event != e {
+				t.Errorf("event expected %v, got %v", e, event)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("event expected %v, got none", e)
+		}
+	}
+
+	// Routes written next keep the sequence growing
+	routeDB.InsertRoute(*NewRoute("CDG", "FCO", 20))
+	if event := <-s.Events(); event.ID != 4 {
+		t.Errorf("event expected ID 4, got %v", event)
+	}
+}
+
+func TestDiffRoutes(t *testing.T) {
+	oldRoutes := []Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 10},
+		{Origin: "BRC", Destination: "SCL", Cost: 5},
+		{Origin: "BRC", Destination: "SCL", Cost: 5},
+	}
+	newRoutes := []Route{
+		{Origin: "BRC", Destination: "SCL", Cost: 5},
+		{Origin: "GRU", Destination: "CDG", Cost: 75},
+	}
+
+	added, removed := diffRoutes(oldRoutes, newRoutes)
+	if len(added) != 1 || added[0] != newRoutes[1] {
+		t.Errorf("diffRoutes expected added %v, got %v", newRoutes[1:], added)
+	}
+	if len(removed) != 2 || removed[0] != oldRoutes[0] || removed[1] != oldRoutes[1] {
+		t.Errorf("diffRoutes expected removed %v, got %v", oldRoutes[:2], removed)
+	}
+}
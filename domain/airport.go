@@ -0,0 +1,60 @@
+package domain
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/dal"
+)
+
+// Airport defines the routes connecting an airport
+type Airport struct {
+	Code string
+	// Outbound holds the routes leaving the airport and Inbound the ones arriving at it
+	// Bidirectional routes are listed on both directions, reversed when needed
+	Outbound []dal.Route
+	Inbound  []dal.Route
+}
+
+// AirportDegree defines how many routes leave and arrive at an airport
+type AirportDegree struct {
+	Code      string
+	OutDegree int
+	InDegree  int
+}
+
+// ListAirports lists every airport connected by the routes, ordered by code
+// Bidirectional routes are counted on both directions
+func ListAirports(routes []dal.Route) []AirportDegree {
+	routeGraph := buildGraph(routes)
+	labels := routeGraph.Labels()
+	airports := make([]AirportDegree, len(labels))
+	for i, code := range labels {
+		out, in := routeGraph.Degree(code)
+		airports[i] = AirportDegree{code, out, in}
+	}
+	return airports
+}
+
+// FindAirport finds the routes connecting the airport
+// Returns false in case no route connects it
+func FindAirport(routes []dal.Route, code string) (Airport, bool) {
+	routeGraph := buildGraph(routes)
+	if !routeGraph.HasNode(code) {
+		return Airport{}, false
+	}
+
+	legRoutes := graphLegs(routes)
+	return Airport{
+		Code:     code,
+		Outbound: edgeRoutes(legRoutes, routeGraph.Outbound(code)),
+		Inbound:  edgeRoutes(legRoutes, routeGraph.Inbound(code)),
+	}, true
+}
+
+// edgeRoutes finds the stored route of each graph edge
+func edgeRoutes(legRoutes map[legKey]dal.Route, edges []algorithm.Edge) []dal.Route {
+	routes := make([]dal.Route, len(edges))
+	for i, e := range edges {
+		routes[i] = legRoutes[legKey{e.Origin, e.Destination, e.Leg}]
+	}
+	return routes
+}
@@ -0,0 +1,61 @@
+package domain
+
+import (
+	"TravelRoute/dal"
+	"reflect"
+	"testing"
+)
+
+func TestFindAirport(t *testing.T) {
+	routes := []dal.Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 10, Carrier: "LA"},
+		{Origin: "GRU", Destination: "BRC", Cost: 12, Carrier: "G3"},
+		{Origin: "BRC", Destination: "SCL", Cost: 5, Bidirectional: true},
+		{Origin: "GRU", Destination: "CDG", Cost: 75},
+	}
+
+	var tests = []struct {
+		code     string
+		found    bool
+		outbound []dal.Route
+		inbound  []dal.Route
+	}{
+		{"GRU", true, []dal.Route{routes[1], routes[0], routes[3]}, []dal.Route{}},
+		{"BRC", true, []dal.Route{routes[2]}, []dal.Route{routes[1], routes[0],
+			{Origin: "SCL", Destination: "BRC", Cost: 5, Bidirectional: true}}},
+		{"SCL", true, []dal.Route{{Origin: "SCL", Destination: "BRC", Cost: 5, Bidirectional: true}}, []dal.Route{routes[2]}},
+		{"FCO", false, nil, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			airport, found := FindAirport(routes, tt.code)
+			if found != tt.found {
+				t.Fatalf("FindAirport expected found %v, got %v", tt.found, found)
+			}
+			if !found {
+				return
+			}
+			if !reflect.DeepEqual(airport, Airport{tt.code, tt.outbound, tt.inbound}) {
+				t.Errorf("FindAirport expected %v %v, got %v %v", tt.outbound, tt.inbound, airport.Outbound, airport.Inbound)
+			}
+		})
+	}
+}
+
+func TestListAirports(t *testing.T) {
+	routes := []dal.Route{
+		{Origin: "GRU", Destination: "BRC", Cost: 10, Carrier: "LA"},
+		{Origin: "GRU", Destination: "BRC", Cost: 12, Carrier: "G3"},
+		{Origin: "BRC", Destination: "SCL", Cost: 5, Bidirectional: true},
+		{Origin: "GRU", Destination: "CDG", Cost: 75},
+	}
+
+	expected := []AirportDegree{{"BRC", 1, 3}, {"CDG", 0, 1}, {"GRU", 3, 0}, {"SCL", 1, 1}}
+	if airports := ListAirports(routes); !reflect.DeepEqual(airports, expected) {
+		t.Errorf("ListAirports expected %v, got %v", expected, airports)
+	}
+	if airports := ListAirports([]dal.Route{}); len(airports) != 0 {
+		t.Errorf("ListAirports expected [], got %v", airports)
+	}
+}
@@ -0,0 +1,62 @@
+package domain
+
+import (
+	"TravelRoute/dal"
+	"errors"
+	"fmt"
+)
+
+// ErrNotEnoughStops is returned when an itinerary has less than 2 stops
+var ErrNotEnoughStops = errors.New("an itinerary needs at least 2 stops")
+
+// UnreachableError is returned when there is no route between two consecutive stops
+type UnreachableError struct {
+	Origin      string
+	Destination string
+}
+
+func (e *UnreachableError) Error() string {
+	return fmt.Sprintf("no route from %v to %v", e.Origin, e.Destination)
+}
+
+// Segment defines the cheapest route between two consecutive stops of an itinerary
+type Segment struct {
+	Origin      string
+	Destination string
+	Route       []string
+	Cost        float32
+}
+
+// FindItinerary finds the cheapest route for each segment of an ordered list of stops
+// Returns the segments and the total cost
+// Returns an error if there are less than 2 stops or if any segment is unreachable
+// Returns an algorithm.NegativeCycleError in case a negative cycle is reachable from any stop
+func FindItinerary(routes []dal.Route, stops []string) ([]Segment, float32, error) {
+	if len(stops) < 2 {
+		return nil, 0, ErrNotEnoughStops
+	}
+
+	routeGraph := buildGraph(routes)
+	// Bellman-Ford reports negative cycles reachable from any stop
+	if routeGraph.HasNegativeWeights() {
+		for i := 1; i < len(stops); i++ {
+			if _, _, err := routeGraph.BellmanFord(stops[i-1], stops[i]); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+
+	segments := make([]Segment, 0, len(stops)-1)
+	var total float32
+	for i := 1; i < len(stops); i++ {
+		origin, destination := stops[i-1], stops[i]
+		route, cost := routeGraph.ShortestPath(origin, destination)
+		if len(route) == 0 {
+			return nil, 0, &UnreachableError{origin, destination}
+		}
+		segments = append(segments, Segment{origin, destination, route, cost})
+		total += cost
+	}
+
+	return segments, total, nil
+}
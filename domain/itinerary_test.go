@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/dal"
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFindItinerary(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", 5))
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "CDG", 75))
+	routeDB.InsertRoute(*dal.NewRoute("CDG", "FCO", 30))
+	routeDB.InsertRoute(*dal.NewRoute("FCO", "GRU", 80))
+
+	segments, cost, err := FindItinerary(routeDB.GetRoutes(), []string{"GRU", "CDG", "FCO", "GRU"})
+	if err != nil {
+		t.Fatalf("FindItinerary unexpected error: %v", err)
+	}
+
+	expected := []Segment{
+		{"GRU", "CDG", []string{"GRU", "CDG"}, 75},
+		{"CDG", "FCO", []string{"CDG", "FCO"}, 30},
+		{"FCO", "GRU", []string{"FCO", "GRU"}, 80},
+	}
+	if len(segments) != len(expected) {
+		t.Fatalf("FindItinerary expected %v segments, got %v", len(expected), len(segments))
+	}
+	for i, segment := range segments {
+		if segment.Origin != expected[i].Origin || segment.Destination != expected[i].Destination ||
+			segment.Cost != expected[i].Cost || len(segment.Route) != len(expected[i].Route) {
+			t.Errorf("FindItinerary expected segment %v, got %v", expected[i], segment)
+		}
+	}
+
+	if cost != 185 {
+		t.Errorf("FindItinerary expected cost %v, got %v", 185, cost)
+	}
+}
+
+func TestFindItineraryErrors(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", 5))
+
+	_, _, err := FindItinerary(routeDB.GetRoutes(), []string{"GRU"})
+	if err != ErrNotEnoughStops {
+		t.Errorf("FindItinerary expected error %v, got %v", ErrNotEnoughStops, err)
+	}
+
+	_, _, err = FindItinerary(routeDB.GetRoutes(), []string{"GRU", "SCL", "BRC"})
+	var unreachable *UnreachableError
+	if !errors.As(err, &unreachable) {
+		t.Fatalf("FindItinerary expected UnreachableError, got %v", err)
+	}
+	if unreachable.Origin != "SCL" || unreachable.Destination != "BRC" {
+		t.Errorf("UnreachableError expected %v -> %v, got %v -> %v", "SCL", "BRC", unreachable.Origin, unreachable.Destination)
+	}
+}
+
+func TestFindItineraryNegativeCycle(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", -5))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "BRC", 2))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "CDG", 1))
+
+	_, _, err := FindItinerary(routeDB.GetRoutes(), []string{"GRU", "CDG"})
+	var cycleErr *algorithm.NegativeCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Errorf("FindItinerary expected NegativeCycleError, got %v", err)
+	}
+}
@@ -0,0 +1,16 @@
+package domain
+
+import "TravelRoute/dal"
+
+// FindCostMatrix finds the cheapest cost between every pair of airports
+// When no airport is given every airport in routes is used
+// Returns the airports and the cost matrix, where +Inf means there is no route
+// Returns an algorithm.NegativeCycleError in case the routes have a negative cycle
+func FindCostMatrix(routes []dal.Route, airports []string) ([]string, [][]float32, error) {
+	routeGraph := buildGraph(routes)
+	if len(airports) == 0 {
+		airports = routeGraph.Labels()
+	}
+	costs, err := routeGraph.AllPairsCosts(airports)
+	return airports, costs, err
+}
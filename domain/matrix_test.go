@@ -0,0 +1,65 @@
+package domain
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/dal"
+	"bytes"
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestFindCostMatrix(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", 5))
+
+	inf := float32(math.Inf(1))
+	var tests = []struct {
+		name             string
+		airports         []string
+		expectedAirports []string
+		expectedCosts    [][]float32
+	}{
+		{"Requested", []string{"SCL", "GRU"}, []string{"SCL", "GRU"}, [][]float32{{0, inf}, {15, 0}}},
+		{"All", []string{}, []string{"BRC", "GRU", "SCL"}, [][]float32{{0, inf, 5}, {10, 0, 15}, {inf, inf, 0}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			airports, costs, err := FindCostMatrix(routeDB.GetRoutes(), tt.airports)
+			if err != nil {
+				t.Fatalf("FindCostMatrix unexpected error: %v", err)
+			}
+			if len(airports) != len(tt.expectedAirports) {
+				t.Fatalf("FindCostMatrix expected airports %v, got %v", tt.expectedAirports, airports)
+			}
+			for i := range airports {
+				if airports[i] != tt.expectedAirports[i] {
+					t.Errorf("FindCostMatrix expected airports %v, got %v", tt.expectedAirports, airports)
+				}
+				for j := range airports {
+					if costs[i][j] != tt.expectedCosts[i][j] {
+						t.Errorf("FindCostMatrix expected costs %v, got %v", tt.expectedCosts, costs)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFindCostMatrixNegativeCycle(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 1))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", -5))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "BRC", 1))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "CDG", 1))
+
+	_, _, err := FindCostMatrix(routeDB.GetRoutes(), []string{"GRU", "CDG"})
+	var cycleErr *algorithm.NegativeCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Errorf("FindCostMatrix expected NegativeCycleError, got %v", err)
+	}
+}
@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"TravelRoute/dal"
+	"sort"
+)
+
+// Reachable defines the cheapest route from an origin to a reachable destination
+type Reachable struct {
+	Destination string
+	Route       []string
+	Cost        float32
+}
+
+// FindReachable finds every destination reachable from origin costing at most maxCost
+// Returns the destinations ordered by cost
+// Returns an algorithm.NegativeCycleError in case a negative cycle is reachable from origin
+func FindReachable(routes []dal.Route, origin string, maxCost float32) ([]Reachable, error) {
+	treeRoutes, treeCosts, err := buildGraph(routes).ShortestPathTree(origin)
+	if err != nil {
+		return nil, err
+	}
+
+	reachable := make([]Reachable, 0, len(treeRoutes))
+	for destination, route := range treeRoutes {
+		if treeCosts[destination] <= maxCost {
+			reachable = append(reachable, Reachable{destination, route, treeCosts[destination]})
+		}
+	}
+
+	sort.Slice(reachable, func(i, j int) bool {
+		if reachable[i].Cost != reachable[j].Cost {
+			return reachable[i].Cost < reachable[j].Cost
+		}
+		return reachable[i].Destination < reachable[j].Destination
+	})
+	return reachable, nil
+}
@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"math"
+	"testing"
+)
+
+func TestFindReachable(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", 5))
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "CDG", 75))
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "ORL", 15))
+
+	var tests = []struct {
+		name                 string
+		maxCost              float32
+		expectedDestinations []string
+	}{
+		{"Unlimited", float32(math.Inf(1)), []string{"BRC", "ORL", "SCL", "CDG"}},
+		{"Budget", 15, []string{"BRC", "ORL", "SCL"}},
+		{"Nothing", 5, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reachable, err := FindReachable(routeDB.GetRoutes(), "GRU", tt.maxCost)
+			if err != nil {
+				t.Fatalf("FindReachable unexpected error: %v", err)
+			}
+			if len(reachable) != len(tt.expectedDestinations) {
+				t.Fatalf("FindReachable expected %v destinations, got %v", len(tt.expectedDestinations), reachable)
+			}
+			for i := range reachable {
+				if reachable[i].Destination != tt.expectedDestinations[i] {
+					t.Errorf("FindReachable expected %v, got %v", tt.expectedDestinations[i], reachable[i].Destination)
+				}
+			}
+		})
+	}
+}
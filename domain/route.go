@@ -5,13 +5,159 @@ import (
 	"TravelRoute/dal"
 )
 
+// BestRoute defines the cheapest route between two airports
+type BestRoute struct {
+	Route []string
+	// Legs holds the stored route used on each hop of Route
+	Legs []dal.Route
+	Cost float32
+}
+
+// SearchOptions restricts the routes a search may use
+type SearchOptions struct {
+	// IncludeCarriers, when not empty, allows only routes flown by these carriers
+	IncludeCarriers []string
+	// ExcludeCarriers forbids routes flown by these carriers
+	ExcludeCarriers []string
+	// Avoid forbids going through these airports
+	Avoid []string
+	// Via forces stopping at these airports, in order
+	Via []string
+}
+
 // FindCheapestRoute Finds the shortest (cheapest) route between origin and destination in routes
 // Returns the list of node labels and the total cost
 // Return an empty slice and 0 in case there is no route
-func FindCheapestRoute(routes []dal.Route, origin string, destination string) ([]string, float32) {
+// Routes with negative costs are searched by Bellman-Ford, which returns an
+// algorithm.NegativeCycleError in case a negative cycle is reachable from origin
+func FindCheapestRoute(routes []dal.Route, origin string, destination string) ([]string, float32, error) {
+	best, err := FindBestRoute(routes, origin, destination, SearchOptions{})
+	return best.Route, best.Cost, err
+}
+
+// FindBestRoute finds the cheapest route between origin and destination in routes
+// using only the routes allowed by options,
+// along with the leg used on each hop, when several routes connect the same airports
+// Returns empty slices and 0 in case there is no route
+// Returns an algorithm.NegativeCycleError in case a negative cycle is reachable from origin
+func FindBestRoute(routes []dal.Route, origin string, destination string, options SearchOptions) (BestRoute, error) {
+	return NewRouteNetwork(routes).FindBestRoute(origin, destination, options)
+}
+
+// RouteNetwork holds the graph of the routes, so several searches over the same routes build it once
+type RouteNetwork struct {
+	routes    []dal.Route
+	graph     *algorithm.Graph
+	legRoutes map[legKey]dal.Route
+}
+
+// NewRouteNetwork builds the graph of the routes
+func NewRouteNetwork(routes []dal.Route) *RouteNetwork {
+	return &RouteNetwork{routes, buildGraph(routes), graphLegs(routes)}
+}
+
+// FindBestRoute finds the cheapest route between origin and destination, as FindBestRoute does
+func (n *RouteNetwork) FindBestRoute(origin string, destination string, options SearchOptions) (BestRoute, error) {
+	routeGraph := n.graph
+	filters := options.filters(n.routes)
+
+	// Bellman-Ford reports negative cycles reachable from any stop
+	if routeGraph.HasNegativeWeights() {
+		stops := append(append([]string{origin}, options.Via...), destination)
+		for i := 1; i < len(stops); i++ {
+			if _, _, err := routeGraph.BellmanFord(stops[i-1], stops[i], filters...); err != nil {
+				return BestRoute{Route: make([]string, 0), Legs: make([]dal.Route, 0)}, err
+			}
+		}
+	}
+
+	route, cost := routeGraph.ShortestPathVia(origin, destination, options.Via, filters...)
+	legs := routeGraph.PathLegs(route, filters...)
+	return BestRoute{Route: route, Legs: findLegs(n.legRoutes, route, legs), Cost: cost}, nil
+}
+
+// filters builds the graph leg filters for the options
+func (options *SearchOptions) filters(routes []dal.Route) []algorithm.LegFilter {
+	filters := make([]algorithm.LegFilter, 0)
+	if len(options.Avoid) != 0 {
+		filters = append(filters, algorithm.AvoidNodes(options.Avoid...))
+	}
+	if len(options.IncludeCarriers) == 0 && len(options.ExcludeCarriers) == 0 {
+		return filters
+	}
+
+	legRoutes := graphLegs(routes)
+	if len(options.IncludeCarriers) != 0 {
+		included := toSet(options.IncludeCarriers)
+		filters = append(filters, func(origin string, destination string, leg string) bool {
+			return included[legRoutes[legKey{origin, destination, leg}].Carrier]
+		})
+	}
+	if len(options.ExcludeCarriers) != 0 {
+		excluded := toSet(options.ExcludeCarriers)
+		filters = append(filters, func(origin string, destination string, leg string) bool {
+			return !excluded[legRoutes[legKey{origin, destination, leg}].Carrier]
+		})
+	}
+	return filters
+}
+
+// toSet transforms the values into a set
+func toSet(values []string) map[string]bool {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return set
+}
+
+// legKey identifies a route among the ones connecting the same airports
+type legKey struct {
+	origin      string
+	destination string
+	leg         string
+}
+
+// buildGraph builds the route graph from the routes
+// Routes connecting the same airports are kept as distinct legs
+// Bidirectional routes are expanded into a leg on each direction
+func buildGraph(routes []dal.Route) *algorithm.Graph {
 	routeGraph := algorithm.NewGraph()
 	for _, r := range routes {
-		routeGraph.Connect(r.Origin, r.Destination, r.Cost)
+		routeGraph.ConnectLeg(r.Origin, r.Destination, routeLeg(&r), r.Cost)
+		if r.Bidirectional {
+			routeGraph.ConnectLeg(r.Destination, r.Origin, routeLeg(&r), r.Cost)
+		}
+	}
+	return routeGraph
+}
+
+// routeLeg names the graph leg of the route
+func routeLeg(r *dal.Route) string {
+	return r.Carrier + "/" + r.FareID
+}
+
+// graphLegs maps every graph leg to the route it came from
+// The reverse leg of a bidirectional route maps to a reversed copy of the route
+// The last route of a leg is the one in the graph
+func graphLegs(routes []dal.Route) map[legKey]dal.Route {
+	legRoutes := make(map[legKey]dal.Route)
+	for _, r := range routes {
+		legRoutes[legKey{r.Origin, r.Destination, routeLeg(&r)}] = r
+		if r.Bidirectional {
+			reverse := r
+			reverse.Origin, reverse.Destination = r.Destination, r.Origin
+			legRoutes[legKey{reverse.Origin, reverse.Destination, routeLeg(&reverse)}] = reverse
+		}
+	}
+	return legRoutes
+}
+
+// findLegs finds the stored route of each hop of the path given the graph legs
+func findLegs(legRoutes map[legKey]dal.Route, path []string, legs []string) []dal.Route {
+	found := make([]dal.Route, len(legs))
+	for i, leg := range legs {
+		found[i] = legRoutes[legKey{path[i], path[i+1], leg}]
 	}
-	return routeGraph.ShortestPath(origin, destination)
+	return found
 }
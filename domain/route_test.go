@@ -1,9 +1,12 @@
 package domain
 
 import (
+	"TravelRoute/algorithm"
 	"TravelRoute/dal"
 	"bytes"
+	"errors"
 	"testing"
+	"time"
 )
 
 func TestGraphShortestPath(t *testing.T) {
@@ -25,7 +28,10 @@ func TestGraphShortestPath(t *testing.T) {
 	}
 
 	for _, test := range tests {
-		route, cost := FindCheapestRoute(routeDB.GetRoutes(), test.origin, test.destination)
+		route, cost, err := FindCheapestRoute(routeDB.GetRoutes(), test.origin, test.destination)
+		if err != nil {
+			t.Fatalf("FindCheapestRoute unexpected error: %v", err)
+		}
 		for i := 0; i < len(test.expectedRoute); i++ {
 			if route[i] != test.expectedRoute[i] {
 				t.Errorf("FindCheapestRoute expected route %v, got %v", test.expectedRoute, route)
@@ -38,3 +44,192 @@ func TestGraphShortestPath(t *testing.T) {
 	}
 
 }
+
+func TestFindCheapestRouteNegativeCycle(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", -5))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "BRC", 2))
+
+	route, cost, err := FindCheapestRoute(routeDB.GetRoutes(), "GRU", "SCL")
+	var cycleErr *algorithm.NegativeCycleError
+	if !errors.As(err, &cycleErr) {
+		t.Fatalf("FindCheapestRoute expected NegativeCycleError, got %v", err)
+	}
+	if len(route) != 0 || cost != 0 {
+		t.Errorf("FindCheapestRoute expected no route, got %v %v", route, cost)
+	}
+}
+
+func TestFindBestRouteLegs(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	cheap := dal.NewRoute("GRU", "CDG", 60)
+	cheap.FareID = "LA8084"
+	expensive := dal.NewRoute("GRU", "CDG", 75)
+	expensive.FareID = "AF457"
+	routeDB.InsertRoute(*cheap)
+	routeDB.InsertRoute(*expensive)
+	routeDB.InsertRoute(*dal.NewRoute("CDG", "FCO", 20))
+
+	best, err := FindBestRoute(routeDB.GetRoutes(), "GRU", "FCO", SearchOptions{})
+	if err != nil {
+		t.Fatalf("FindBestRoute unexpected error: %v", err)
+	}
+
+	if best.Cost != 80 {
+		t.Errorf("FindBestRoute expected cost %v, got %v", 80, best.Cost)
+	}
+
+	expectedLegs := []dal.Route{*cheap, *dal.NewRoute("CDG", "FCO", 20)}
+	if len(best.Legs) != len(expectedLegs) {
+		t.Fatalf("FindBestRoute expected legs %v, got %v", expectedLegs, best.Legs)
+	}
+	for i := range expectedLegs {
+		// Legs keep the time they were recorded at
+		best.Legs[i].Time = time.Time{}
+		if best.Legs[i] != expectedLegs[i] {
+			t.Errorf("FindBestRoute expected leg %v, got %v", expectedLegs[i], best.Legs[i])
+		}
+	}
+
+	best, err = FindBestRoute(routeDB.GetRoutes(), "FCO", "GRU", SearchOptions{})
+	if err != nil || len(best.Route) != 0 || len(best.Legs) != 0 {
+		t.Errorf("FindBestRoute expected no route, got %v %v", best, err)
+	}
+}
+
+func TestFindBestRouteCarriers(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(dal.Route{Origin: "GRU", Destination: "CDG", Cost: 75, Carrier: "AF"})
+	routeDB.InsertRoute(dal.Route{Origin: "GRU", Destination: "CDG", Cost: 60, Carrier: "LA"})
+	routeDB.InsertRoute(dal.Route{Origin: "GRU", Destination: "SCL", Cost: 10, Carrier: "JJ"})
+	routeDB.InsertRoute(dal.Route{Origin: "SCL", Destination: "CDG", Cost: 30, Carrier: "LA"})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "CDG", 90))
+
+	var tests = []struct {
+		name          string
+		options       SearchOptions
+		expectedRoute []string
+		expectedCost  float32
+	}{
+		{"NoFilter", SearchOptions{}, []string{"GRU", "SCL", "CDG"}, 40},
+		{"ExcludeJJ", SearchOptions{ExcludeCarriers: []string{"JJ"}}, []string{"GRU", "CDG"}, 60},
+		{"ExcludeJJAndLA", SearchOptions{ExcludeCarriers: []string{"JJ", "LA"}}, []string{"GRU", "CDG"}, 75},
+		{"IncludeLA", SearchOptions{IncludeCarriers: []string{"LA"}}, []string{"GRU", "CDG"}, 60},
+		{"IncludeLAExcludeLA", SearchOptions{IncludeCarriers: []string{"LA"}, ExcludeCarriers: []string{"LA"}}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best, err := FindBestRoute(routeDB.GetRoutes(), "GRU", "CDG", tt.options)
+			if err != nil {
+				t.Fatalf("FindBestRoute unexpected error: %v", err)
+			}
+			if len(best.Route) != len(tt.expectedRoute) || best.Cost != tt.expectedCost {
+				t.Fatalf("FindBestRoute expected %v %v, got %v %v", tt.expectedRoute, tt.expectedCost, best.Route, best.Cost)
+			}
+			for i := range best.Route {
+				if best.Route[i] != tt.expectedRoute[i] {
+					t.Errorf("FindBestRoute expected route %v, got %v", tt.expectedRoute, best.Route)
+				}
+			}
+			for _, leg := range best.Legs {
+				for _, excluded := range tt.options.ExcludeCarriers {
+					if leg.Carrier == excluded {
+						t.Errorf("FindBestRoute used excluded leg %v", leg)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestFindBestRouteAvoidVia(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", 5))
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "CDG", 75))
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "SCL", 20))
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "ORL", 56))
+	routeDB.InsertRoute(*dal.NewRoute("ORL", "CDG", 5))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "ORL", 20))
+
+	var tests = []struct {
+		name          string
+		options       SearchOptions
+		expectedRoute []string
+		expectedCost  float32
+	}{
+		{"AvoidORL", SearchOptions{Avoid: []string{"ORL"}}, []string{"GRU", "CDG"}, 75},
+		{"ViaSCL", SearchOptions{Via: []string{"SCL"}}, []string{"GRU", "BRC", "SCL", "ORL", "CDG"}, 40},
+		{"ViaSCLAvoidBRC", SearchOptions{Via: []string{"SCL"}, Avoid: []string{"BRC"}}, []string{"GRU", "SCL", "ORL", "CDG"}, 45},
+		{"ViaORLAvoidORL", SearchOptions{Via: []string{"ORL"}, Avoid: []string{"ORL"}}, []string{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			best, err := FindBestRoute(routeDB.GetRoutes(), "GRU", "CDG", tt.options)
+			if err != nil {
+				t.Fatalf("FindBestRoute unexpected error: %v", err)
+			}
+			if len(best.Route) != len(tt.expectedRoute) || best.Cost != tt.expectedCost {
+				t.Fatalf("FindBestRoute expected %v %v, got %v %v", tt.expectedRoute, tt.expectedCost, best.Route, best.Cost)
+			}
+			for i := range best.Route {
+				if best.Route[i] != tt.expectedRoute[i] {
+					t.Errorf("FindBestRoute expected route %v, got %v", tt.expectedRoute, best.Route)
+				}
+			}
+			if len(best.Route) != 0 && len(best.Legs) != len(best.Route)-1 {
+				t.Errorf("FindBestRoute expected %v legs, got %v", len(best.Route)-1, best.Legs)
+			}
+		})
+	}
+}
+
+func TestFindBestRouteBidirectional(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(dal.Route{Origin: "GRU", Destination: "SCL", Cost: 20, Carrier: "LA", Bidirectional: true})
+	routeDB.InsertRoute(dal.Route{Origin: "CDG", Destination: "SCL", Cost: 60, Carrier: "AF", Bidirectional: true})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "CDG", 100))
+
+	best, err := FindBestRoute(routeDB.GetRoutes(), "CDG", "GRU", SearchOptions{})
+	if err != nil {
+		t.Fatalf("FindBestRoute unexpected error: %v", err)
+	}
+	if best.Cost != 80 {
+		t.Errorf("FindBestRoute expected cost %v, got %v", 80, best.Cost)
+	}
+
+	expectedLegs := []dal.Route{
+		{Origin: "CDG", Destination: "SCL", Cost: 60, Carrier: "AF", Bidirectional: true},
+		{Origin: "SCL", Destination: "GRU", Cost: 20, Carrier: "LA", Bidirectional: true},
+	}
+	if len(best.Legs) != len(expectedLegs) {
+		t.Fatalf("FindBestRoute expected legs %v, got %v", expectedLegs, best.Legs)
+	}
+	for i := range expectedLegs {
+		// Legs keep the time they were recorded at
+		best.Legs[i].Time = time.Time{}
+		if best.Legs[i] != expectedLegs[i] {
+			t.Errorf("FindBestRoute expected leg %v, got %v", expectedLegs[i], best.Legs[i])
+		}
+	}
+
+	// Filters apply to the reverse leg as well
+	best, err = FindBestRoute(routeDB.GetRoutes(), "SCL", "GRU", SearchOptions{ExcludeCarriers: []string{"LA"}})
+	if err != nil || len(best.Route) != 0 {
+		t.Errorf("FindBestRoute expected no route, got %v %v", best.Route, err)
+	}
+
+	// One way routes stay one way
+	best, err = FindBestRoute(routeDB.GetRoutes(), "CDG", "GRU", SearchOptions{Avoid: []string{"SCL"}})
+	if err != nil || len(best.Route) != 0 {
+		t.Errorf("FindBestRoute expected no route, got %v %v", best.Route, err)
+	}
+}
@@ -0,0 +1,54 @@
+package domain
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/dal"
+	"errors"
+	"fmt"
+)
+
+// MaxTourCities is the biggest amount of distinct cities a tour visits
+// Each pair of cities is searched for its cheapest route, so the work grows with the square of the amount
+const MaxTourCities = 30
+
+// ErrNotEnoughCities is returned when a tour has less than 2 distinct cities
+var ErrNotEnoughCities = errors.New("a tour needs at least 2 distinct cities")
+
+// ErrTooManyCities is returned when a tour has more than MaxTourCities distinct cities
+var ErrTooManyCities = fmt.Errorf("a tour visits at most %v distinct cities", MaxTourCities)
+
+// ErrNoTour is returned when there is no tour visiting every city
+var ErrNoTour = errors.New("no tour visits every city")
+
+// FindCheapestTour finds the cheapest order to visit every city, starting from the first one
+// When roundTrip is set the tour goes back to the first city in the end
+// Returns an error if there are less than 2 or more than MaxTourCities distinct cities, or if there is no tour
+// Returns an algorithm.NegativeCycleError in case a negative cycle is reachable from any city
+func FindCheapestTour(routes []dal.Route, cities []string, roundTrip bool) (algorithm.Tour, error) {
+	distinct := make(map[string]bool)
+	for _, city := range cities {
+		distinct[city] = true
+	}
+	if len(distinct) < 2 {
+		return algorithm.Tour{}, ErrNotEnoughCities
+	}
+	if len(distinct) > MaxTourCities {
+		return algorithm.Tour{}, ErrTooManyCities
+	}
+
+	routeGraph := buildGraph(routes)
+	// Bellman-Ford reports negative cycles reachable from any city
+	if routeGraph.HasNegativeWeights() {
+		for _, city := range cities {
+			if _, _, err := routeGraph.ShortestPathTree(city); err != nil {
+				return algorithm.Tour{}, err
+			}
+		}
+	}
+
+	tour := routeGraph.CheapestTour(cities, roundTrip)
+	if len(tour.Order) == 0 {
+		return algorithm.Tour{}, ErrNoTour
+	}
+	return tour, nil
+}
@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"TravelRoute/dal"
+	"bytes"
+	"fmt"
+	"testing"
+)
+
+func TestFindCheapestTour(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "CDG", 75))
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "FCO", 20))
+	routeDB.InsertRoute(*dal.NewRoute("FCO", "CDG", 10))
+	routeDB.InsertRoute(*dal.NewRoute("CDG", "FCO", 10))
+	routeDB.InsertRoute(*dal.NewRoute("CDG", "GRU", 50))
+
+	tour, err := FindCheapestTour(routeDB.GetRoutes(), []string{"GRU", "CDG", "FCO"}, true)
+	if err != nil {
+		t.Fatalf("FindCheapestTour unexpected error: %v", err)
+	}
+
+	expectedOrder := []string{"GRU", "FCO", "CDG"}
+	for i := range expectedOrder {
+		if i >= len(tour.Order) || tour.Order[i] != expectedOrder[i] {
+			t.Fatalf("FindCheapestTour expected order %v, got %v", expectedOrder, tour.Order)
+		}
+	}
+	if tour.Cost != 80 {
+		t.Errorf("FindCheapestTour expected cost %v, got %v", 80, tour.Cost)
+	}
+
+	_, err = FindCheapestTour(routeDB.GetRoutes(), []string{"GRU", "GRU"}, true)
+	if err != ErrNotEnoughCities {
+		t.Errorf("FindCheapestTour expected error %v, got %v", ErrNotEnoughCities, err)
+	}
+
+	cities := make([]string, MaxTourCities+1)
+	for i := range cities {
+		cities[i] = fmt.Sprintf("C%02d", i)
+	}
+	_, err = FindCheapestTour(routeDB.GetRoutes(), cities, false)
+	if err != ErrTooManyCities {
+		t.Errorf("FindCheapestTour expected error %v, got %v", ErrTooManyCities, err)
+	}
+
+	_, err = FindCheapestTour(routeDB.GetRoutes(), []string{"FCO", "SCL"}, false)
+	if err != ErrNoTour {
+		t.Errorf("FindCheapestTour expected error %v, got %v", ErrNoTour, err)
+	}
+}
@@ -4,20 +4,22 @@ import (
 	"TravelRoute/controller"
 	"TravelRoute/dal"
 	"TravelRoute/domain"
+	"TravelRoute/rpc"
 	"bufio"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 )
 
-func buildRoutesDB() *dal.DB {
-	file, err := os.OpenFile(os.Args[1], os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+func buildRoutesDB(path string) *dal.DB {
+	routesDB, err := dal.OpenFileDB(path)
 	if err != nil {
 		log.Fatalf("could not open file: %v", err)
 	}
 
-	routesDB := dal.NewDB(file)
 	fmt.Println("Routes added:")
 	for _, route := range routesDB.GetRoutes() {
 		fmt.Println(route)
@@ -25,6 +27,20 @@ func buildRoutesDB() *dal.DB {
 	return routesDB
 }
 
+// compact rewrites the routes file dropping the versions of the routes older than retention
+func compact(path string, retention time.Duration) {
+	routesDB, err := dal.OpenFileDB(path)
+	if err != nil {
+		log.Fatalf("could not open file: %v", err)
+	}
+
+	dropped, err := routesDB.Compact(retention)
+	if err != nil {
+		log.Fatalf("could not compact file: %v", err)
+	}
+	fmt.Printf("%v compacted: %v lines dropped\n", path, dropped)
+}
+
 func readInput(scanner *bufio.Scanner) (string, bool) {
 	scanner.Scan()
 	if scanner.Text() == "q" {
@@ -33,16 +49,89 @@ func readInput(scanner *bufio.Scanner) (string, bool) {
 	return scanner.Text(), false
 }
 
+// webServerFlags defines the flags configuring the webserver
+type webServerFlags struct {
+	keys     *string
+	cert     *string
+	key      *string
+	clientCA *string
+	rate     *float64
+	burst    *int
+	maxBody  *int64
+	watches  *int
+	timeouts controller.Timeouts
+}
+
+// options builds the webserver options from the flags
+func (f *webServerFlags) options() controller.Options {
+	options := controller.Options{}
+	if *f.keys != "" {
+		keys, err := controller.LoadKeys(*f.keys)
+		if err != nil {
+			log.Fatalf("could not load keys: %v", err)
+		}
+		options.Keys = keys
+	}
+
+	if *f.cert != "" || *f.key != "" {
+		if *f.cert == "" || *f.key == "" {
+			log.Fatal("both -cert and -key are required to serve HTTPS")
+		}
+		options.TLS = &controller.TLSOptions{CertFile: *f.cert, KeyFile: *f.key, ClientCAFile: *f.clientCA}
+	} else if *f.clientCA != "" {
+		log.Fatal("-client-ca requires -cert and -key")
+	}
+
+	if *f.rate > 0 {
+		options.RateLimit = &controller.RateLimit{Rate: *f.rate, Burst: *f.burst}
+	}
+	options.MaxBodyBytes = *f.maxBody
+	options.MaxWatches = *f.watches
+	options.Timeouts = &f.timeouts
+	return options
+}
+
 func main() {
+	webFlags := &webServerFlags{
+		keys:     flag.String("keys", "", "file with the API keys required by the webserver, one KEY,ROLE per line"),
+		cert:     flag.String("cert", "", "PEM certificate served over HTTPS, reloaded when changed"),
+		key:      flag.String("key", "", "PEM private key of the certificate"),
+		clientCA: flag.String("client-ca", "", "PEM bundle of the CAs client certificates must be signed by"),
+		rate:     flag.Float64("rate", 0, "requests per second each client may make, unlimited when 0"),
+		burst:    flag.Int("burst", 20, "requests each client may make at once, when -rate is set"),
+		maxBody:  flag.Int64("max-body", controller.DefaultMaxBodyBytes, "maximum size of the request bodies in bytes, unlimited when negative"),
+		watches:  flag.Int("max-watches", controller.DefaultMaxWatches, "maximum amount of watches registered, unlimited when negative"),
+	}
+	retention := flag.Duration("retention", dal.DefaultRetention, "how long compact keeps the versions of the routes")
+	flag.DurationVar(&webFlags.timeouts.ReadTimeout, "read-timeout", controller.DefaultTimeouts.ReadTimeout, "time to read a request, unlimited when 0")
+	flag.DurationVar(&webFlags.timeouts.WriteTimeout, "write-timeout", controller.DefaultTimeouts.WriteTimeout, "time to write a response, unlimited when 0")
+	flag.DurationVar(&webFlags.timeouts.IdleTimeout, "idle-timeout", controller.DefaultTimeouts.IdleTimeout, "time to keep an idle connection, unlimited when 0")
+	flag.Usage = func() {
+		fmt.Println("Usage: TravelRoute [flags] FILE.csv\n\tPress 'q' to exit")
+		fmt.Println("       TravelRoute [-retention DURATION] compact FILE.csv")
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	args := flag.Args()
+
+	if len(args) == 2 && args[0] == "compact" {
+		compact(args[1], *retention)
+		return
+	}
 
-	if len(os.Args) != 2 {
-		fmt.Println("Usage: TravelRoute FILE.csv\n\tPress 'q' to exit")
+	if len(args) != 1 {
+		flag.Usage()
 		os.Exit(1)
 		return
 	}
 
-	routesDB := buildRoutesDB()
-	srv := controller.StartWebServer(routesDB, 8080)
+	routesDB := buildRoutesDB(args[0])
+	watcher := dal.WatchFile(routesDB, args[0], time.Second)
+	options := webFlags.options()
+	srv := controller.StartWebServerWithOptions(routesDB, 8080, options)
+	// The RPCs are served as the webserver is, with the same keys, certificates, limits and timeouts
+	rs := rpc.StartRouteServerWithOptions(routesDB, 8081,
+		rpc.Options{Keys: options.Keys, TLS: options.TLS, Timeouts: options.Timeouts, RateLimit: options.RateLimit})
 
 	scanner := bufio.NewScanner(os.Stdin)
 	for {
@@ -59,8 +148,10 @@ func main() {
 		}
 
 		fmt.Println("Calculating best route...")
-		bestRoute, cost := domain.FindCheapestRoute(routesDB.GetRoutes(), origin, destination)
-		if len(bestRoute) != 0 {
+		bestRoute, cost, err := domain.FindCheapestRoute(routesDB.GetRoutes(), origin, destination)
+		if err != nil {
+			fmt.Println(err)
+		} else if len(bestRoute) != 0 {
 			fmt.Printf("Best route: %v > $%v\n", strings.Join(bestRoute, " - "), cost)
 		} else {
 			fmt.Println("No route found!")
@@ -68,4 +159,6 @@ func main() {
 	}
 
 	controller.StopWebServer(srv)
+	rpc.StopRouteServer(rs)
+	watcher.Stop()
 }
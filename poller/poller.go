@@ -0,0 +1,86 @@
+// Package poller implements polling files for changes on disk.
+package poller
+
+import (
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// Poller polls a set of files, calling back whenever any of them changes on disk
+type Poller struct {
+	paths []string
+	// infos holds the files as they were on the last poll, nil for the ones never found
+	infos   []os.FileInfo
+	changed func(previous []os.FileInfo, current []os.FileInfo)
+	stop    chan struct{}
+	wg      *sync.WaitGroup
+}
+
+// Start records the files at paths as they are and starts polling them every interval
+// Whenever any of them changes, changed is called with the files as they were on the previous poll
+// and as they are now, in the order of paths
+// Returns a pointer to the Poller that can be Stopped latter
+func Start(paths []string, interval time.Duration, changed func(previous []os.FileInfo, current []os.FileInfo)) *Poller {
+	p := &Poller{paths, make([]os.FileInfo, len(paths)), changed, make(chan struct{}), &sync.WaitGroup{}}
+	p.stat()
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.poll()
+			case <-p.stop:
+				return
+			}
+		}
+	}()
+
+	return p
+}
+
+// Stop stops polling the files
+func (p *Poller) Stop() {
+	close(p.stop)
+	p.wg.Wait()
+}
+
+// Same tells whether both infos are of the same file, unchanged
+func Same(a os.FileInfo, b os.FileInfo) bool {
+	return a != nil && b != nil && a.ModTime().Equal(b.ModTime()) && a.Size() == b.Size() && os.SameFile(a, b)
+}
+
+// stat records the files as they are on disk
+// Files that can not be stat'ed are left as they were
+// Returns the files as they were before, and whether any of them changed
+func (p *Poller) stat() ([]os.FileInfo, bool) {
+	previous := append([]os.FileInfo(nil), p.infos...)
+	changed := false
+	for i, path := range p.paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			log.Printf("could not stat %v: %v", path, err)
+			continue
+		}
+
+		if Same(info, p.infos[i]) {
+			continue
+		}
+		p.infos[i] = info
+		changed = true
+	}
+	return previous, changed
+}
+
+// poll calls back in case any file changed since the last poll
+func (p *Poller) poll() {
+	if previous, changed := p.stat(); changed {
+		p.changed(previous, append([]os.FileInfo(nil), p.infos...))
+	}
+}
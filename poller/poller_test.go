@@ -0,0 +1,63 @@
+package poller
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPoller(t *testing.T) {
+	dir, err := ioutil.TempDir("", "poller")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	existing := filepath.Join(dir, "existing")
+	missing := filepath.Join(dir, "missing")
+	if err := ioutil.WriteFile(existing, []byte("a"), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+
+	type change struct{ previous, current []os.FileInfo }
+	changes := make(chan change, 10)
+	p := Start([]string{existing, missing}, 10*time.Millisecond, func(previous []os.FileInfo, current []os.FileInfo) {
+		changes <- change{previous, current}
+	})
+	defer p.Stop()
+
+	select {
+	case c := <-changes:
+		t.Fatalf("Poller expected no change, got %v", c)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	if err := ioutil.WriteFile(missing, []byte("b"), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+	select {
+	case c := <-changes:
+		if !Same(c.previous[0], c.current[0]) {
+			t.Errorf("%v expected unchanged, got %v and %v", existing, c.previous[0], c.current[0])
+		}
+		if c.previous[1] != nil || c.current[1] == nil || c.current[1].Size() != 1 {
+			t.Errorf("%v expected created, got %v and %v", missing, c.previous[1], c.current[1])
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Poller expected a change, got none")
+	}
+
+	if err := ioutil.WriteFile(existing, []byte("aa"), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+	select {
+	case c := <-changes:
+		if c.previous[0].Size() != 1 || c.current[0].Size() != 2 {
+			t.Errorf("%v expected to grow, got %v and %v", existing, c.previous[0].Size(), c.current[0].Size())
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("Poller expected a change, got none")
+	}
+}
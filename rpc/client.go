@@ -0,0 +1,83 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+)
+
+// Client calls the RouteService RPCs, protobuf encoded
+type Client struct {
+	httpClient *http.Client
+	baseURL    string
+	key        string
+}
+
+// NewClient constructs a client for the RouteService served at baseURL, as in "http://localhost:8081"
+// http.DefaultClient is used when httpClient is nil
+func NewClient(httpClient *http.Client, baseURL string) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{httpClient: httpClient, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+// SetKey sets the API key sent along every RPC, as a bearer token
+func (c *Client) SetKey(key string) {
+	c.key = key
+}
+
+// ListRoutes lists the stored routes
+func (c *Client) ListRoutes(req *ListRoutesRequest) (*ListRoutesResponse, error) {
+	resp := &ListRoutesResponse{}
+	return resp, c.call("ListRoutes", req, resp)
+}
+
+// InsertRoute stores the route
+func (c *Client) InsertRoute(req *InsertRouteRequest) (*InsertRouteResponse, error) {
+	resp := &InsertRouteResponse{}
+	return resp, c.call("InsertRoute", req, resp)
+}
+
+// BestRoute finds the cheapest route between two airports
+func (c *Client) BestRoute(req *BestRouteRequest) (*BestRouteResponse, error) {
+	resp := &BestRouteResponse{}
+	return resp, c.call("BestRoute", req, resp)
+}
+
+// call posts the request to the RPC, decoding its response into resp
+// Returns an *Error in case the RPC fails
+func (c *Client) call(name string, req message, resp message) error {
+	httpReq, err := http.NewRequest(http.MethodPost, c.baseURL+servicePath+name, bytes.NewReader(req.marshal()))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", contentTypeProto)
+	if c.key != "" {
+		httpReq.Header.Set("Authorization", "Bearer "+c.key)
+	}
+	httpResp, err := c.httpClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	body, err := ioutil.ReadAll(httpResp.Body)
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		rpcErr := &Error{}
+		if json.Unmarshal(body, rpcErr) != nil || rpcErr.Code == "" {
+			rpcErr = errorf(codeOf(httpResp.StatusCode), "%v", strings.TrimSpace(string(body)))
+		}
+		return rpcErr
+	}
+	if err := resp.unmarshal(body); err != nil {
+		return fmt.Errorf("could not decode %v response: %v", name, err)
+	}
+	return nil
+}
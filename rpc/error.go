@@ -0,0 +1,71 @@
+package rpc
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// Codes of the errors returned, as defined by the Connect protocol
+const (
+	CodeInvalidArgument    = "invalid_argument"
+	CodeFailedPrecondition = "failed_precondition"
+	CodeUnimplemented      = "unimplemented"
+	CodeUnauthenticated    = "unauthenticated"
+	CodePermissionDenied   = "permission_denied"
+	CodeResourceExhausted  = "resource_exhausted"
+	CodeInternal           = "internal"
+	CodeUnknown            = "unknown"
+)
+
+// Error is returned by the RPCs, and replied as JSON whatever the codec of the request
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%v: %v", e.Code, e.Message)
+}
+
+// errorf creates an Error with the formatted message
+func errorf(code string, format string, a ...interface{}) *Error {
+	return &Error{code, fmt.Sprintf(format, a...)}
+}
+
+// httpStatus maps the code to the HTTP status replied
+func httpStatus(code string) int {
+	switch code {
+	case CodeInvalidArgument, CodeFailedPrecondition:
+		return http.StatusBadRequest
+	case CodeUnimplemented:
+		return http.StatusNotFound
+	case CodeUnauthenticated:
+		return http.StatusUnauthorized
+	case CodePermissionDenied:
+		return http.StatusForbidden
+	case CodeResourceExhausted:
+		return http.StatusTooManyRequests
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// codeOf maps the HTTP status to a code, for errors replied without a body
+func codeOf(status int) string {
+	switch status {
+	case http.StatusBadRequest:
+		return CodeInvalidArgument
+	case http.StatusNotFound:
+		return CodeUnimplemented
+	case http.StatusUnauthorized:
+		return CodeUnauthenticated
+	case http.StatusForbidden:
+		return CodePermissionDenied
+	case http.StatusTooManyRequests:
+		return CodeResourceExhausted
+	case http.StatusInternalServerError:
+		return CodeInternal
+	default:
+		return CodeUnknown
+	}
+}
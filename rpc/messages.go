@@ -0,0 +1,258 @@
+package rpc
+
+import (
+	"TravelRoute/dal"
+)
+
+// The messages declared in route.proto
+// They are encoded either as protobuf or as JSON, following the protobuf JSON mapping
+
+// message is implemented by every message, so the codecs can handle any of them
+type message interface {
+	marshal() []byte
+	unmarshal(buf []byte) error
+}
+
+// Route mirrors dal.Route
+type Route struct {
+	Origin        string  `json:"origin,omitempty"`
+	Destination   string  `json:"destination,omitempty"`
+	Cost          float32 `json:"cost,omitempty"`
+	Carrier       string  `json:"carrier,omitempty"`
+	FareID        string  `json:"fareId,omitempty"`
+	Bidirectional bool    `json:"bidirectional,omitempty"`
+}
+
+// ListRoutesRequest is the request of ListRoutes, it has no fields
+type ListRoutesRequest struct{}
+
+// ListRoutesResponse holds the routes stored
+type ListRoutesResponse struct {
+	Routes []Route `json:"routes,omitempty"`
+}
+
+// InsertRouteRequest holds the route to be stored
+type InsertRouteRequest struct {
+	Route *Route `json:"route,omitempty"`
+}
+
+// InsertRouteResponse holds the route stored
+type InsertRouteResponse struct {
+	Route *Route `json:"route,omitempty"`
+}
+
+// BestRouteRequest holds the airports the cheapest route is searched between
+type BestRouteRequest struct {
+	Origin      string `json:"origin,omitempty"`
+	Destination string `json:"destination,omitempty"`
+}
+
+// BestRouteResponse holds the airports of the cheapest route and its cost, empty in case there is none
+type BestRouteResponse struct {
+	Route []string `json:"route,omitempty"`
+	Cost  float32  `json:"cost,omitempty"`
+}
+
+// fromDAL converts the route stored
+func fromDAL(route dal.Route) Route {
+	return Route{route.Origin, route.Destination, route.Cost, route.Carrier, route.FareID, route.Bidirectional}
+}
+
+// toDAL converts the route to be stored
+func (m *Route) toDAL() dal.Route {
+	return dal.Route{Origin: m.Origin, Destination: m.Destination, Cost: m.Cost,
+		Carrier: m.Carrier, FareID: m.FareID, Bidirectional: m.Bidirectional}
+}
+
+func (m *Route) marshal() []byte {
+	var e encoder
+	e.string(1, m.Origin)
+	e.string(2, m.Destination)
+	e.float(3, m.Cost)
+	e.string(4, m.Carrier)
+	e.string(5, m.FareID)
+	e.bool(6, m.Bidirectional)
+	return e.buf
+}
+
+func (m *Route) unmarshal(buf []byte) error {
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.number {
+		case 1:
+			err = f.expect(wireBytes)
+			m.Origin = string(f.bytes)
+		case 2:
+			err = f.expect(wireBytes)
+			m.Destination = string(f.bytes)
+		case 3:
+			err = f.expect(wireFixed32)
+			m.Cost = f.float()
+		case 4:
+			err = f.expect(wireBytes)
+			m.Carrier = string(f.bytes)
+		case 5:
+			err = f.expect(wireBytes)
+			m.FareID = string(f.bytes)
+		case 6:
+			err = f.expect(wireVarint)
+			m.Bidirectional = f.varint != 0
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *ListRoutesRequest) marshal() []byte {
+	return []byte{}
+}
+
+func (m *ListRoutesRequest) unmarshal(buf []byte) error {
+	_, err := decodeFields(buf)
+	return err
+}
+
+func (m *ListRoutesResponse) marshal() []byte {
+	var e encoder
+	for i := range m.Routes {
+		e.message(1, m.Routes[i].marshal())
+	}
+	return e.buf
+}
+
+func (m *ListRoutesResponse) unmarshal(buf []byte) error {
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		if f.number != 1 {
+			continue
+		}
+		var route Route
+		if err := f.expect(wireBytes); err != nil {
+			return err
+		}
+		if err := route.unmarshal(f.bytes); err != nil {
+			return err
+		}
+		m.Routes = append(m.Routes, route)
+	}
+	return nil
+}
+
+func (m *InsertRouteRequest) marshal() []byte {
+	return marshalRoute(m.Route)
+}
+
+func (m *InsertRouteRequest) unmarshal(buf []byte) error {
+	route, err := unmarshalRoute(buf)
+	m.Route = route
+	return err
+}
+
+func (m *InsertRouteResponse) marshal() []byte {
+	return marshalRoute(m.Route)
+}
+
+func (m *InsertRouteResponse) unmarshal(buf []byte) error {
+	route, err := unmarshalRoute(buf)
+	m.Route = route
+	return err
+}
+
+// marshalRoute encodes a message holding only the route in field 1
+func marshalRoute(route *Route) []byte {
+	var e encoder
+	if route != nil {
+		e.message(1, route.marshal())
+	}
+	return e.buf
+}
+
+// unmarshalRoute decodes a message holding only the route in field 1
+// Returns nil in case the route is missing
+func unmarshalRoute(buf []byte) (*Route, error) {
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return nil, err
+	}
+	var route *Route
+	for _, f := range fields {
+		if f.number != 1 {
+			continue
+		}
+		if err := f.expect(wireBytes); err != nil {
+			return nil, err
+		}
+		// Embedded messages appearing more than once are merged
+		if route == nil {
+			route = &Route{}
+		}
+		if err := route.unmarshal(f.bytes); err != nil {
+			return nil, err
+		}
+	}
+	return route, nil
+}
+
+func (m *BestRouteRequest) marshal() []byte {
+	var e encoder
+	e.string(1, m.Origin)
+	e.string(2, m.Destination)
+	return e.buf
+}
+
+func (m *BestRouteRequest) unmarshal(buf []byte) error {
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.number {
+		case 1:
+			err = f.expect(wireBytes)
+			m.Origin = string(f.bytes)
+		case 2:
+			err = f.expect(wireBytes)
+			m.Destination = string(f.bytes)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (m *BestRouteResponse) marshal() []byte {
+	var e encoder
+	e.repeatedString(1, m.Route)
+	e.float(2, m.Cost)
+	return e.buf
+}
+
+func (m *BestRouteResponse) unmarshal(buf []byte) error {
+	fields, err := decodeFields(buf)
+	if err != nil {
+		return err
+	}
+	for _, f := range fields {
+		switch f.number {
+		case 1:
+			err = f.expect(wireBytes)
+			m.Route = append(m.Route, string(f.bytes))
+		case 2:
+			err = f.expect(wireFixed32)
+			m.Cost = f.float()
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,77 @@
+package rpc
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+func TestRouteMarshal(t *testing.T) {
+	route := Route{Origin: "GRU", Destination: "BRC", Cost: 10}
+	// Fields 1 and 2 length delimited, field 3 as a little-endian fixed32
+	expected := []byte{0x0a, 0x03, 'G', 'R', 'U', 0x12, 0x03, 'B', 'R', 'C', 0x1d, 0x00, 0x00, 0x20, 0x41}
+
+	if buf := route.marshal(); !bytes.Equal(buf, expected) {
+		t.Errorf("route.marshal expected % x, got % x", expected, buf)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	var tests = []struct {
+		name    string
+		message message
+		decoded message
+	}{
+		{"Route", &Route{"GRU", "CDG", 75.5, "AF", "AF457", true}, &Route{}},
+		{"empty Route", &Route{}, &Route{}},
+		{"ListRoutesRequest", &ListRoutesRequest{}, &ListRoutesRequest{}},
+		{"ListRoutesResponse", &ListRoutesResponse{[]Route{{Origin: "GRU", Destination: "BRC", Cost: 10}, {}}}, &ListRoutesResponse{}},
+		{"InsertRouteRequest", &InsertRouteRequest{&Route{Origin: "BRC", Destination: "SCL", Cost: -5}}, &InsertRouteRequest{}},
+		{"InsertRouteRequest without route", &InsertRouteRequest{}, &InsertRouteRequest{}},
+		{"InsertRouteResponse", &InsertRouteResponse{&Route{Origin: "BRC", Destination: "SCL", Cost: 5}}, &InsertRouteResponse{}},
+		{"BestRouteRequest", &BestRouteRequest{"GRU", "CDG"}, &BestRouteRequest{}},
+		{"BestRouteResponse", &BestRouteResponse{[]string{"GRU", "BRC", "SCL"}, 15}, &BestRouteResponse{}},
+		{"empty BestRouteResponse", &BestRouteResponse{}, &BestRouteResponse{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.decoded.unmarshal(tt.message.marshal()); err != nil {
+				t.Fatalf("unmarshal error: %v", err)
+			}
+			if !reflect.DeepEqual(tt.decoded, tt.message) {
+				t.Errorf("unmarshal expected %v, got %v", tt.message, tt.decoded)
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	var tests = []struct {
+		name     string
+		buf      []byte
+		expected Route
+		valid    bool
+	}{
+		{"unknown fields skipped", []byte{0x0a, 0x01, 'A', 0x38, 0x96, 0x01, 0x41, 1, 2, 3, 4, 5, 6, 7, 8, 0x4d, 1, 2, 3, 4, 0x52, 0x00}, Route{Origin: "A"}, true},
+		{"last value wins", []byte{0x0a, 0x01, 'A', 0x0a, 0x01, 'B'}, Route{Origin: "B"}, true},
+		{"truncated string", []byte{0x0a, 0x03, 'G', 'R'}, Route{}, false},
+		{"truncated float", []byte{0x1d, 0x00, 0x00}, Route{}, false},
+		{"truncated key", []byte{0x80}, Route{}, false},
+		{"wrong wire type", []byte{0x18, 0x0a}, Route{}, false},
+		{"field 0", []byte{0x02, 0x00}, Route{}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var route Route
+			err := route.unmarshal(tt.buf)
+			if (err == nil) != tt.valid {
+				t.Fatalf("route.unmarshal expected valid %v, got %v", tt.valid, err)
+			}
+			if tt.valid && route != tt.expected {
+				t.Errorf("route.unmarshal expected %v, got %v", tt.expected, route)
+			}
+		})
+	}
+}
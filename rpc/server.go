@@ -0,0 +1,271 @@
+package rpc
+
+import (
+	"TravelRoute/controller"
+	"TravelRoute/dal"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"math"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// servicePath prefixes the path of every RPC, as in "/travelroute.v1.RouteService/ListRoutes"
+const servicePath = "/travelroute.v1.RouteService/"
+
+// Content types of the codecs supported
+const (
+	contentTypeProto = "application/proto"
+	contentTypeJSON  = "application/json"
+)
+
+// maxMessageSize limits the size of the requests read, as gRPC does by default
+const maxMessageSize = 4 << 20
+
+// mutatingRPCs lists the RPCs changing the stored routes
+var mutatingRPCs = map[string]bool{"InsertRoute": true}
+
+// Options defines how the RouteServer serves the RPCs
+// They take the same values as the options of the webserver
+type Options struct {
+	// Keys, when set, holds the API keys the RPCs require, the same ones the webserver accepts
+	// Only admin keys may call the RPCs changing the routes
+	Keys *controller.KeyStore
+	// TLS, when set, serves HTTPS instead of plain HTTP
+	TLS *controller.TLSOptions
+	// Timeouts of the http.Server, controller.DefaultTimeouts when not set
+	Timeouts *controller.Timeouts
+	// RateLimit, when set, limits the RPCs of each client
+	RateLimit *controller.RateLimit
+}
+
+// procedure binds an RPC to the routeService method implementing it
+type procedure struct {
+	newRequest func() message
+	call       func(req message) (message, error)
+}
+
+// RouteServer defines the RPC server for the TravelRoute application
+type RouteServer struct {
+	srv *http.Server
+	wg  *sync.WaitGroup
+	// reloader keeps the certificates up to date, when serving HTTPS
+	reloader *controller.CertReloader
+}
+
+// StartRouteServer starts the RouteService at the provided port, separated from the webserver
+// Receives a pointer to the DataBase to fetch and persist Route information
+// Returns a pointer to the RouteServer that can be Stopped latter
+func StartRouteServer(routeDB *dal.DB, port int) *RouteServer {
+	return StartRouteServerWithOptions(routeDB, port, Options{})
+}
+
+// StartRouteServerWithOptions starts the RouteService at the provided port, as set by the options
+// Receives a pointer to the DataBase to fetch and persist Route information
+// Returns a pointer to the RouteServer that can be Stopped latter
+func StartRouteServerWithOptions(routeDB *dal.DB, port int, options Options) *RouteServer {
+	timeouts := controller.DefaultTimeouts
+	if options.Timeouts != nil {
+		timeouts = *options.Timeouts
+	}
+	srv := &http.Server{
+		Addr:         fmt.Sprintf(":%v", port),
+		Handler:      NewHandlerWithOptions(routeDB, options),
+		ReadTimeout:  timeouts.ReadTimeout,
+		WriteTimeout: timeouts.WriteTimeout,
+		IdleTimeout:  timeouts.IdleTimeout,
+	}
+
+	var reloader *controller.CertReloader
+	if options.TLS != nil {
+		var err error
+		if reloader, err = controller.NewCertReloader(*options.TLS); err != nil {
+			log.Fatal("TLS: " + err.Error())
+		}
+		srv.TLSConfig = reloader.TLSConfig()
+	}
+
+	// Listens before returning so the server is ready to accept connections
+	listener, err := net.Listen("tcp", srv.Addr)
+	if err != nil {
+		log.Fatal("Listen: " + err.Error())
+	}
+
+	wg := &sync.WaitGroup{}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		fmt.Printf("RouteService listening on port %v...\n", port)
+		serve := srv.Serve
+		if reloader != nil {
+			// The certificates are taken from the TLS config, instead of files
+			serve = func(l net.Listener) error { return srv.ServeTLS(l, "", "") }
+		}
+		if err := serve(listener); err != http.ErrServerClosed {
+			log.Fatal("Serve: " + err.Error())
+		}
+	}()
+
+	return &RouteServer{srv, wg, reloader}
+}
+
+// StopRouteServer stops the RPC server
+// Receives a pointer to the running server
+func StopRouteServer(rs *RouteServer) {
+	if err := rs.srv.Shutdown(context.Background()); err != nil {
+		panic(err)
+	}
+	rs.wg.Wait()
+	if rs.reloader != nil {
+		rs.reloader.Stop()
+	}
+}
+
+// NewHandler constructs the handler serving the RouteService over the Connect unary protocol,
+// either protobuf or JSON encoded, so it can be served on any listener
+func NewHandler(routeDB *dal.DB) http.Handler {
+	s := &routeService{routeDB}
+	procedures := map[string]procedure{
+		"ListRoutes": {
+			func() message { return &ListRoutesRequest{} },
+			func(req message) (message, error) { return s.ListRoutes(req.(*ListRoutesRequest)) },
+		},
+		"InsertRoute": {
+			func() message { return &InsertRouteRequest{} },
+			func(req message) (message, error) { return s.InsertRoute(req.(*InsertRouteRequest)) },
+		},
+		"BestRoute": {
+			func() message { return &BestRouteRequest{} },
+			func(req message) (message, error) { return s.BestRoute(req.(*BestRouteRequest)) },
+		},
+	}
+
+	mux := http.NewServeMux()
+	for name, p := range procedures {
+		mux.Handle(servicePath+name, p)
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, errorf(CodeUnimplemented, "%v is not implemented", r.URL.Path))
+	})
+	return mux
+}
+
+// NewHandlerWithOptions constructs the handler serving the RouteService, as set by the options
+// The RPCs are throttled before their keys are checked, as the webserver does
+func NewHandlerWithOptions(routeDB *dal.DB, options Options) http.Handler {
+	handler := NewHandler(routeDB)
+	if options.Keys != nil {
+		handler = &authorizer{options.Keys, handler}
+	}
+	if options.RateLimit != nil {
+		if options.RateLimit.Rate <= 0 {
+			log.Fatal("RateLimit: the rate must be positive")
+		}
+		handler = &throttler{controller.NewRateLimiter(*options.RateLimit), handler}
+	}
+	return handler
+}
+
+// throttler limits the RPCs of each client before serving them
+type throttler struct {
+	limiter *controller.RateLimiter
+	handler http.Handler
+}
+
+// ServeHTTP replies with the error in case the client of the request has no tokens left
+func (t *throttler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if allowed, wait := t.limiter.Allow(r); !allowed {
+		w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+		writeError(w, errorf(CodeResourceExhausted, "too many requests, try again later"))
+		return
+	}
+	t.handler.ServeHTTP(w, r)
+}
+
+// authorizer checks the API key of every RPC before serving it
+type authorizer struct {
+	keys    *controller.KeyStore
+	handler http.Handler
+}
+
+// ServeHTTP replies with the error in case the key of the request does not grant its role
+func (a *authorizer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	role, found := a.keys.Role(r)
+	if !found {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="TravelRoute"`)
+		writeError(w, errorf(CodeUnauthenticated, "missing or unknown API key"))
+		return
+	}
+	if role != controller.RoleAdmin && mutatingRPCs[strings.TrimPrefix(r.URL.Path, servicePath)] {
+		writeError(w, errorf(CodePermissionDenied, "the %v role can not change the routes", role))
+		return
+	}
+	a.handler.ServeHTTP(w, r)
+}
+
+// ServeHTTP decodes the request, calls the RPC and encodes its response with the same codec
+func (p procedure) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, fmt.Sprintf("%v: Method not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType != contentTypeProto && contentType != contentTypeJSON {
+		w.Header().Set("Accept-Post", contentTypeProto+", "+contentTypeJSON)
+		http.Error(w, fmt.Sprintf("Unsupported content type: %v", contentType), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxMessageSize))
+	if err != nil {
+		writeError(w, errorf(CodeInvalidArgument, "%v", err))
+		return
+	}
+	req := p.newRequest()
+	if contentType == contentTypeProto {
+		err = req.unmarshal(body)
+	} else {
+		err = json.Unmarshal(body, req)
+	}
+	if err != nil {
+		writeError(w, errorf(CodeInvalidArgument, "could not decode request: %v", err))
+		return
+	}
+
+	resp, err := p.call(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	var out []byte
+	if contentType == contentTypeProto {
+		out = resp.marshal()
+	} else if out, err = json.Marshal(resp); err != nil {
+		writeError(w, errorf(CodeInternal, "%v", err))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write(out)
+}
+
+// writeError replies with the error as JSON, the way the Connect protocol expects
+func writeError(w http.ResponseWriter, err error) {
+	rpcErr, ok := err.(*Error)
+	if !ok {
+		rpcErr = errorf(CodeUnknown, "%v", err)
+	}
+	js, _ := json.Marshal(rpcErr)
+	w.Header().Set("Content-Type", contentTypeJSON)
+	w.WriteHeader(httpStatus(rpcErr.Code))
+	w.Write(js)
+}
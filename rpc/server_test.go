@@ -0,0 +1,377 @@
+package rpc
+
+import (
+	"TravelRoute/controller"
+	"TravelRoute/dal"
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// pipeListener is an in-process listener, whose connections are dialed through net.Pipe
+type pipeListener struct {
+	conns  chan net.Conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+func newPipeListener() *pipeListener {
+	return &pipeListener{conns: make(chan net.Conn), closed: make(chan struct{})}
+}
+
+func (l *pipeListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	}
+}
+
+func (l *pipeListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *pipeListener) Addr() net.Addr {
+	return &net.UnixAddr{Name: "pipe", Net: "pipe"}
+}
+
+func (l *pipeListener) DialContext(ctx context.Context, network string, addr string) (net.Conn, error) {
+	server, client := net.Pipe()
+	select {
+	case l.conns <- server:
+		return client, nil
+	case <-l.closed:
+		return nil, errors.New("listener closed")
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// startInProcess serves the RouteService over an in-process listener
+// Returns the HTTP client dialing it, the server is stopped when the test ends
+func startInProcess(t *testing.T, routeDB *dal.DB) *http.Client {
+	return serveInProcess(t, NewHandler(routeDB))
+}
+
+// serveInProcess serves the handler over an in-process listener
+// Returns the HTTP client dialing it, the server is stopped when the test ends
+func serveInProcess(t *testing.T, handler http.Handler) *http.Client {
+	listener := newPipeListener()
+	srv := &http.Server{Handler: handler}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		srv.Serve(listener)
+	}()
+	t.Cleanup(func() {
+		srv.Shutdown(context.Background())
+		<-done
+	})
+
+	return &http.Client{Transport: &http.Transport{DialContext: listener.DialContext}}
+}
+
+func TestRouteService(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	client := NewClient(startInProcess(t, routeDB), "http://travelroute")
+
+	inserted, err := client.InsertRoute(&InsertRouteRequest{&Route{Origin: "BRC", Destination: "SCL", Cost: 5, Carrier: "LA"}})
+	if err != nil {
+		t.Fatalf("client.InsertRoute error: %v", err)
+	}
+	if expected := (Route{Origin: "BRC", Destination: "SCL", Cost: 5, Carrier: "LA"}); *inserted.Route != expected {
+		t.Errorf("client.InsertRoute expected %v, got %v", expected, *inserted.Route)
+	}
+
+	list, err := client.ListRoutes(&ListRoutesRequest{})
+	if err != nil {
+		t.Fatalf("client.ListRoutes error: %v", err)
+	}
+	expected := []Route{{Origin: "GRU", Destination: "BRC", Cost: 10}, {Origin: "BRC", Destination: "SCL", Cost: 5, Carrier: "LA"}}
+	if !reflect.DeepEqual(list.Routes, expected) {
+		t.Errorf("client.ListRoutes expected %v, got %v", expected, list.Routes)
+	}
+
+	best, err := client.BestRoute(&BestRouteRequest{"GRU", "SCL"})
+	if err != nil {
+		t.Fatalf("client.BestRoute error: %v", err)
+	}
+	if !reflect.DeepEqual(best, &BestRouteResponse{[]string{"GRU", "BRC", "SCL"}, 15}) {
+		t.Errorf("client.BestRoute expected GRU > BRC > SCL 15, got %v", best)
+	}
+
+	// Shares the database with the webserver, so routes inserted elsewhere are seen
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "SCL", 12))
+	if best, err = client.BestRoute(&BestRouteRequest{"GRU", "SCL"}); err != nil || best.Cost != 12 {
+		t.Errorf("client.BestRoute expected cost 12, got %v %v", best, err)
+	}
+
+	if best, err = client.BestRoute(&BestRouteRequest{"SCL", "GRU"}); err != nil || len(best.Route) != 0 || best.Cost != 0 {
+		t.Errorf("client.BestRoute expected no route, got %v %v", best, err)
+	}
+}
+
+func TestRouteServiceErrors(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*dal.NewRoute("BRC", "SCL", -5))
+	routeDB.InsertRoute(*dal.NewRoute("SCL", "BRC", 2))
+	client := NewClient(startInProcess(t, routeDB), "http://travelroute")
+
+	var tests = []struct {
+		name string
+		call func() error
+		code string
+	}{
+		{"missing origin", func() error {
+			_, err := client.BestRoute(&BestRouteRequest{Destination: "SCL"})
+			return err
+		}, CodeInvalidArgument},
+		{"negative cycle", func() error {
+			_, err := client.BestRoute(&BestRouteRequest{"BRC", "SCL"})
+			return err
+		}, CodeFailedPrecondition},
+		{"missing route", func() error {
+			_, err := client.InsertRoute(&InsertRouteRequest{})
+			return err
+		}, CodeInvalidArgument},
+		{"missing destination", func() error {
+			_, err := client.InsertRoute(&InsertRouteRequest{&Route{Origin: "GRU", Cost: 1}})
+			return err
+		}, CodeInvalidArgument},
+		{"unknown RPC", func() error {
+			return client.call("DeleteRoute", &ListRoutesRequest{}, &ListRoutesResponse{})
+		}, CodeUnimplemented},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var rpcErr *Error
+			if err := tt.call(); !errors.As(err, &rpcErr) || rpcErr.Code != tt.code {
+				t.Errorf("expected %v, got %v", tt.code, err)
+			}
+		})
+	}
+
+	if len(routeDB.GetRoutes()) != 2 {
+		t.Errorf("expected no route inserted, got %v", routeDB.GetRoutes())
+	}
+}
+
+func TestRouteServiceProtocol(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	httpClient := startInProcess(t, routeDB)
+
+	var tests = []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		expected    string
+	}{
+		{"JSON", http.MethodPost, "/travelroute.v1.RouteService/BestRoute", "application/json",
+			`{"origin":"GRU","destination":"BRC"}`, http.StatusOK, `{"route":["GRU","BRC"],"cost":10}`},
+		{"JSON with charset", http.MethodPost, "/travelroute.v1.RouteService/ListRoutes", "application/json; charset=utf-8",
+			`{}`, http.StatusOK, `{"routes":[{"origin":"GRU","destination":"BRC","cost":10}]}`},
+		{"JSON insert", http.MethodPost, "/travelroute.v1.RouteService/InsertRoute", "application/json",
+			`{"route":{"origin":"BRC","destination":"SCL","cost":5,"fareId":"F1"}}`, http.StatusOK,
+			`{"route":{"origin":"BRC","destination":"SCL","cost":5,"fareId":"F1"}}`},
+		{"invalid JSON", http.MethodPost, "/travelroute.v1.RouteService/BestRoute", "application/json",
+			`{"origin":`, http.StatusBadRequest, `{"code":"invalid_argument","message":"could not decode request: unexpected end of JSON input"}`},
+		{"invalid proto", http.MethodPost, "/travelroute.v1.RouteService/BestRoute", "application/proto",
+			"\x0a\x05GRU", http.StatusBadRequest, `{"code":"invalid_argument","message":"could not decode request: proto: message truncated"}`},
+		{"unsupported content type", http.MethodPost, "/travelroute.v1.RouteService/ListRoutes", "text/plain",
+			``, http.StatusUnsupportedMediaType, "Unsupported content type: text/plain\n"},
+		{"GET", http.MethodGet, "/travelroute.v1.RouteService/ListRoutes", "",
+			``, http.StatusMethodNotAllowed, "GET: Method not allowed\n"},
+		{"unknown RPC", http.MethodPost, "/travelroute.v1.RouteService/DeleteRoute", "application/json",
+			`{}`, http.StatusNotFound, `{"code":"unimplemented","message":"/travelroute.v1.RouteService/DeleteRoute is not implemented"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, err := http.NewRequest(tt.method, "http://travelroute"+tt.path, strings.NewReader(tt.body))
+			if err != nil {
+				t.Fatalf("http.NewRequest error: %v", err)
+			}
+			if tt.contentType != "" {
+				req.Header.Set("Content-Type", tt.contentType)
+			}
+			resp, err := httpClient.Do(req)
+			if err != nil {
+				t.Fatalf("httpClient.Do error: %v", err)
+			}
+			defer resp.Body.Close()
+			body, _ := ioutil.ReadAll(resp.Body)
+
+			if resp.StatusCode != tt.status || string(body) != tt.expected {
+				t.Errorf("expected %v %v, got %v %v", tt.status, tt.expected, resp.StatusCode, string(body))
+			}
+		})
+	}
+}
+
+func TestRouteServiceAuth(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	keys, err := controller.NewKeyStore(map[string]string{"reader": controller.RoleReadOnly, "admin": controller.RoleAdmin})
+	if err != nil {
+		t.Fatalf("controller.NewKeyStore error: %v", err)
+	}
+	httpClient := serveInProcess(t, NewHandlerWithOptions(routeDB, Options{Keys: keys}))
+
+	insert := &InsertRouteRequest{&Route{Origin: "GRU", Destination: "BRC", Cost: 10}}
+	var tests = []struct {
+		name   string
+		key    string
+		list   string
+		insert string
+	}{
+		{"no key", "", CodeUnauthenticated, CodeUnauthenticated},
+		{"unknown key", "asdf", CodeUnauthenticated, CodeUnauthenticated},
+		{"read-only key", "reader", "", CodePermissionDenied},
+		{"admin key", "admin", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient(httpClient, "http://travelroute")
+			client.SetKey(tt.key)
+
+			var rpcErr *Error
+			if _, err := client.ListRoutes(&ListRoutesRequest{}); (tt.list == "" && err != nil) ||
+				(tt.list != "" && (!errors.As(err, &rpcErr) || rpcErr.Code != tt.list)) {
+				t.Errorf("client.ListRoutes expected %q, got %v", tt.list, err)
+			}
+			if _, err := client.InsertRoute(insert); (tt.insert == "" && err != nil) ||
+				(tt.insert != "" && (!errors.As(err, &rpcErr) || rpcErr.Code != tt.insert)) {
+				t.Errorf("client.InsertRoute expected %q, got %v", tt.insert, err)
+			}
+		})
+	}
+
+	// Only the admin key inserted the route
+	if routes := routeDB.GetRoutes(); len(routes) != 1 {
+		t.Errorf("routeDB.GetRoutes expected 1 route, got %v", routes)
+	}
+}
+
+func TestStartStopRouteServer(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	rs := StartRouteServer(routeDB, 8081)
+
+	list, err := NewClient(nil, "http://localhost:8081").ListRoutes(&ListRoutesRequest{})
+	if err != nil || len(list.Routes) != 1 {
+		t.Errorf("client.ListRoutes expected 1 route, got %v %v", list, err)
+	}
+
+	StopRouteServer(rs)
+}
+
+func TestRouteServiceRateLimit(t *testing.T) {
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	httpClient := serveInProcess(t, NewHandlerWithOptions(routeDB, Options{RateLimit: &controller.RateLimit{Rate: 0.01, Burst: 2}}))
+	client := NewClient(httpClient, "http://travelroute")
+
+	for i := 0; i < 2; i++ {
+		if _, err := client.ListRoutes(&ListRoutesRequest{}); err != nil {
+			t.Fatalf("client.ListRoutes error: %v", err)
+		}
+	}
+
+	var rpcErr *Error
+	if _, err := client.BestRoute(&BestRouteRequest{Origin: "GRU", Destination: "BRC"}); !errors.As(err, &rpcErr) || rpcErr.Code != CodeResourceExhausted {
+		t.Errorf("client.BestRoute expected %q, got %v", CodeResourceExhausted, err)
+	}
+}
+
+// writeSelfSigned writes a self signed certificate for localhost and its key to dir
+// Returns the TLS options serving it and the pool trusting it
+func writeSelfSigned(t *testing.T, dir string) (controller.TLSOptions, *x509.CertPool) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("ecdsa.GenerateKey error: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		DNSNames:     []string{"localhost"},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("x509.CreateCertificate error: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("x509.MarshalECPrivateKey error: %v", err)
+	}
+
+	options := controller.TLSOptions{CertFile: filepath.Join(dir, "cert.pem"), KeyFile: filepath.Join(dir, "key.pem")}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	if err := ioutil.WriteFile(options.CertFile, certPEM, 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+	if err := ioutil.WriteFile(options.KeyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatalf("ioutil.WriteFile error: %v", err)
+	}
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(certPEM)
+	return options, roots
+}
+
+func TestStartStopRouteServerTLS(t *testing.T) {
+	dir, err := ioutil.TempDir("", "certs")
+	if err != nil {
+		t.Fatalf("ioutil.TempDir error: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	options, roots := writeSelfSigned(t, dir)
+
+	routeDB := dal.NewDB(&bytes.Buffer{})
+	routeDB.InsertRoute(*dal.NewRoute("GRU", "BRC", 10))
+	rs := StartRouteServerWithOptions(routeDB, 8081, Options{TLS: &options, Timeouts: &controller.Timeouts{ReadTimeout: time.Second}})
+	if rs.srv.ReadTimeout != time.Second {
+		t.Errorf("ReadTimeout expected %v, got %v", time.Second, rs.srv.ReadTimeout)
+	}
+
+	httpsClient := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+	list, err := NewClient(httpsClient, "https://localhost:8081").ListRoutes(&ListRoutesRequest{})
+	if err != nil || len(list.Routes) != 1 {
+		t.Errorf("client.ListRoutes expected 1 route, got %v %v", list, err)
+	}
+
+	// Plain HTTP is refused
+	if _, err := NewClient(nil, "http://localhost:8081").ListRoutes(&ListRoutesRequest{}); err == nil {
+		t.Errorf("client.ListRoutes over HTTP expected to fail, got %v", err)
+	}
+
+	StopRouteServer(rs)
+}
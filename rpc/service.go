@@ -0,0 +1,57 @@
+package rpc
+
+import (
+	"TravelRoute/algorithm"
+	"TravelRoute/dal"
+	"TravelRoute/domain"
+	"errors"
+)
+
+// routeService implements the RouteService RPCs over the routes database
+type routeService struct {
+	routeDB *dal.DB
+}
+
+// ListRoutes lists the stored routes
+func (s *routeService) ListRoutes(req *ListRoutesRequest) (*ListRoutesResponse, error) {
+	routes := s.routeDB.GetRoutes()
+	resp := &ListRoutesResponse{Routes: make([]Route, len(routes))}
+	for i, route := range routes {
+		resp.Routes[i] = fromDAL(route)
+	}
+	return resp, nil
+}
+
+// InsertRoute stores the route, overwriting the previous version of the same route
+func (s *routeService) InsertRoute(req *InsertRouteRequest) (*InsertRouteResponse, error) {
+	if req.Route == nil {
+		return nil, errorf(CodeInvalidArgument, "missing route")
+	}
+	route := req.Route.toDAL()
+	if err := route.Validate(); err != nil {
+		return nil, errorf(CodeInvalidArgument, "%v", err)
+	}
+
+	s.routeDB.InsertRoute(route)
+	return &InsertRouteResponse{Route: req.Route}, nil
+}
+
+// BestRoute finds the cheapest route between two airports
+// The route is empty in case there is none
+func (s *routeService) BestRoute(req *BestRouteRequest) (*BestRouteResponse, error) {
+	if req.Origin == "" {
+		return nil, errorf(CodeInvalidArgument, "missing origin")
+	}
+	if req.Destination == "" {
+		return nil, errorf(CodeInvalidArgument, "missing destination")
+	}
+
+	route, cost, err := domain.FindCheapestRoute(s.routeDB.GetRoutes(), req.Origin, req.Destination)
+	var cycleErr *algorithm.NegativeCycleError
+	if errors.As(err, &cycleErr) {
+		return nil, errorf(CodeFailedPrecondition, "%v", err)
+	} else if err != nil {
+		return nil, errorf(CodeInternal, "%v", err)
+	}
+	return &BestRouteResponse{Route: route, Cost: cost}, nil
+}
@@ -0,0 +1,146 @@
+package rpc
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math"
+)
+
+// Wire types of the protobuf encoding
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// errTruncated is returned when a message ends in the middle of a field
+var errTruncated = errors.New("proto: message truncated")
+
+// encoder appends protobuf fields to a buffer
+// Fields holding the zero value are not written, as proto3 does
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) uvarint(value uint64) {
+	var varint [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(varint[:], value)
+	e.buf = append(e.buf, varint[:n]...)
+}
+
+func (e *encoder) tag(field int, wireType int) {
+	e.uvarint(uint64(field<<3 | wireType))
+}
+
+func (e *encoder) string(field int, value string) {
+	if value == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.uvarint(uint64(len(value)))
+	e.buf = append(e.buf, value...)
+}
+
+// repeatedString writes every value, including empty ones
+func (e *encoder) repeatedString(field int, values []string) {
+	for _, value := range values {
+		e.tag(field, wireBytes)
+		e.uvarint(uint64(len(value)))
+		e.buf = append(e.buf, value...)
+	}
+}
+
+func (e *encoder) float(field int, value float32) {
+	if value == 0 {
+		return
+	}
+	e.tag(field, wireFixed32)
+	var fixed [4]byte
+	binary.LittleEndian.PutUint32(fixed[:], math.Float32bits(value))
+	e.buf = append(e.buf, fixed[:]...)
+}
+
+func (e *encoder) bool(field int, value bool) {
+	if !value {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = append(e.buf, 1)
+}
+
+// message writes the message embedded, even when empty
+func (e *encoder) message(field int, m []byte) {
+	e.tag(field, wireBytes)
+	e.uvarint(uint64(len(m)))
+	e.buf = append(e.buf, m...)
+}
+
+// field holds a decoded field, only the member of its wire type is set
+type field struct {
+	number   int
+	wireType int
+	varint   uint64
+	fixed32  uint32
+	bytes    []byte
+}
+
+// decodeFields splits a message into its fields, in the order they were written
+func decodeFields(buf []byte) ([]field, error) {
+	fields := make([]field, 0)
+	for len(buf) > 0 {
+		key, n := binary.Uvarint(buf)
+		if n <= 0 {
+			return nil, errTruncated
+		}
+		buf = buf[n:]
+
+		f := field{number: int(key >> 3), wireType: int(key & 7)}
+		if f.number == 0 {
+			return nil, errors.New("proto: invalid field number 0")
+		}
+		switch f.wireType {
+		case wireVarint:
+			f.varint, n = binary.Uvarint(buf)
+			if n <= 0 {
+				return nil, errTruncated
+			}
+			buf = buf[n:]
+		case wireFixed64:
+			if len(buf) < 8 {
+				return nil, errTruncated
+			}
+			buf = buf[8:]
+		case wireBytes:
+			length, n := binary.Uvarint(buf)
+			if n <= 0 || uint64(len(buf)-n) < length {
+				return nil, errTruncated
+			}
+			f.bytes = buf[n : n+int(length)]
+			buf = buf[n+int(length):]
+		case wireFixed32:
+			if len(buf) < 4 {
+				return nil, errTruncated
+			}
+			f.fixed32 = binary.LittleEndian.Uint32(buf)
+			buf = buf[4:]
+		default:
+			return nil, fmt.Errorf("proto: unsupported wire type %v", f.wireType)
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// expect checks the field has the wire type of its declaration
+func (f field) expect(wireType int) error {
+	if f.wireType != wireType {
+		return fmt.Errorf("proto: field %v expected wire type %v, got %v", f.number, wireType, f.wireType)
+	}
+	return nil
+}
+
+func (f field) float() float32 {
+	return math.Float32frombits(f.fixed32)
+}